@@ -0,0 +1,77 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	util "github.com/datatogether/api/apiutil"
+	"github.com/qri-io/qri/core"
+	"github.com/qri-io/qri/repo"
+)
+
+// AnalyticsHandlers wraps an AnalyticsRequests with http.HandlerFuncs
+type AnalyticsHandlers struct {
+	core.AnalyticsRequests
+	repo repo.Repo
+}
+
+// NewAnalyticsHandlers allocates an AnalyticsHandlers pointer
+func NewAnalyticsHandlers(r repo.Repo) *AnalyticsHandlers {
+	req := core.NewAnalyticsRequests(r, nil)
+	h := AnalyticsHandlers{*req, r}
+	return &h
+}
+
+// AnalyticsHandler lists usage counters for all datasets
+func (h *AnalyticsHandlers) AnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "OPTIONS":
+		util.EmptyOkHandler(w, r)
+	case "GET":
+		h.listHandler(w, r)
+	default:
+		util.NotFoundHandler(w, r)
+	}
+}
+
+// DatasetAnalyticsHandler gets usage counters for a single dataset
+func (h *AnalyticsHandlers) DatasetAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "OPTIONS":
+		util.EmptyOkHandler(w, r)
+	case "GET":
+		h.getHandler(w, r)
+	default:
+		util.NotFoundHandler(w, r)
+	}
+}
+
+func (h *AnalyticsHandlers) listHandler(w http.ResponseWriter, r *http.Request) {
+	lp := core.ListParamsFromRequest(r)
+
+	res := []*repo.DatasetStats{}
+	if err := h.List(&lp, &res); err != nil {
+		util.WriteErrResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	util.WritePageResponse(w, res, r, lp.Page())
+}
+
+func (h *AnalyticsHandlers) getHandler(w http.ResponseWriter, r *http.Request) {
+	args, err := DatasetRefFromPath(r.URL.Path[len("/analytics"):])
+	if err != nil {
+		util.WriteErrResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	if args.Name == "" {
+		util.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("peername & name of dataset needed"))
+		return
+	}
+
+	res := &repo.DatasetStats{}
+	if err := h.Get(&args, res); err != nil {
+		util.WriteErrResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	util.WriteResponse(w, res)
+}
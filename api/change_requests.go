@@ -0,0 +1,226 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	util "github.com/datatogether/api/apiutil"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/qri/core"
+	"github.com/qri-io/qri/repo"
+)
+
+// ChangeRequestHandlers wraps a ChangeRequestRequests with http.HandlerFuncs
+type ChangeRequestHandlers struct {
+	core.ChangeRequestRequests
+	repo     repo.Repo
+	ReadOnly bool
+}
+
+// NewChangeRequestHandlers allocates a ChangeRequestHandlers pointer
+func NewChangeRequestHandlers(r repo.Repo, readOnly bool) *ChangeRequestHandlers {
+	req := core.NewChangeRequestRequests(r, nil)
+	req.Remote = true
+	h := ChangeRequestHandlers{*req, r, readOnly}
+	return &h
+}
+
+// ChangeRequestsHandler lists & creates change requests
+func (h *ChangeRequestHandlers) ChangeRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	if h.ReadOnly {
+		readOnlyResponse(w, "/requests")
+		return
+	}
+
+	switch r.Method {
+	case "OPTIONS":
+		util.EmptyOkHandler(w, r)
+	case "GET":
+		h.listHandler(w, r)
+	case "POST":
+		h.createHandler(w, r)
+	default:
+		util.NotFoundHandler(w, r)
+	}
+}
+
+// ChangeRequestHandler gets a single change request, or acts on it with
+// /requests/[id]/accept, /requests/[id]/reject, or /requests/[id]/send
+func (h *ChangeRequestHandlers) ChangeRequestHandler(w http.ResponseWriter, r *http.Request) {
+	if h.ReadOnly {
+		readOnlyResponse(w, "/requests/")
+		return
+	}
+
+	id := strings.Trim(r.URL.Path[len("/requests/"):], "/")
+	action := ""
+	if i := strings.Index(id, "/"); i != -1 {
+		id, action = id[:i], id[i+1:]
+	}
+
+	switch r.Method {
+	case "OPTIONS":
+		util.EmptyOkHandler(w, r)
+	case "GET":
+		if action != "" {
+			util.NotFoundHandler(w, r)
+			return
+		}
+		h.getHandler(w, r, id)
+	case "POST":
+		h.actionHandler(w, r, id, action)
+	default:
+		util.NotFoundHandler(w, r)
+	}
+}
+
+func (h *ChangeRequestHandlers) listHandler(w http.ResponseWriter, r *http.Request) {
+	lp := core.ListParamsFromRequest(r)
+	params := &core.ListChangeRequestsParams{
+		Incoming: r.FormValue("incoming") == "true",
+		Outgoing: r.FormValue("outgoing") == "true",
+		Status:   repo.ChangeRequestStatus(r.FormValue("status")),
+		Limit:    lp.Limit,
+		Offset:   lp.Offset,
+	}
+
+	res := []*repo.ChangeRequest{}
+	if err := h.List(params, &res); err != nil {
+		util.WriteErrResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	util.WritePageResponse(w, res, r, lp.Page())
+}
+
+func (h *ChangeRequestHandlers) getHandler(w http.ResponseWriter, r *http.Request, id string) {
+	res := &repo.ChangeRequest{}
+	if err := h.Get(&id, res); err != nil {
+		util.WriteErrResponse(w, http.StatusNotFound, err)
+		return
+	}
+	util.WriteResponse(w, res)
+}
+
+func (h *ChangeRequestHandlers) actionHandler(w http.ResponseWriter, r *http.Request, id, action string) {
+	var (
+		res interface{}
+		err error
+	)
+
+	switch action {
+	case "accept":
+		ref := &repo.DatasetRef{}
+		err = h.Accept(&id, ref)
+		res = ref
+	case "reject":
+		cr := &repo.ChangeRequest{}
+		err = h.Reject(&id, cr)
+		res = cr
+	case "send":
+		cr := &repo.ChangeRequest{}
+		err = h.Send(&id, cr)
+		res = cr
+	default:
+		util.NotFoundHandler(w, r)
+		return
+	}
+
+	if err != nil {
+		util.WriteErrResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	util.WriteResponse(w, res)
+}
+
+type changeRequestParamsJSON struct {
+	Target    string          `json:"target"`
+	Title     string          `json:"title,omitempty"`
+	Message   string          `json:"message,omitempty"`
+	Meta      json.RawMessage `json:"meta,omitempty"`
+	Structure json.RawMessage `json:"structure,omitempty"`
+}
+
+// createHandler accepts either a json body, or a multipart form with the
+// same fields as /save
+func (h *ChangeRequestHandlers) createHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		target string
+		p      = &core.CreateChangeRequestParams{}
+	)
+
+	if r.Header.Get("Content-Type") == "application/json" {
+		body := &changeRequestParamsJSON{}
+		if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+			util.WriteErrResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		target = body.Target
+		p.Title = body.Title
+		p.Message = body.Message
+		if len(body.Meta) != 0 {
+			p.Metadata = cafs.NewMemfileReader("meta.json", bytes.NewReader(body.Meta))
+			p.MetadataFilename = "meta.json"
+		}
+		if len(body.Structure) != 0 {
+			p.Structure = cafs.NewMemfileReader("structure.json", bytes.NewReader(body.Structure))
+			p.StructureFilename = "structure.json"
+		}
+	} else {
+		target = r.FormValue("target")
+		p.URL = r.FormValue("url")
+		p.Title = r.FormValue("title")
+		p.Message = r.FormValue("message")
+
+		infile, fileHeader, err := r.FormFile("file")
+		if err != nil && err != http.ErrMissingFile {
+			util.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("error opening data file: %s", err))
+			return
+		}
+		if infile != nil {
+			p.Data = cafs.NewMemfileReader(fileHeader.Filename, infile)
+			p.DataFilename = fileHeader.Filename
+		}
+
+		metadatafile, metadataHeader, err := r.FormFile("metadata")
+		if err != nil && err != http.ErrMissingFile {
+			util.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("error opening metadata file: %s", err))
+			return
+		}
+		if metadatafile != nil {
+			p.Metadata = cafs.NewMemfileReader(metadataHeader.Filename, metadatafile)
+			p.MetadataFilename = metadataHeader.Filename
+		}
+
+		structurefile, structureHeader, err := r.FormFile("structure")
+		if err != nil && err != http.ErrMissingFile {
+			util.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("error opening structure file: %s", err))
+			return
+		}
+		if structurefile != nil {
+			p.Structure = cafs.NewMemfileReader(structureHeader.Filename, structurefile)
+			p.StructureFilename = structureHeader.Filename
+		}
+	}
+
+	if core.IsLocalSource(p.URL) {
+		util.WriteErrResponse(w, http.StatusBadRequest, errLocalSource)
+		return
+	}
+
+	ref, err := repo.ParseDatasetRef(target)
+	if err != nil {
+		util.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("invalid target: %s", err.Error()))
+		return
+	}
+	p.Target = ref
+
+	res := &repo.ChangeRequest{}
+	if err := h.Create(p, res); err != nil {
+		util.WriteErrResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	util.WriteResponse(w, res)
+}
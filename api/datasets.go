@@ -30,6 +30,7 @@ type DatasetHandlers struct {
 // NewDatasetHandlers allocates a DatasetHandlers pointer
 func NewDatasetHandlers(r repo.Repo, readOnly bool) *DatasetHandlers {
 	req := core.NewDatasetRequests(r, nil)
+	req.Remote = true
 	h := DatasetHandlers{*req, r, readOnly}
 	return &h
 }
@@ -238,6 +239,9 @@ func (h *DatasetHandlers) getHandler(w http.ResponseWriter, r *http.Request) {
 		util.WriteErrResponse(w, http.StatusInternalServerError, err)
 		return
 	}
+	if err := repo.RecordStat(h.repo, *res, repo.StatAPIReads); err != nil {
+		log.Debugf("error recording api read: %s", err.Error())
+	}
 	util.WriteResponse(w, res)
 }
 
@@ -341,6 +345,10 @@ func (h *DatasetHandlers) peerListHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// errLocalSource is returned when api callers ask to read data from the
+// machine qri is running on
+var errLocalSource = fmt.Errorf("local sources like file:// urls can't be used over the api")
+
 func (h *DatasetHandlers) initHandler(w http.ResponseWriter, r *http.Request) {
 	p := &core.InitParams{}
 	switch r.Header.Get("Content-Type") {
@@ -357,10 +365,11 @@ func (h *DatasetHandlers) initHandler(w http.ResponseWriter, r *http.Request) {
 
 	default:
 		p = &core.InitParams{
-			Peername: r.FormValue("peername"),
-			URL:      r.FormValue("url"),
-			Name:     r.FormValue("name"),
-			Private:  r.FormValue("private") == "true",
+			Peername:   r.FormValue("peername"),
+			URL:        r.FormValue("url"),
+			SourceAuth: r.FormValue("source_auth"),
+			Name:       r.FormValue("name"),
+			Private:    r.FormValue("private") == "true",
 		}
 
 		infile, fileHeader, err := r.FormFile("file")
@@ -394,6 +403,11 @@ func (h *DatasetHandlers) initHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if core.IsLocalSource(p.URL) {
+		util.WriteErrResponse(w, http.StatusBadRequest, errLocalSource)
+		return
+	}
+
 	res := &repo.DatasetRef{}
 	if err := h.Init(p, res); err != nil {
 		log.Infof("error initializing dataset: %s", err.Error())
@@ -430,6 +444,8 @@ type saveParamsJSON struct {
 	Name      string          `json:"name,omitempty"`
 	Title     string          `json:"title,omitempty"`
 	Message   string          `json:"message,omitempty"`
+	Branch    string          `json:"branch,omitempty"`
+	DryRun    bool            `json:"dryRun,omitempty"`
 	Data      json.RawMessage `json:"data,omitempty"`
 	Meta      json.RawMessage `json:"meta,omitempty"`
 	Structure json.RawMessage `json:"structure,omitempty"`
@@ -437,6 +453,7 @@ type saveParamsJSON struct {
 
 func (h *DatasetHandlers) saveHandler(w http.ResponseWriter, r *http.Request) {
 	save := &core.SaveParams{}
+	dryRun := r.URL.Query().Get("dryRun") == "true"
 	if r.Header.Get("Content-Type") == "application/json" {
 		saveParams := &saveParamsJSON{}
 		err := json.NewDecoder(r.Body).Decode(saveParams)
@@ -462,7 +479,9 @@ func (h *DatasetHandlers) saveHandler(w http.ResponseWriter, r *http.Request) {
 			Name:     saveParams.Name,
 			Title:    saveParams.Title,
 			Message:  saveParams.Message,
+			Branch:   saveParams.Branch,
 		}
+		dryRun = dryRun || saveParams.DryRun
 		if len(saveParams.Data) != 0 {
 			util.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("cannot accept data files using Content-Type: application/json. must make a mime/multipart request"))
 			return
@@ -482,12 +501,21 @@ func (h *DatasetHandlers) saveHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	} else {
 		save = &core.SaveParams{
-			Peername: r.FormValue("peername"),
-			URL:      r.FormValue("url"),
-			Name:     r.FormValue("name"),
-			Title:    r.FormValue("title"),
-			Message:  r.FormValue("message"),
-		}
+			Peername:     r.FormValue("peername"),
+			URL:          r.FormValue("url"),
+			SourceAuth:   r.FormValue("source_auth"),
+			Name:         r.FormValue("name"),
+			Title:        r.FormValue("title"),
+			Message:      r.FormValue("message"),
+			Branch:       r.FormValue("branch"),
+			Append:       r.FormValue("append") == "true",
+			Patch:        r.FormValue("patch") == "true",
+			PatchDeletes: r.FormValue("patch_deletes") == "true",
+		}
+		if key := r.FormValue("key"); key != "" {
+			save.PrimaryKey = strings.Split(key, ",")
+		}
+		dryRun = dryRun || r.FormValue("dryRun") == "true"
 
 		infile, fileHeader, err := r.FormFile("file")
 		if err != nil && err != http.ErrMissingFile {
@@ -520,8 +548,29 @@ func (h *DatasetHandlers) saveHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if core.IsLocalSource(save.URL) {
+		util.WriteErrResponse(w, http.StatusBadRequest, errLocalSource)
+		return
+	}
+
+	if dryRun {
+		preview := &core.SavePreview{}
+		if err := h.SaveDryRun(save, preview); err != nil {
+			util.WriteErrResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+		util.WriteResponse(w, preview)
+		return
+	}
+
 	res := &repo.DatasetRef{}
-	if err := h.Save(save, res); err != nil {
+	if err := h.Save(save, res); core.IsSourceNotModified(err) {
+		// nothing to save isn't a failure, respond with the unchanged version
+		if err := h.Get(&repo.DatasetRef{Peername: save.Peername, Name: save.Name, Tag: save.Branch}, res); err != nil {
+			util.WriteErrResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+	} else if err != nil {
 		util.WriteErrResponse(w, http.StatusInternalServerError, err)
 		return
 	}
@@ -642,6 +691,7 @@ func (h DatasetHandlers) dataHandler(w http.ResponseWriter, r *http.Request) {
 
 	p := &core.StructuredDataParams{
 		Path:   d.Path,
+		Ref:    d,
 		Format: dataset.JSONDataFormat,
 		Limit:  limit,
 		Offset: offset,
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	util "github.com/datatogether/api/apiutil"
+	"github.com/qri-io/qri/core"
+	"github.com/qri-io/qri/repo"
+)
+
+// GraphHandlers wraps a GraphRequests with http.HandlerFuncs
+type GraphHandlers struct {
+	core.GraphRequests
+	repo     repo.Repo
+	ReadOnly bool
+}
+
+// NewGraphHandlers allocates a GraphHandlers pointer
+func NewGraphHandlers(r repo.Repo, readOnly bool) *GraphHandlers {
+	req := core.NewGraphRequests(r, nil)
+	h := GraphHandlers{*req, r, readOnly}
+	return &h
+}
+
+// GraphHandler graphs every dataset in the repo, or a single dataset:
+//
+//	GET /graph graphs all datasets
+//	GET /graph/me/dataset_name graphs a single dataset
+//
+// Graphs are JSON unless ?format=dot is given. ?depth=n limits the number of
+// links followed, ?types=dataset,transform limits the types of nodes shown
+func (h *GraphHandlers) GraphHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "OPTIONS":
+		util.EmptyOkHandler(w, r)
+	case "GET":
+		if h.ReadOnly && r.URL.Path == "/graph" {
+			readOnlyResponse(w, "/graph")
+			return
+		}
+		h.graphHandler(w, r)
+	default:
+		util.NotFoundHandler(w, r)
+	}
+}
+
+func (h *GraphHandlers) graphHandler(w http.ResponseWriter, r *http.Request) {
+	p := &core.GraphParams{}
+	if path := strings.TrimPrefix(r.URL.Path, "/graph"); path != "" && path != "/" {
+		ref, err := DatasetRefFromPath(path)
+		if err != nil {
+			util.WriteErrResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		p.Ref = ref
+	}
+	if r.FormValue("depth") != "" {
+		depth, err := util.ReqParamInt("depth", r)
+		if err != nil {
+			util.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("invalid depth: %s", err.Error()))
+			return
+		}
+		p.Depth = depth
+	}
+	if types := r.FormValue("types"); types != "" {
+		p.Types = strings.Split(types, ",")
+	}
+
+	res := &repo.GraphView{}
+	if err := h.Graph(p, res); err != nil {
+		util.WriteErrResponse(w, http.StatusBadRequest, err)
+		return
+	}
+
+	switch r.FormValue("format") {
+	case "", "json":
+		util.WriteResponse(w, res)
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		w.Write(res.DOT())
+	default:
+		util.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("unrecognized format: %s", r.FormValue("format")))
+	}
+}
@@ -236,6 +236,24 @@ func NewServerRoutes(s *Server) *http.ServeMux {
 	hh.HistoryRequests.Node = s.qriNode
 	m.Handle("/history/", s.middleware(hh.LogHandler))
 
+	crh := NewChangeRequestHandlers(s.qriNode.Repo, s.cfg.API.ReadOnly)
+	// TODO - stupid hack for now.
+	crh.ChangeRequestRequests.Node = s.qriNode
+	m.Handle("/requests", s.middleware(crh.ChangeRequestsHandler))
+	m.Handle("/requests/", s.middleware(crh.ChangeRequestHandler))
+
+	th := NewTagHandlers(s.qriNode.Repo, s.cfg.API.ReadOnly)
+	m.Handle("/tags", s.middleware(th.TagsHandler))
+	m.Handle("/tags/", s.middleware(th.TagHandler))
+
+	gh := NewGraphHandlers(s.qriNode.Repo, s.cfg.API.ReadOnly)
+	m.Handle("/graph", s.middleware(gh.GraphHandler))
+	m.Handle("/graph/", s.middleware(gh.GraphHandler))
+
+	ah := NewAnalyticsHandlers(s.qriNode.Repo)
+	m.Handle("/analytics", s.middleware(ah.AnalyticsHandler))
+	m.Handle("/analytics/", s.middleware(ah.DatasetAnalyticsHandler))
+
 	rh := NewRootHandler(dsh, ph)
 	m.Handle("/", s.datasetRefMiddleware(s.middleware(rh.Handler)))
 
@@ -130,6 +130,30 @@ func TestServerRoutes(t *testing.T) {
 
 		{"GET", "/connect/", "", "", 400},
 
+		// change requests
+		{"GET", "/requests", "", "requestsResponse.json", 200},
+		{"GET", "/requests/not_a_request", "", "", 404},
+		{"POST", "/requests/not_a_request/accept", "", "", 400},
+
+		// tags
+		{"GET", "/tags", "", "", 200},
+		{"GET", "/tags/me/movies", "", "", 200},
+		{"POST", "/tags/me/movies", "", "", 400},
+		{"DELETE", "/tags/me/movies/at/not_a_tag", "", "", 400},
+
+		// analytics
+		{"GET", "/analytics", "", "", 200},
+		{"GET", "/analytics/", "", "", 400},
+		{"POST", "/analytics", "", "", 404},
+
+		// graph
+		{"GET", "/graph", "", "", 200},
+		{"GET", "/graph?format=dot&depth=2", "", "", 200},
+		{"GET", "/graph/me/movies?types=dataset,transform", "", "", 200},
+		{"GET", "/graph?types=not_a_type", "", "", 400},
+		{"GET", "/graph?format=svg", "", "", 400},
+		{"POST", "/graph", "", "", 404},
+
 		// blatently checking all options for easy test coverage bump
 		{"OPTIONS", "/add", "", "", 200},
 		{"OPTIONS", "/add/", "", "", 200},
@@ -143,6 +167,14 @@ func TestServerRoutes(t *testing.T) {
 		{"OPTIONS", "/me/", "", "", 200},
 		{"OPTIONS", "/list/", "", "", 200},
 		{"OPTIONS", "/history/", "", "", 200},
+		{"OPTIONS", "/requests", "", "", 200},
+		{"OPTIONS", "/requests/", "", "", 200},
+		{"OPTIONS", "/tags", "", "", 200},
+		{"OPTIONS", "/tags/", "", "", 200},
+		{"OPTIONS", "/analytics", "", "", 200},
+		{"OPTIONS", "/analytics/", "", "", 200},
+		{"OPTIONS", "/graph", "", "", 200},
+		{"OPTIONS", "/graph/", "", "", 200},
 	}
 
 	for i, c := range cases {
@@ -418,6 +450,13 @@ func testMimeMultipart(t *testing.T, server *httptest.Server, client *http.Clien
 				"peername": "peer",
 			},
 		},
+		{"POST", "/requests", "testdata/requestResponseLocalSource.json", 400,
+			map[string]string{},
+			map[string]string{
+				"target": "peer/cities",
+				"url":    "file:///etc/passwd",
+			},
+		},
 	}
 
 	for i, c := range cases {
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	util "github.com/datatogether/api/apiutil"
+	"github.com/qri-io/qri/core"
+	"github.com/qri-io/qri/repo"
+)
+
+// TagHandlers wraps a TagRequests with http.HandlerFuncs
+type TagHandlers struct {
+	core.TagRequests
+	repo     repo.Repo
+	ReadOnly bool
+}
+
+// NewTagHandlers allocates a TagHandlers pointer
+func NewTagHandlers(r repo.Repo, readOnly bool) *TagHandlers {
+	req := core.NewTagRequests(r, nil)
+	h := TagHandlers{*req, r, readOnly}
+	return &h
+}
+
+// TagsHandler lists all known tags
+func (h *TagHandlers) TagsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "OPTIONS":
+		util.EmptyOkHandler(w, r)
+	case "GET":
+		if h.ReadOnly {
+			readOnlyResponse(w, "/tags")
+			return
+		}
+		h.listHandler(w, r)
+	default:
+		util.NotFoundHandler(w, r)
+	}
+}
+
+// TagHandler lists, creates & removes tags for a single dataset:
+//
+//	GET /tags/me/dataset_name lists tags
+//	POST /tags/me/dataset_name?tag=v1 tags the latest version
+//	POST /tags/me/dataset_name/at/ipfs/Qm...?tag=v1 tags a specific version
+//	DELETE /tags/me/dataset_name/at/v1 removes a tag
+func (h *TagHandlers) TagHandler(w http.ResponseWriter, r *http.Request) {
+	if h.ReadOnly {
+		readOnlyResponse(w, "/tags/")
+		return
+	}
+
+	switch r.Method {
+	case "OPTIONS":
+		util.EmptyOkHandler(w, r)
+	case "GET":
+		h.listHandler(w, r)
+	case "POST", "PUT":
+		h.tagHandler(w, r)
+	case "DELETE":
+		h.untagHandler(w, r)
+	default:
+		util.NotFoundHandler(w, r)
+	}
+}
+
+func (h *TagHandlers) listHandler(w http.ResponseWriter, r *http.Request) {
+	ref := repo.DatasetRef{}
+	if path := r.URL.Path[len("/tags"):]; path != "" && path != "/" {
+		args, err := DatasetRefFromPath(path)
+		if err != nil {
+			util.WriteErrResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		ref = args
+	}
+
+	res := []repo.DatasetRef{}
+	if err := h.List(&ref, &res); err != nil {
+		util.WriteErrResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	util.WriteResponse(w, res)
+}
+
+func (h *TagHandlers) tagHandler(w http.ResponseWriter, r *http.Request) {
+	args, err := DatasetRefFromPath(r.URL.Path[len("/tags"):])
+	if err != nil {
+		util.WriteErrResponse(w, http.StatusBadRequest, err)
+		return
+	}
+
+	p := &core.TagParams{
+		Ref: args,
+		Tag: r.FormValue("tag"),
+	}
+	res := &repo.DatasetRef{}
+	if err := h.Tag(p, res); err != nil {
+		util.WriteErrResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	util.WriteResponse(w, res)
+}
+
+func (h *TagHandlers) untagHandler(w http.ResponseWriter, r *http.Request) {
+	args, err := DatasetRefFromPath(r.URL.Path[len("/tags"):])
+	if err != nil {
+		util.WriteErrResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	if args.Tag == "" {
+		util.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("tag is required"))
+		return
+	}
+
+	ok := false
+	if err := h.Untag(&args, &ok); err != nil {
+		util.WriteErrResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	util.WriteResponse(w, args)
+}
@@ -17,6 +17,7 @@ var (
 	addDsStructureFilepath string
 	addDsName              string
 	addDsURL               string
+	addDsSourceAuth        string
 	addDsPassive           bool
 	addDsShowValidation    bool
 	addDsPrivate           bool
@@ -39,12 +40,26 @@ add currently supports two data formats:
 
 Once you’ve added data, you can use the export command to pull the data out of 
 qri, change the data outside of qri, and use the save command to record those 
-changes to qri.`,
+changes to qri.
+
+The --url flag reads data from a url, and supports these kinds of url:
+- http:// & https:// urls
+- file:// urls of local files, eg: file:///path/to/data.csv
+- s3:// urls of objects in s3-compatible stores, eg: s3://bucket/data.csv. 
+  add ?endpoint=host:port to use stores other than AWS
+- sqlite:// urls of a table in a local sqlite database, 
+  eg: sqlite:///path/to/db.sqlite?table=birds. needs the sqlite3 command
+The url is kept as the dataset's download path, so saves & updates read 
+data from the same place.`,
 	Example: `  add a new dataset named annual_pop:
   $ qri add --data data.csv me/annual_pop
 
   create a dataset with a metadata and data file:
-  $ qri add --meta meta.json --data comics.csv me/comic_characters`,
+  $ qri add --meta meta.json --data comics.csv me/comic_characters
+
+  add a dataset from a url that needs the credentials named "census" in 
+  the fetch.auth section of your config:
+  $ qri add --url https://example.com/pop.csv --source-auth census me/pop`,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		loadConfig()
 	},
@@ -95,6 +110,9 @@ func initDataset(name repo.DatasetRef) {
 	if addDsFilepath == "" && addDsURL == "" || addDsFilepath != "" && addDsURL != "" {
 		ErrExit(fmt.Errorf("please provide either a file or a url argument"))
 	}
+	if addDsSourceAuth != "" && addDsURL == "" {
+		ErrExit(fmt.Errorf("--source-auth only applies when adding from a url"))
+	}
 
 	dataFile, err = loadFileIfPath(addDsFilepath)
 	ExitIfErr(err)
@@ -107,6 +125,7 @@ func initDataset(name repo.DatasetRef) {
 		Peername:     name.Peername,
 		Name:         name.Name,
 		URL:          addDsURL,
+		SourceAuth:   addDsSourceAuth,
 		DataFilename: filepath.Base(addDsFilepath),
 		Private:      addDsPrivate,
 	}
@@ -149,7 +168,8 @@ func initDataset(name repo.DatasetRef) {
 }
 
 func init() {
-	datasetAddCmd.Flags().StringVarP(&addDsURL, "url", "", "", "url of file to initialize from")
+	datasetAddCmd.Flags().StringVarP(&addDsURL, "url", "", "", "url to initialize from, eg: https://, file://, s3://, sqlite://")
+	datasetAddCmd.Flags().StringVarP(&addDsSourceAuth, "source-auth", "", "", "name of configured credentials to fetch --url with")
 	datasetAddCmd.Flags().StringVarP(&addDsFilepath, "data", "", "", "data file to initialize from")
 	datasetAddCmd.Flags().StringVarP(&addDsStructureFilepath, "structure", "", "", "dataset structure JSON file")
 	datasetAddCmd.Flags().StringVarP(&addDsMetaFilepath, "meta", "", "", "dataset metadata JSON file")
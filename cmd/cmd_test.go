@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
@@ -12,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/qri-io/qri/config"
+	"github.com/qri-io/qri/repo"
 	"github.com/qri-io/registry"
 	"github.com/qri-io/registry/regserver/handlers"
 	"github.com/spf13/cobra"
@@ -94,6 +96,49 @@ const profileData = `
 }
 `
 
+// previousVersion stands in for the path of the version before the latest
+// version of me/movies in command arguments, which isn't known until the
+// command runs
+const previousVersion = "{previous_version}"
+
+// withVersions fills in version paths of command arguments
+func withVersions(args []string) ([]string, error) {
+	filled := make([]string, len(args))
+	for i, arg := range args {
+		if !strings.Contains(arg, previousVersion) {
+			filled[i] = arg
+			continue
+		}
+		req, err := datasetRequests(false)
+		if err != nil {
+			return nil, err
+		}
+		ref := &repo.DatasetRef{}
+		if err := req.Get(&repo.DatasetRef{Peername: "me", Name: "movies"}, ref); err != nil {
+			return nil, err
+		}
+		filled[i] = strings.Replace(arg, previousVersion, ref.Dataset.PreviousPath, -1)
+	}
+	return filled, nil
+}
+
+// dataReads gets the number of times data has been read for a dataset
+func dataReads(refstr string) (int, error) {
+	req, err := analyticsRequests(false)
+	if err != nil {
+		return 0, err
+	}
+	ref, err := repo.ParseDatasetRef(refstr)
+	if err != nil {
+		return 0, err
+	}
+	stats := &repo.DatasetStats{}
+	if err := req.Get(&ref, stats); err != nil {
+		return 0, err
+	}
+	return stats.Counts[repo.StatDataReads], nil
+}
+
 // This is a basic integration test that makes sure basic happy paths work on the CLI
 func TestCommandsIntegration(t *testing.T) {
 	if err := confirmQriNotRunning(); err != nil {
@@ -102,6 +147,13 @@ func TestCommandsIntegration(t *testing.T) {
 
 	registryServer := httptest.NewServer(handlers.NewRoutes(registry.NewProfiles()))
 
+	// serves data for saves from a url
+	sourceServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte(moviesCSVData2 + "\nYet Another Film,99"))
+	}))
+	defer sourceServer.Close()
+
 	path := filepath.Join(os.TempDir(), "qri_test_commands_integration")
 	t.Logf("test filepath: %s", path)
 
@@ -150,6 +202,10 @@ func TestCommandsIntegration(t *testing.T) {
 		{"config", "get"},
 		{"config", "get", "profile"},
 		{"config", "set", "webapp.port", "3505"},
+		{"repo", "info"},
+		{"repo", "stats"},
+		{"repo", "stats", "--format", "json"},
+		{"repo", "migrate"},
 		// TODO - add setting whole config via a file
 		// {"config", "set", "-i" + profileDataFilepath},
 		{"info", "me"},
@@ -160,16 +216,57 @@ func TestCommandsIntegration(t *testing.T) {
 		{"list"},
 		{"save", "--data=" + movies2FilePath, "-t" + "commit_1", "me/movies"},
 		{"log", "me/movies"},
+		{"save", "--data=" + moviesFilePath, "-t" + "branch_1", "--branch", "fix", "me/movies"},
+		{"log", "me/movies@fix"},
+		{"log", "--graph", "me/movies"},
+		{"revert", "me/movies@" + previousVersion},
+		{"save", "--data=" + movies2FilePath, "--dry-run", "me/movies"},
+		{"save", "--url=" + sourceServer.URL + "/movies.csv", "me/movies"},
 		{"diff", "me/movies", "me/movies2", "-d", "detail"},
 		{"export", "--dataset", "-o" + path, "me/movies"},
+		{"tag", "me/movies", "v1"},
+		{"tag", "me/movies"},
 		{"rename", "me/movies", "me/movie"},
+		{"tag"},
+		{"tag", "--delete", "me/movie@v1"},
 		{"data", "--limit=1", "--data-format=cbor", "me/movie"},
+		{"repo", "export", filepath.Join(path, "backup.zip")},
+		{"graph"},
+		{"graph", "--depth", "2", "--types", "dataset,transform", "--format", "json", "me/movie"},
+		{"stats"},
+		{"stats", "me/movie"},
 		{"validate", "me/movie"},
+		{"update", "list"},
+		{"update", "run"},
 		{"remove", "me/movie"},
+		{"fsck", "--format", "json"},
+		{"gc", "--dry-run"},
+		{"gc"},
+		{"request", "list"},
+		{"request", "list", "--incoming"},
 		{"setup", "--remove"},
 	}
 
+	// checks run after the command with matching arguments succeeds
+	checks := map[string]func() error{
+		"stats me/movie": func() error {
+			reads, err := dataReads("me/movie")
+			if err != nil {
+				return err
+			}
+			if reads != 1 {
+				return fmt.Errorf("expected qri data to record 1 data read. got: %d", reads)
+			}
+			return nil
+		},
+	}
+
 	for i, args := range commands {
+		args, err := withVersions(args)
+		if err != nil {
+			t.Errorf("case %d error reading versions: %s", i, err.Error())
+			continue
+		}
 		func() {
 			defer func() {
 				if e := recover(); e != nil {
@@ -182,6 +279,11 @@ func TestCommandsIntegration(t *testing.T) {
 				t.Errorf("case %d unexpected error executing command\n%s\n%s", i, strings.Join(args, " "), err.Error())
 				return
 			}
+			if check, ok := checks[strings.Join(args, " ")]; ok {
+				if err := check(); err != nil {
+					t.Errorf("case %d check failed\n%s\n%s", i, strings.Join(args, " "), err.Error())
+				}
+			}
 		}()
 	}
 }
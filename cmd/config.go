@@ -54,9 +54,10 @@ var configGetCommand = &cobra.Command{
 	Use:   "get",
 	Short: "get configuration settings",
 	Long: `get outputs your current configuration file with private keys 
-removed by default, making it easier to share your qri configuration settings.
+& source credentials removed by default, making it easier to share your qri 
+configuration settings.
 
-The --with-private-keys option will show private keys.
+The --with-private-keys option will show private keys & source credentials.
 PLEASE PLEASE PLEASE NEVER SHARE YOUR PRIVATE KEYS WITH ANYONE. EVER.
 Anyone with your private keys can impersonate you on qri.`,
 	Args: cobra.MaximumNArgs(1),
@@ -89,6 +90,18 @@ Anyone with your private keys can impersonate you on qri.`,
 			if cfg.P2P != nil {
 				cfg.P2P.PrivKey = ""
 			}
+			if cfg.Fetch != nil && len(cfg.Fetch.Auth) > 0 {
+				// copy fetch config so hiding credentials doesn't remove them
+				fetch := *cfg.Fetch
+				fetch.Auth = map[string]*config.SourceAuth{}
+				for name, auth := range cfg.Fetch.Auth {
+					fetch.Auth[name] = &config.SourceAuth{}
+					if auth != nil {
+						fetch.Auth[name].Hosts = auth.Hosts
+					}
+				}
+				cfg.Fetch = &fetch
+			}
 		}
 
 		if len(args) == 1 {
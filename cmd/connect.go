@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"github.com/qri-io/qri/api"
 	"github.com/qri-io/qri/config"
@@ -23,6 +24,7 @@ var (
 	connectCmdRegistry string
 	disableP2P         bool
 	connectReadOnly    bool
+	disableUpdates     bool
 )
 
 // connectCmd represents the run command
@@ -39,6 +41,10 @@ things:
 - Connect to IPFS
 - Start a local API server
 
+While connected, datasets created from a url are re-fetched on the schedule 
+set by their metadata's accrualPeriodicity, saving a new version when data 
+changes. Use “qri update list” to see the schedule.
+
 When you run connect you are connecting to the distributed web, interacting with
 peers & swapping data.`,
 	PreRun: func(cmd *cobra.Command, args []string) {
@@ -102,6 +108,11 @@ peers & swapping data.`,
 		})
 		ExitIfErr(err)
 
+		// read-only nodes don't save, so they can't update
+		if !disableUpdates && !connectReadOnly {
+			go core.NewUpdateScheduler(r).Start(context.Background())
+		}
+
 		err = s.Serve()
 		if err != nil && err.Error() == "http: Server closed" {
 			return
@@ -120,6 +131,7 @@ func init() {
 	connectCmd.Flags().BoolVarP(&disableRPC, "disable-rpc", "", false, "disables rpc, overrides the rpc-port flag")
 	connectCmd.Flags().BoolVarP(&disableWebapp, "disable-webapp", "", false, "disables webapp, overrides the webapp-port flag")
 	connectCmd.Flags().BoolVarP(&disableP2P, "disable-p2p", "", false, "disable peer-2-peer networking")
+	connectCmd.Flags().BoolVarP(&disableUpdates, "disable-updates", "", false, "don't update datasets from their source urls")
 
 	connectCmd.Flags().BoolVarP(&connectSetup, "setup", "", false, "run setup if necessary, reading options from enviornment variables")
 	connectCmd.Flags().BoolVarP(&connectReadOnly, "read-only", "", false, "run qri in read-only mode, limits the api endpoints")
@@ -36,8 +36,10 @@ Data reads records from a dataset`,
 			return
 		}
 
-		r := getRepo(false)
-		req := core.NewDatasetRequests(r, nil)
+		// reading data records a stat, so this command can't use a
+		// read-only repo
+		req, err := datasetRequests(false)
+		ExitIfErr(err)
 
 		dsr, err := repo.ParseDatasetRef(args[0])
 		ExitIfErr(err)
@@ -54,6 +56,7 @@ Data reads records from a dataset`,
 		p := &core.StructuredDataParams{
 			Format: df,
 			Path:   ds.Path().String(),
+			Ref:    *res,
 			Limit:  dataCmdLimit,
 			Offset: dataCmdOffset,
 			All:    dataCmdAll,
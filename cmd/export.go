@@ -47,8 +47,8 @@ To export everything about a dataset, use the --dataset flag.`,
 		}
 		path := cmd.Flag("output").Value.String()
 
-		r := getRepo(false)
-		req := core.NewDatasetRequests(r, nil)
+		r, cli := getReadOnlyRepo()
+		req := core.NewDatasetRequests(r, cli)
 
 		dsr, err := repo.ParseDatasetRef(args[0])
 		ExitIfErr(err)
@@ -63,17 +63,16 @@ To export everything about a dataset, use the --dataset flag.`,
 		if exportCmdNameSpaced {
 			peerName := dsr.Peername
 			if peerName == "me" {
-				myProfile, err := r.Profile()
-				if err != nil {
-					ExitIfErr(err)
-				}
-				peerName = myProfile.Peername
+				peerName = res.Peername
 			}
 			path = filepath.Join(path, peerName)
 		}
 		path = filepath.Join(path, dsr.Name)
 
 		if cmd.Flag("zip").Value.String() == "true" {
+			if r == nil {
+				ErrExit(fmt.Errorf("can't write a zip archive while another qri process holds the repo, stop it & try again"))
+			}
 			dst, err := os.Create(fmt.Sprintf("%s.zip", path))
 			ExitIfErr(err)
 
@@ -157,15 +156,30 @@ To export everything about a dataset, use the --dataset flag.`,
 		}
 
 		if exportCmdData {
-			src, err := dsfs.LoadData(r.Store(), ds)
-			ExitIfErr(err)
-
 			dataPath := filepath.Join(path, fmt.Sprintf("data.%s", ds.Structure.Format.String()))
 			dst, err := os.Create(dataPath)
 			ExitIfErr(err)
 
-			_, err = io.Copy(dst, src)
-			ExitIfErr(err)
+			if r != nil {
+				src, err := dsfs.LoadData(r.Store(), ds)
+				ExitIfErr(err)
+				_, err = io.Copy(dst, src)
+				ExitIfErr(err)
+			} else {
+				// no direct access to the store, read data from the process
+				// holding the repo
+				p := &core.StructuredDataParams{
+					Format: ds.Structure.Format,
+					Path:   ds.Path().String(),
+					Ref:    *res,
+					All:    true,
+				}
+				sd := &core.StructuredData{}
+				err = req.StructuredData(p, sd)
+				ExitIfErr(err)
+				_, err = dst.Write(sd.Data)
+				ExitIfErr(err)
+			}
 
 			err = dst.Close()
 			ExitIfErr(err)
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/qri-io/qri/core"
+	"github.com/qri-io/qri/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fsckCmdRepair bool
+	fsckCmdFormat string
+)
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "check the integrity of your local repository",
+	Long: `
+fsck (file system check) verifies that everything in your repo is intact:
+- every dataset reference resolves to a dataset
+- every version in a dataset's history can be loaded
+- dataset data matches the checksum recorded in its structure
+- commit signatures verify against your profile's key
+- profiles are consistent with the datasets that reference them
+- datasets are pinned, for stores that can report pins
+
+Use --repair to drop references to datasets that can't be found & pin the
+rest. Use --format json for a machine-readable report. fsck exits with a 
+non-zero status if any problems are left unrepaired.`,
+	Example: `  # check repo & fix what can be fixed
+  $ qri fsck --repair`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		req, err := repoRequests(false)
+		ExitIfErr(err)
+
+		res := &repo.FsckReport{}
+		err = req.Fsck(&core.FsckParams{Repair: fsckCmdRepair}, res)
+		ExitIfErr(err)
+
+		switch fsckCmdFormat {
+		case "json":
+			data, err := json.MarshalIndent(res, "", "  ")
+			ExitIfErr(err)
+			fmt.Println(string(data))
+		case "text":
+			for _, is := range res.Issues {
+				if is.Repaired {
+					printSuccess("repaired %s %s: %s", is.Type, is.Ref, is.Message)
+				} else {
+					printWarning("%s %s: %s", is.Type, is.Ref, is.Message)
+				}
+			}
+			printInfo("checked %d references, %d versions, %d profiles", res.Refs, res.Versions, res.Profiles)
+		default:
+			ErrExit(fmt.Errorf("unrecognized format: %s", fsckCmdFormat))
+		}
+
+		if n := res.Unrepaired(); n > 0 {
+			ErrExit(fmt.Errorf("found %d problem(s)", n))
+		}
+	},
+}
+
+func init() {
+	fsckCmd.Flags().BoolVarP(&fsckCmdRepair, "repair", "", false, "drop dangling references & pin datasets")
+	fsckCmd.Flags().StringVarP(&fsckCmdFormat, "format", "f", "text", "report format. either text or json")
+	RootCmd.AddCommand(fsckCmd)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"github.com/qri-io/qri/core"
+	"github.com/qri-io/qri/repo"
+	"github.com/spf13/cobra"
+)
+
+var gcCmdDryRun bool
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "remove unreferenced data from your local repository",
+	Long: `
+gc (garbage collect) frees up space by removing content that none of your 
+datasets depend on. Content is kept if it's part of any version of a dataset 
+in your repo: dataset history, data, structure, metadata, transforms & commits 
+are all kept, along with your profile photos. Everything else that qri knows 
+about is unpinned & removed from the store.
+
+Use --dry-run to see how much space would be reclaimed without removing 
+anything.`,
+	Example: `  # show what garbage collection would remove
+  $ qri gc --dry-run`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		req, err := repoRequests(false)
+		ExitIfErr(err)
+
+		res := &repo.GCResult{}
+		err = req.GC(&core.GCParams{DryRun: gcCmdDryRun}, res)
+		ExitIfErr(err)
+
+		for _, item := range res.Garbage {
+			printInfo("%s\t%d bytes", item.Path, item.Size)
+		}
+
+		if gcCmdDryRun {
+			printInfo("%d unreferenced paths, %d bytes reclaimable", len(res.Garbage), res.Bytes)
+			return
+		}
+		printSuccess("removed %d unreferenced paths, reclaimed %d bytes", len(res.Garbage), res.Bytes)
+	},
+}
+
+func init() {
+	gcCmd.Flags().BoolVarP(&gcCmdDryRun, "dry-run", "", false, "report reclaimable space without removing anything")
+	RootCmd.AddCommand(gcCmd)
+}
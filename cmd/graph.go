@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/qri/core"
+	"github.com/qri-io/qri/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphCmdDepth int
+	graphCmdTypes []string
+)
+
+// graphCmd represents the graph command
+var graphCmd = &cobra.Command{
+	Use:   "graph [dataset]",
+	Short: "show how datasets depend on one another",
+	Long: `
+Graph shows the versions of a dataset along with the data, commits & transforms 
+each version is made of, and the datasets transforms draw from. With no 
+arguments graph shows every dataset in your repo.
+
+Graphs are written in the Graphviz DOT language by default, which tools like 
+dot can render as an image. Use --format json for a list of nodes & links.
+
+Large histories make for large graphs. --depth limits how many links are 
+followed from the starting dataset, and --types limits the kinds of node 
+shown. Types are: dataset, data, commit & transform.`,
+	Example: `  # render the history of a dataset as an svg image
+  $ qri graph me/dataset_name | dot -Tsvg > graph.svg
+
+  # show the last three versions of a dataset
+  $ qri graph --depth 3 --types dataset me/dataset_name`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			ErrExit(fmt.Errorf("only one dataset can be graphed at a time"))
+		}
+
+		p := &core.GraphParams{Depth: graphCmdDepth, Types: graphCmdTypes}
+		if len(args) == 1 {
+			ref, err := repo.ParseDatasetRef(args[0])
+			ExitIfErr(err)
+			p.Ref = ref
+		}
+
+		req, err := graphRequests(false)
+		ExitIfErr(err)
+
+		res := &repo.GraphView{}
+		err = req.Graph(p, res)
+		ExitIfErr(err)
+
+		switch outformat := cmd.Flag("format").Value.String(); outformat {
+		case "", "dot":
+			fmt.Printf("%s", res.DOT())
+		case dataset.JSONDataFormat.String():
+			data, err := json.MarshalIndent(res, "", "  ")
+			ExitIfErr(err)
+			fmt.Printf("%s\n", string(data))
+		default:
+			ErrExit(fmt.Errorf("unrecognized format: %s", outformat))
+		}
+	},
+}
+
+func init() {
+	graphCmd.Flags().IntVarP(&graphCmdDepth, "depth", "d", 0, "number of links to follow, default 0 follows all links")
+	graphCmd.Flags().StringSliceVarP(&graphCmdTypes, "types", "t", nil, "types of node to show, default all")
+	graphCmd.Flags().StringP("format", "f", "", "set output format [dot, json]")
+	RootCmd.AddCommand(graphCmd)
+}
@@ -41,14 +41,18 @@ var infoCmd = &cobra.Command{
 
 		online := false
 		// check to see if we're all local
-		r := getRepo(false)
-		for _, arg := range args {
-			ref, err := repo.ParseDatasetRef(arg)
-			ExitIfErr(err)
-			err = repo.CanonicalizeDatasetRef(r, &ref)
-			ExitIfErr(err)
-			if ref.Path == "" {
-				online = true
+		// without a repo, requests go to the process holding it, which
+		// canonicalizes refs itself
+		r, _ := getReadOnlyRepo()
+		if r != nil {
+			for _, arg := range args {
+				ref, err := repo.ParseDatasetRef(arg)
+				ExitIfErr(err)
+				err = repo.CanonicalizeDatasetRef(r, &ref)
+				ExitIfErr(err)
+				if ref.Path == "" {
+					online = true
+				}
 			}
 		}
 
@@ -63,8 +67,10 @@ var infoCmd = &cobra.Command{
 			ExitIfErr(err)
 
 			if ref.IsPeerRef() {
-				err = repo.CanonicalizeProfile(r, &ref)
-				ExitIfErr(err)
+				if r != nil {
+					err = repo.CanonicalizeProfile(r, &ref)
+					ExitIfErr(err)
+				}
 				p := &core.PeerInfoParams{
 					Peername: ref.Peername,
 				}
@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+
 	// "encoding/json"
 	// "fmt"
 	// "github.com/qri-io/dataset"
@@ -13,6 +16,7 @@ import (
 var (
 	dsLogLimit, dsLogOffset int
 	dsLogName               string
+	dsLogGraph              bool
 )
 
 var datasetLogCmd = &cobra.Command{
@@ -27,9 +31,19 @@ about how that dataset looked at at that point in time.
 We call these snapshots versions. Each version has an author (the peer that 
 created the version) and a message explaining what changed. Log prints these 
 details in order of occurrence, starting with the most recent known version, 
-working backwards in time.`,
+working backwards in time.
+
+Datasets with branches have more than one line of history. Use --graph to show 
+all branches of a dataset side by side, with each branch drawn from the version 
+it diverged from.`,
 	Example: `  show log for the dataset b5/precip:
-	$ qri log b5/precip`,
+	$ qri log b5/precip
+
+  show log for the "fix" branch of b5/precip:
+	$ qri log b5/precip@fix
+
+  show all branches of b5/precip:
+	$ qri log --graph b5/precip`,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		loadConfig()
 	},
@@ -42,21 +56,33 @@ working backwards in time.`,
 
 		online := false
 
-		r := getRepo(false)
+		r, _ := getReadOnlyRepo()
 		ref, err := repo.ParseDatasetRef(args[0])
 		ExitIfErr(err)
 
-		err = repo.CanonicalizeDatasetRef(r, &ref)
-		ExitIfErr(err)
+		// without a repo, requests go to the process holding it, which
+		// canonicalizes refs itself
+		if r != nil {
+			err = repo.CanonicalizeDatasetRef(r, &ref)
+			ExitIfErr(err)
 
-		if ref.Path == "" {
-			online = true
+			if ref.Path == "" {
+				online = true
+			}
 		}
 
 		// TODO - add limit & offset params
 		hr, err := historyRequests(online)
 		ExitIfErr(err)
 
+		if dsLogGraph {
+			graph := []core.BranchLog{}
+			err = hr.Graph(&ref, &graph)
+			ExitIfErr(err)
+			printLogGraph(graph)
+			return
+		}
+
 		p := &core.LogParams{
 			// Limit:  dsLogLimit,
 			// Offset: dsLogOffset,
@@ -97,4 +123,62 @@ func init() {
 	datasetLogCmd.Flags().IntVarP(&dsLogLimit, "limit", "l", 25, "limit results, default 25")
 	datasetLogCmd.Flags().IntVarP(&dsLogOffset, "offset", "o", 0, "offset results, default 0")
 	datasetLogCmd.Flags().StringVarP(&dsLogName, "name", "n", "", "name of dataset to get logs for")
+	datasetLogCmd.Flags().BoolVarP(&dsLogGraph, "graph", "g", false, "show all branches of the dataset")
+}
+
+// logGraphRow is a single version in a log graph, drawn in the lane of the
+// branch that holds it
+type logGraphRow struct {
+	lane int
+	ref  repo.DatasetRef
+}
+
+// printLogGraph draws the branches of a dataset as lanes, newest version
+// first. Each version is marked with a "*" in its branch's lane, and a
+// branch's lane joins the lane it forked from with a "/"
+func printLogGraph(graph []core.BranchLog) {
+	rows := []logGraphRow{}
+	for lane, b := range graph {
+		for _, ref := range b.Versions {
+			rows = append(rows, logGraphRow{lane, ref})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].ref.Dataset.Commit.Timestamp.After(rows[j].ref.Dataset.Commit.Timestamp)
+	})
+
+	// a lane is open from its branch's newest version until the version it
+	// forked from
+	open := make([]bool, len(graph))
+	lanes := func(mark int, char string) string {
+		cols := make([]string, len(graph))
+		for i := range cols {
+			switch {
+			case i == mark:
+				cols[i] = char
+			case open[i]:
+				cols[i] = "|"
+			default:
+				cols[i] = " "
+			}
+		}
+		return strings.Join(cols, " ")
+	}
+
+	for _, row := range rows {
+		open[row.lane] = true
+		for i, b := range graph {
+			if open[i] && b.ForkPath == row.ref.Path {
+				open[i] = false
+				printInfo(lanes(i, "/"))
+			}
+		}
+
+		label := ""
+		if v := graph[row.lane].Versions; v[0].Path == row.ref.Path {
+			label = fmt.Sprintf(" (%s)", graph[row.lane].Branch)
+		}
+		commit := row.ref.Dataset.Commit
+		printSuccess("%s %s - %s%s\n%s\t%s", lanes(row.lane, "*"), commit.Timestamp.Format("Jan _2 15:04:05"), row.ref.Path, label, lanes(-1, ""), commit.Title)
+	}
 }
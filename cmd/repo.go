@@ -1,17 +1,24 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/rpc"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/qri-io/cafs"
 	ipfs "github.com/qri-io/cafs/ipfs"
 	"github.com/qri-io/qri/config"
 	"github.com/qri-io/qri/core"
 	"github.com/qri-io/qri/p2p"
 	"github.com/qri-io/qri/repo"
 	"github.com/qri-io/qri/repo/fs"
+	"github.com/spf13/cobra"
 )
 
 var (
@@ -28,22 +35,128 @@ func getRepo(online bool) repo.Repo {
 		ErrExit(fmt.Errorf("no qri repo found, please run `qri setup`"))
 	}
 
-	fs := getIpfsFilestore(online)
-	r, err := fsrepo.NewRepo(fs, core.Config.Profile, QriRepoPath)
+	fs := getFilestore(online)
+	r, err := openRepo(fs)
 	ExitIfErr(err)
 
 	return r
 }
 
-func getIpfsFilestore(online bool) *ipfs.Filestore {
-	fs, err := ipfs.NewFilestore(func(cfg *ipfs.StoreCfg) {
-		cfg.FsRepoPath = IpfsFsPath
-		cfg.Online = online
+// getReadOnlyRepo opens the repo with a shared lock for commands that don't
+// write to the repo, allowing them to run alongside one another. If another
+// process holds the repo, getReadOnlyRepo returns an RPC client connected to
+// that process instead
+func getReadOnlyRepo() (repo.Repo, *rpc.Client) {
+	if repository != nil {
+		return repository, nil
+	} else if rpcClient != nil {
+		return nil, rpcClient
+	}
+
+	if !QRIRepoInitialized() {
+		ErrExit(fmt.Errorf("no qri repo found, please run `qri setup`"))
+	}
+
+	fs, err := newFilestore(false)
+	if err != nil {
+		if strings.Contains(err.Error(), "lock") {
+			cli, dialErr := dialRPC()
+			if dialErr == nil {
+				return nil, cli
+			}
+		}
+		ErrExit(err)
+	}
+
+	r, err := openRepo(fs, func(c *config.Repo) {
+		c.ReadOnly = true
 	})
+	if fsrepo.IsLocked(err) {
+		// another process holds the repo, try to reach it over RPC
+		if cli, dialErr := dialRPC(); dialErr == nil {
+			return nil, cli
+		}
+	}
+	ExitIfErr(err)
+
+	return r, nil
+}
+
+// dialRPC connects to the RPC listener of a running qri process
+func dialRPC() (*rpc.Client, error) {
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", core.Config.RPC.Port))
+	if err != nil {
+		return nil, err
+	}
+	return rpc.NewClient(conn), nil
+}
+
+// openRepo opens the fs repo at QriRepoPath, wrapping it in any middleware
+// listed in configuration
+func openRepo(store cafs.Filestore, opts ...func(*config.Repo)) (repo.Repo, error) {
+	opts = append([]func(*config.Repo){repoConfig}, opts...)
+	cfg := config.DefaultRepo()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	r, err := fsrepo.NewRepo(store, core.Config.Profile, QriRepoPath, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := repo.ApplyMiddleware(r, cfg.Middleware)
+	if err != nil {
+		if c, ok := r.(io.Closer); ok {
+			c.Close()
+		}
+		return nil, err
+	}
+	return wrapped, nil
+}
+
+// repoConfig applies the repo section of the loaded configuration
+// when creating a repo
+func repoConfig(c *config.Repo) {
+	if core.Config != nil && core.Config.Repo != nil {
+		*c = *core.Config.Repo
+	}
+}
+
+func getFilestore(online bool) cafs.Filestore {
+	fs, err := newFilestore(online)
 	ExitIfErr(err)
 	return fs
 }
 
+// newFilestore creates the content store specified by configuration
+func newFilestore(online bool) (cafs.Filestore, error) {
+	cfg := config.DefaultStore()
+	if core.Config != nil && core.Config.Store != nil {
+		cfg = core.Config.Store
+	}
+
+	switch cfg.Type {
+	case "ipfs":
+		return ipfs.NewFilestore(func(c *ipfs.StoreCfg) {
+			c.FsRepoPath = IpfsFsPath
+			c.Online = online
+		})
+	case "fs":
+		return fsrepo.NewFilestore(fsStorePath(cfg))
+	default:
+		return nil, fmt.Errorf("unknown store type: %s", cfg.Type)
+	}
+}
+
+// fsStorePath gives the location of an fs store
+func fsStorePath(cfg *config.Store) string {
+	if cfg.Path != "" {
+		return cfg.Path
+	}
+	return filepath.Join(QriRepoPath, "store")
+}
+
 func datasetRequests(online bool) (*core.DatasetRequests, error) {
 	// TODO - bad bad hardcode
 	if conn, err := net.Dial("tcp", ":2504"); err == nil {
@@ -85,6 +198,46 @@ func searchRequests(online bool) (*core.SearchRequests, error) {
 	return core.NewSearchRequests(r, cli), nil
 }
 
+func repoRequests(online bool) (*core.RepoRequests, error) {
+	r, cli, err := repoOrClient(online)
+	if err != nil {
+		return nil, err
+	}
+	return core.NewRepoRequests(r, cli), nil
+}
+
+func analyticsRequests(online bool) (*core.AnalyticsRequests, error) {
+	r, cli, err := repoOrClient(online)
+	if err != nil {
+		return nil, err
+	}
+	return core.NewAnalyticsRequests(r, cli), nil
+}
+
+func tagRequests(online bool) (*core.TagRequests, error) {
+	r, cli, err := repoOrClient(online)
+	if err != nil {
+		return nil, err
+	}
+	return core.NewTagRequests(r, cli), nil
+}
+
+func graphRequests(online bool) (*core.GraphRequests, error) {
+	r, cli, err := repoOrClient(online)
+	if err != nil {
+		return nil, err
+	}
+	return core.NewGraphRequests(r, cli), nil
+}
+
+func updateRequests(online bool) (*core.UpdateRequests, error) {
+	r, cli, err := repoOrClient(online)
+	if err != nil {
+		return nil, err
+	}
+	return core.NewUpdateRequests(r, cli), nil
+}
+
 func historyRequests(online bool) (*core.HistoryRequests, error) {
 	// TODO - bad bad hardcode
 	if conn, err := net.Dial("tcp", ":2504"); err == nil {
@@ -110,6 +263,30 @@ func historyRequests(online bool) (*core.HistoryRequests, error) {
 	return req, nil
 }
 
+func changeRequestRequests(online bool) (*core.ChangeRequestRequests, error) {
+	// TODO - bad bad hardcode
+	if conn, err := net.Dial("tcp", ":2504"); err == nil {
+		return core.NewChangeRequestRequests(nil, rpc.NewClient(conn)), nil
+	}
+
+	if !online {
+		r, cli, err := repoOrClient(online)
+		if err != nil {
+			return nil, err
+		}
+		return core.NewChangeRequestRequests(r, cli), nil
+	}
+
+	n, err := qriNode(online)
+	if err != nil {
+		return nil, err
+	}
+
+	req := core.NewChangeRequestRequests(n.Repo, nil)
+	req.Node = n
+	return req, nil
+}
+
 func peerRequests(online bool) (*core.PeerRequests, error) {
 	// return nil, nil
 
@@ -132,21 +309,26 @@ func repoOrClient(online bool) (repo.Repo, *rpc.Client, error) {
 		return nil, rpcClient, nil
 	}
 
-	if fs, err := ipfs.NewFilestore(func(cfg *ipfs.StoreCfg) {
-		cfg.FsRepoPath = IpfsFsPath
-		cfg.Online = online
-	}); err == nil {
-		r, err := fsrepo.NewRepo(fs, core.Config.Profile, QriRepoPath)
+	if fs, err := newFilestore(online); err == nil {
+		r, err := openRepo(fs)
+		if fsrepo.IsLocked(err) {
+			// another process holds the repo, try to reach it over RPC
+			cli, dialErr := dialRPC()
+			if dialErr != nil {
+				return nil, nil, err
+			}
+			return nil, cli, nil
+		}
 		ExitIfErr(err)
 
 		return r, nil, err
 
 	} else if strings.Contains(err.Error(), "lock") {
-		conn, err := net.Dial("tcp", fmt.Sprintf(":%d", core.Config.RPC.Port))
+		cli, err := dialRPC()
 		if err != nil {
 			return nil, nil, err
 		}
-		return nil, rpc.NewClient(conn), nil
+		return nil, cli, nil
 	} else {
 		return nil, nil, err
 	}
@@ -157,19 +339,16 @@ func repoOrClient(online bool) (repo.Repo, *rpc.Client, error) {
 func qriNode(online bool) (node *p2p.QriNode, err error) {
 	var (
 		r  repo.Repo
-		fs *ipfs.Filestore
+		fs cafs.Filestore
 	)
 
-	fs, err = ipfs.NewFilestore(func(cfg *ipfs.StoreCfg) {
-		cfg.FsRepoPath = IpfsFsPath
-		cfg.Online = online
-	})
+	fs, err = newFilestore(online)
 
 	if err != nil {
 		return
 	}
 
-	r, err = fsrepo.NewRepo(fs, core.Config.Profile, QriRepoPath)
+	r, err = openRepo(fs)
 	if err != nil {
 		return
 	}
@@ -191,3 +370,219 @@ func qriNode(online bool) (node *p2p.QriNode, err error) {
 
 	return
 }
+
+// repoCmd represents commands that inspect & maintain the qri repo
+var repoCmd = &cobra.Command{
+	Use:   "repo",
+	Short: "inspect & maintain your qri repository",
+	Long: `
+repo commands operate on the qri repository stored at $QRI_PATH. The repo
+records which version of the on-disk layout it was written with. When qri
+changes how repos are stored, older repos are upgraded when opened, or 
+explicitly with qri repo migrate. A backup of the repo is made before any 
+migration runs.`,
+	Example: `  # show repo version & size
+  $ qri repo info
+
+  # show how much space each dataset uses
+  $ qri repo stats
+
+  # upgrade the repo to the latest version
+  $ qri repo migrate
+
+  # back up the repo to a file, and restore it on another machine
+  $ qri repo export qri_backup.zip
+  $ qri repo import qri_backup.zip`,
+}
+
+var repoInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "show repo version & storage information",
+	Run: func(cmd *cobra.Command, args []string) {
+		if !QRIRepoInitialized() {
+			ErrExit(fmt.Errorf("no qri repo found, please run `qri setup`"))
+		}
+
+		info, err := fsrepo.ReadInfo(QriRepoPath)
+		ExitIfErr(err)
+		size, err := fsrepo.Size(QriRepoPath)
+		ExitIfErr(err)
+		pending, err := fsrepo.PendingMigrations(QriRepoPath)
+		ExitIfErr(err)
+
+		printInfo("path:    %s", QriRepoPath)
+		printInfo("version: %d (latest: %d)", info.Version, fsrepo.CurrentVersion)
+		if !info.Created.IsZero() {
+			printInfo("created: %s", info.Created.Format(time.RFC1123))
+			printInfo("updated: %s", info.Updated.Format(time.RFC1123))
+		}
+		printInfo("size:    %d bytes", size)
+
+		if len(pending) > 0 {
+			printWarning("%d pending migration(s), run `qri repo migrate` to upgrade:", len(pending))
+			for _, m := range pending {
+				printInfo("  %d: %s", m.Version, m.Description)
+			}
+		}
+	},
+}
+
+var repoMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "upgrade the repo to the latest version",
+	Long: `
+migrate applies any pending migrations to the qri repo, one version at a time.
+Before changing anything, a copy of the repo is saved to the backups directory
+within $QRI_PATH. qri connect must not be running while migrating.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !QRIRepoInitialized() {
+			ErrExit(fmt.Errorf("no qri repo found, please run `qri setup`"))
+		}
+
+		applied, err := fsrepo.Migrate(QriRepoPath)
+		ExitIfErr(err)
+
+		if len(applied) == 0 {
+			printInfo("repo is up to date at version %d", fsrepo.CurrentVersion)
+			return
+		}
+		for _, m := range applied {
+			printInfo("migrated to version %d: %s", m.Version, m.Description)
+		}
+		printSuccess("repo migrated to version %d", fsrepo.CurrentVersion)
+	},
+}
+
+var repoExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "write a backup of the repo to a file",
+	Long: `
+export writes a single zip file holding everything needed to restore the repo 
+elsewhere: dataset references, tags, branches, your profile, known profiles, 
+the event log, and every block of content your datasets depend on. Restore the 
+file with qri repo import.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			ErrExit(fmt.Errorf("please provide a file to export to"))
+		}
+
+		f, err := os.Create(args[0])
+		ExitIfErr(err)
+		defer f.Close()
+
+		m, err := repo.ExportBundle(getRepo(false), f)
+		ExitIfErr(err)
+
+		printSuccess("exported %d datasets & %d blocks to %s", len(m.Refs), len(m.Blocks), args[0])
+	},
+}
+
+var repoImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "restore a repo from a file written by export",
+	Long: `
+import restores a backup written by qri repo export. Imports only work on repos 
+that don't have any datasets yet, so run qri setup on a new machine first. The 
+content of every block is checked against the hash it was exported under.
+
+Your private key is kept in config, not the repo. To act as the profile that 
+owns the imported datasets, copy the profile & private key from the original 
+machine's config.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			ErrExit(fmt.Errorf("please provide a file to import"))
+		}
+
+		f, err := os.Open(args[0])
+		ExitIfErr(err)
+		defer f.Close()
+		fi, err := f.Stat()
+		ExitIfErr(err)
+
+		r := getRepo(false)
+		m, err := repo.ImportBundle(r, f, fi.Size())
+		ExitIfErr(err)
+
+		printSuccess("imported %d datasets & %d blocks from %s", len(m.Refs), len(m.Blocks), args[0])
+		if pro, err := r.Profile(); err == nil && m.Profile != nil && pro.ID != m.Profile.ID {
+			printWarning("imported datasets belong to %s, copy their profile & private key into config to edit them", m.Profile.Peername)
+		}
+	},
+}
+
+var repoStatsCmdFormat string
+
+var repoStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "show how much space datasets use",
+	Long: `
+stats reports what's in the repo & how much space it takes: the number of
+datasets & versions, distinct blocks of content, and bytes used by each
+dataset. A dataset's own bytes are blocks no other dataset uses, shared
+bytes are blocks it has in common with other datasets. Pinned bytes are only
+reported for stores that can check pins. Growth lists the versions & bytes
+added each day, as recorded in the event log.`,
+	Example: `  # show repo statistics as json
+  $ qri repo stats --format json`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		req, err := repoRequests(false)
+		ExitIfErr(err)
+
+		res := &repo.Summary{}
+		err = req.Stats(nil, res)
+		ExitIfErr(err)
+
+		switch repoStatsCmdFormat {
+		case "json":
+			data, err := json.MarshalIndent(res, "", "  ")
+			ExitIfErr(err)
+			fmt.Println(string(data))
+		case "text":
+			printInfo("datasets:  %d", res.Refs)
+			printInfo("versions:  %d", res.Versions)
+			printInfo("blocks:    %d (%d data)", res.Blocks, res.DataBlocks)
+			printInfo("size:      %d bytes", res.Bytes)
+			if res.PinsChecked {
+				printInfo("pinned:    %d bytes", res.PinnedBytes)
+				printInfo("unpinned:  %d bytes", res.UnpinnedBytes)
+			}
+			if res.Unreadable > 0 {
+				printWarning("%d version(s) couldn't be read, run qri fsck for details", res.Unreadable)
+			}
+
+			if len(res.Datasets) > 0 {
+				printInfo("\ndataset\tversions\town bytes\tshared bytes")
+				for _, ds := range res.Datasets {
+					printInfo("%s\t%d\t%d\t%d", ds.Ref.AliasString(), ds.Versions, ds.OwnBytes, ds.SharedBytes)
+				}
+			}
+			if len(res.Growth) > 0 {
+				printInfo("\ndate\tversions\tbytes added\ttotal bytes")
+				for _, g := range res.Growth {
+					printInfo("%s\t%d\t%d\t%d", g.Date.Format("2006-01-02"), g.Versions, g.Bytes, g.TotalBytes)
+				}
+			}
+		default:
+			ErrExit(fmt.Errorf("unrecognized format: %s", repoStatsCmdFormat))
+		}
+	},
+}
+
+func init() {
+	repoStatsCmd.Flags().StringVarP(&repoStatsCmdFormat, "format", "f", "text", "output format. either text or json")
+	repoCmd.AddCommand(repoInfoCmd)
+	repoCmd.AddCommand(repoStatsCmd)
+	repoCmd.AddCommand(repoMigrateCmd)
+	repoCmd.AddCommand(repoExportCmd)
+	repoCmd.AddCommand(repoImportCmd)
+	RootCmd.AddCommand(repoCmd)
+}
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/qri-io/qri/core"
+	"github.com/qri-io/qri/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	requestCreateDataFile      string
+	requestCreateURL           string
+	requestCreateMetaFile      string
+	requestCreateStructureFile string
+	requestCreateTitle         string
+	requestCreateMessage       string
+
+	requestListIncoming bool
+	requestListOutgoing bool
+	requestListStatus   string
+	requestListLimit    int
+	requestListOffset   int
+)
+
+// requestCmd represents commands for working with change requests
+var requestCmd = &cobra.Command{
+	Use:     "request",
+	Aliases: []string{"requests"},
+	Short:   "propose changes to other peers' datasets",
+	Long: `
+Change requests are how you suggest changes to a dataset you don't control.
+A change request holds a new version of someone else's dataset, built on top
+of their latest version. It's sent to the dataset's owner, who can accept it,
+adding your version to their dataset's history, or reject it.
+
+Change requests can only be accepted if the dataset hasn't changed since the
+request was made. Sending & receiving change requests requires a p2p
+connection, so it's best to have qri connect running.`,
+	Example: `  # propose new metadata for b5's world_bank_population dataset
+  $ qri request create b5/world_bank_population --meta meta.json -t "add a description"
+
+  # list change requests made to your datasets
+  $ qri request list --incoming
+
+  # accept a change request
+  $ qri request accept QmVfnp5Gga5FfmZAMnPTVXgi9kAZyGs4zmDpjxzvE9rkFE`,
+}
+
+var requestCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "propose changes to a peer's dataset",
+	Long: `
+create builds a new version of a peer's dataset from the data, metadata and
+structure files you provide, and sends it to them as a change request. Provide
+a dataset path with peername/dataset_name@/ipfs/path to build on a specific
+version, otherwise the latest version is requested from the network. If the
+dataset owner can't be reached, the request is kept and can be sent later with
+qri request send.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			ErrExit(fmt.Errorf("please provide a reference to the dataset to propose changes to"))
+		}
+		if requestCreateMetaFile == "" && requestCreateDataFile == "" && requestCreateStructureFile == "" && requestCreateURL == "" {
+			ErrExit(fmt.Errorf("one of --structure, --meta or --data or --url is required"))
+		}
+
+		ref, err := repo.ParseDatasetRef(args[0])
+		ExitIfErr(err)
+
+		p := &core.CreateChangeRequestParams{
+			Target: ref,
+			SaveParams: core.SaveParams{
+				URL:               requestCreateURL,
+				Title:             requestCreateTitle,
+				Message:           requestCreateMessage,
+				DataFilename:      filepath.Base(requestCreateDataFile),
+				MetadataFilename:  filepath.Base(requestCreateMetaFile),
+				StructureFilename: filepath.Base(requestCreateStructureFile),
+			},
+		}
+
+		dataFile, err := loadFileIfPath(requestCreateDataFile)
+		ExitIfErr(err)
+		metaFile, err := loadFileIfPath(requestCreateMetaFile)
+		ExitIfErr(err)
+		structureFile, err := loadFileIfPath(requestCreateStructureFile)
+		ExitIfErr(err)
+		if dataFile != nil {
+			p.Data = dataFile
+		}
+		if metaFile != nil {
+			p.Metadata = metaFile
+		}
+		if structureFile != nil {
+			p.Structure = structureFile
+		}
+
+		req, err := changeRequestRequests(true)
+		ExitIfErr(err)
+
+		res := &repo.ChangeRequest{}
+		err = req.Create(p, res)
+		ExitIfErr(err)
+
+		printChangeRequest(res)
+		if !res.Sent {
+			printWarning("couldn't reach %s, run `qri request send %s` to try again", res.Target.Peername, res.ID)
+			return
+		}
+		printSuccess("change request sent to %s", res.Target.Peername)
+	},
+}
+
+var requestListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list change requests",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		req, err := changeRequestRequests(false)
+		ExitIfErr(err)
+
+		p := &core.ListChangeRequestsParams{
+			Incoming: requestListIncoming,
+			Outgoing: requestListOutgoing,
+			Status:   repo.ChangeRequestStatus(requestListStatus),
+			Limit:    requestListLimit,
+			Offset:   requestListOffset,
+		}
+		res := []*repo.ChangeRequest{}
+		err = req.List(p, &res)
+		ExitIfErr(err)
+
+		if len(res) == 0 {
+			printInfo("no change requests")
+			return
+		}
+		for _, cr := range res {
+			printChangeRequest(cr)
+			fmt.Println()
+		}
+	},
+}
+
+var requestGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "show details of a change request",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		id := requestID(args)
+		req, err := changeRequestRequests(false)
+		ExitIfErr(err)
+
+		res := &repo.ChangeRequest{}
+		err = req.Get(&id, res)
+		ExitIfErr(err)
+		printChangeRequest(res)
+	},
+}
+
+var requestAcceptCmd = &cobra.Command{
+	Use:   "accept",
+	Short: "accept a change request, adding the proposed version to your dataset",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		id := requestID(args)
+		req, err := changeRequestRequests(true)
+		ExitIfErr(err)
+
+		res := &repo.DatasetRef{}
+		err = req.Accept(&id, res)
+		ExitIfErr(err)
+		printSuccess("change request accepted, dataset updated: %s", res)
+	},
+}
+
+var requestRejectCmd = &cobra.Command{
+	Use:   "reject",
+	Short: "reject a change request",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		id := requestID(args)
+		req, err := changeRequestRequests(true)
+		ExitIfErr(err)
+
+		res := &repo.ChangeRequest{}
+		err = req.Reject(&id, res)
+		ExitIfErr(err)
+		printSuccess("change request %s rejected", res.ID)
+	},
+}
+
+var requestSendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "send a change request to the owner of the dataset it changes",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		id := requestID(args)
+		req, err := changeRequestRequests(true)
+		ExitIfErr(err)
+
+		res := &repo.ChangeRequest{}
+		err = req.Send(&id, res)
+		ExitIfErr(err)
+		printSuccess("change request sent to %s", res.Target.Peername)
+	},
+}
+
+// requestID gets a change request ID from command args, exiting if
+// one isn't provided
+func requestID(args []string) string {
+	if len(args) < 1 {
+		ErrExit(fmt.Errorf("please provide a change request id"))
+	}
+	return args[0]
+}
+
+func printChangeRequest(cr *repo.ChangeRequest) {
+	printInfo("%s  %s", cr.ID, cr.Status)
+	printInfo("  title:    %s", cr.Title)
+	if cr.Message != "" {
+		printInfo("  message:  %s", cr.Message)
+	}
+	printInfo("  author:   %s", cr.Proposal.Peername)
+	printInfo("  target:   %s", cr.Target)
+	printInfo("  proposal: %s", cr.Proposal.Path)
+	printInfo("  updated:  %s", cr.Updated.Format(time.RFC1123))
+}
+
+func init() {
+	requestCreateCmd.Flags().StringVarP(&requestCreateDataFile, "data", "", "", "data file for the proposed version")
+	requestCreateCmd.Flags().StringVarP(&requestCreateURL, "url", "", "", "url to fetch data for the proposed version from")
+	requestCreateCmd.Flags().StringVarP(&requestCreateMetaFile, "meta", "", "", "metadata.json file")
+	requestCreateCmd.Flags().StringVarP(&requestCreateStructureFile, "structure", "", "", "structure.json file")
+	requestCreateCmd.Flags().StringVarP(&requestCreateTitle, "title", "t", "", "title of the change request. required")
+	requestCreateCmd.Flags().StringVarP(&requestCreateMessage, "message", "m", "", "description of proposed changes")
+
+	requestListCmd.Flags().BoolVarP(&requestListIncoming, "incoming", "", false, "only list change requests made to your datasets")
+	requestListCmd.Flags().BoolVarP(&requestListOutgoing, "outgoing", "", false, "only list change requests you've made")
+	requestListCmd.Flags().StringVarP(&requestListStatus, "status", "s", "", "only list change requests with status: open, accepted, or rejected")
+	requestListCmd.Flags().IntVarP(&requestListLimit, "limit", "l", 25, "limit results, default 25")
+	requestListCmd.Flags().IntVarP(&requestListOffset, "offset", "o", 0, "offset results, default 0")
+
+	requestCmd.AddCommand(requestCreateCmd)
+	requestCmd.AddCommand(requestListCmd)
+	requestCmd.AddCommand(requestGetCmd)
+	requestCmd.AddCommand(requestAcceptCmd)
+	requestCmd.AddCommand(requestRejectCmd)
+	requestCmd.AddCommand(requestSendCmd)
+	RootCmd.AddCommand(requestCmd)
+}
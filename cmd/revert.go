@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/qri-io/qri/core"
+	"github.com/qri-io/qri/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	revertBranch  string
+	revertTitle   string
+	revertMessage string
+)
+
+var revertCmd = &cobra.Command{
+	Use:   "revert",
+	Short: "roll a dataset back to an earlier version",
+	Long: `
+Revert saves a new version of a dataset with the data, structure & metadata
+of an earlier version. History isn't rewritten: the new version comes after
+the latest version, so the versions being undone stay in the log & can be
+reverted to later on.
+
+The version to revert to is given by path, which you can find with qri log.
+It must be an earlier version of the same dataset.`,
+	Example: `  undo the latest save of me/annual_pop:
+  $ qri log me/annual_pop
+  $ qri revert me/annual_pop@/ipfs/QmZfwmhbcgSDGqGaoMMYx8jxBGauZw75zPjnZAyfwPso7M`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			ErrExit(fmt.Errorf("please provide the version of a dataset to revert to"))
+		}
+
+		ref, err := repo.ParseDatasetRef(args[0])
+		ExitIfErr(err)
+
+		req, err := datasetRequests(false)
+		ExitIfErr(err)
+
+		p := &core.RevertParams{
+			Ref:     ref,
+			Branch:  revertBranch,
+			Title:   revertTitle,
+			Message: revertMessage,
+		}
+		res := repo.DatasetRef{}
+		err = req.Revert(p, &res)
+		ExitIfErr(err)
+
+		if res.Branch != "" {
+			printSuccess("reverted branch %s to %s: %s", res.Branch, ref.Path, res)
+		} else {
+			printSuccess("reverted to %s: %s", ref.Path, res)
+		}
+	},
+}
+
+func init() {
+	revertCmd.Flags().StringVarP(&revertBranch, "branch", "b", "", "branch to revert, defaults to the dataset's main line of history")
+	revertCmd.Flags().StringVarP(&revertTitle, "title", "t", "", "title of commit message for revert")
+	revertCmd.Flags().StringVarP(&revertMessage, "message", "m", "", "commit message for revert")
+	RootCmd.AddCommand(revertCmd)
+}
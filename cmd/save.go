@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/qri-io/dsdiff"
 	"github.com/qri-io/qri/core"
 	"github.com/qri-io/qri/repo"
 	"github.com/spf13/cobra"
@@ -14,6 +16,7 @@ import (
 var (
 	saveDataFile       string
 	saveURL            string
+	saveSourceAuth     string
 	saveMetaFile       string
 	saveStructureFile  string
 	saveTitle          string
@@ -21,6 +24,12 @@ var (
 	savePassive        bool
 	saveRescursive     bool
 	saveShowValidation bool
+	saveBranch         string
+	saveAppend         bool
+	savePatch          bool
+	savePatchDeletes   bool
+	saveKey            []string
+	saveDryRun         bool
 )
 
 // saveCmd represents the save command
@@ -35,8 +44,39 @@ an entry is added to your dataset’s log
 provide a message about what you changed and why. If you don’t provide a message 
 we’ll automatically generate one for you.
 
+Saves extend the latest version of a dataset. Use --branch to save to a named 
+line of history instead, leaving the dataset's latest version alone. Saving to 
+a new branch starts it from the latest version, or the version given in the 
+dataset reference. Saves that don't build on the latest version of a branch 
+are refused, use “qri log --graph [ref]” to see where branches diverge.
+
+Use --append to add rows to the end of a dataset instead of replacing its data. 
+Appended data must be in the same format as the dataset, including any header 
+row, and every row must match the dataset's schema.
+
+Use --patch to update rows by primary key. Rows with keys that aren't in the 
+dataset are added, rows with existing keys replace the rows they match. With 
+--patch-deletes, rows that only have their key set remove the matching row. 
+Declare the primary key with --key, it's remembered for later patches.
+
+Use --dry-run to see what a save would change without saving anything.
+
 Currently you can only save changes to datasets that you control. Tools for 
 collaboration are in the works. Sit tight sportsfans.`,
+	Example: `  save a new version to a branch named "fix":
+	$ qri save --data data.csv -t "fix typos" --branch fix me/dataset_name
+
+  add rows to the end of a dataset:
+	$ qri save --data new_rows.csv --append me/dataset_name
+
+  update rows by the "id" column:
+	$ qri save --data changes.csv --patch --key id me/dataset_name
+
+  preview changes before saving them:
+	$ qri save --data data.csv --dry-run me/dataset_name
+
+  start a branch from an earlier version:
+	$ qri save --data data.csv -t "redo" --branch redo me/dataset_name@/ipfs/QmHashOfVersion`,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		loadConfig()
 	},
@@ -49,8 +89,8 @@ collaboration are in the works. Sit tight sportsfans.`,
 		if len(args) < 1 {
 			ErrExit(fmt.Errorf("please provide the name of an existing dataset so save updates to"))
 		}
-		if saveMetaFile == "" && saveDataFile == "" && saveStructureFile == "" {
-			ErrExit(fmt.Errorf("one of --structure, --meta or --data or --url is required"))
+		if saveMetaFile == "" && saveDataFile == "" && saveStructureFile == "" && saveURL == "" && len(saveKey) == 0 {
+			ErrExit(fmt.Errorf("one of --structure, --meta, --data, --url or --key is required"))
 		}
 
 		ref, err := repo.ParseDatasetRef(args[0])
@@ -67,11 +107,18 @@ collaboration are in the works. Sit tight sportsfans.`,
 			Name:              ref.Name,
 			Peername:          ref.Peername,
 			URL:               saveURL,
+			SourceAuth:        saveSourceAuth,
 			Title:             saveTitle,
 			Message:           saveMessage,
 			DataFilename:      filepath.Base(saveDataFile),
 			MetadataFilename:  filepath.Base(saveMetaFile),
 			StructureFilename: filepath.Base(saveStructureFile),
+			Branch:            saveBranch,
+			PreviousPath:      ref.Path,
+			Append:            saveAppend,
+			Patch:             savePatch,
+			PatchDeletes:      savePatchDeletes,
+			PrimaryKey:        saveKey,
 		}
 
 		if dataFile != nil {
@@ -87,11 +134,27 @@ collaboration are in the works. Sit tight sportsfans.`,
 		req, err := datasetRequests(false)
 		ExitIfErr(err)
 
+		if saveDryRun {
+			preview := &core.SavePreview{}
+			err = req.SaveDryRun(save, preview)
+			ExitIfErr(err)
+			printSavePreview(preview)
+			return
+		}
+
 		res := &repo.DatasetRef{}
 		err = req.Save(save, res)
+		if core.IsSourceNotModified(err) {
+			printInfo("%s, nothing to save", err.Error())
+			return
+		}
 		ExitIfErr(err)
 
-		printSuccess("dataset saved: %s", res)
+		if res.Branch != "" {
+			printSuccess("dataset saved to branch %s: %s", res.Branch, res)
+		} else {
+			printSuccess("dataset saved: %s", res)
+		}
 		if res.Dataset.Structure.ErrCount > 0 {
 			printWarning(fmt.Sprintf("this dataset has %d validation errors", res.Dataset.Structure.ErrCount))
 			if saveShowValidation {
@@ -108,13 +171,40 @@ collaboration are in the works. Sit tight sportsfans.`,
 	},
 }
 
+// printSavePreview shows the commit & changes of a dry-run save
+func printSavePreview(preview *core.SavePreview) {
+	ds := preview.Dataset
+	printInfo("dry run, nothing was saved")
+	if ds.Commit != nil {
+		printInfo("title: %s", ds.Commit.Title)
+	}
+	if ds.Structure != nil {
+		printInfo("entries: %d", ds.Structure.Entries)
+	}
+
+	result, err := dsdiff.MapDiffsToString(preview.Diff, "listKeys")
+	ExitIfErr(err)
+	if strings.TrimSpace(result) == "" {
+		printInfo("no changes")
+		return
+	}
+	printDiffs(result)
+}
+
 func init() {
 	saveCmd.Flags().StringVarP(&saveDataFile, "data", "", "", "data file that forms the dataset")
 	saveCmd.Flags().StringVarP(&saveURL, "url", "", "", "url that data file can be updated from")
+	saveCmd.Flags().StringVarP(&saveSourceAuth, "source-auth", "", "", "name of configured credentials to fetch --url with, defaults to the credentials last used")
 	saveCmd.Flags().StringVarP(&saveMetaFile, "meta", "", "", "metadata.json file")
 	saveCmd.Flags().StringVarP(&saveStructureFile, "structure", "", "", "structure.json file")
 	saveCmd.Flags().StringVarP(&saveTitle, "title", "t", "", "title of commit message for save")
 	saveCmd.Flags().StringVarP(&saveMessage, "message", "m", "", "commit message for save")
 	saveCmd.Flags().BoolVarP(&saveShowValidation, "show-validation", "s", false, "display a list of validation errors upon adding")
+	saveCmd.Flags().StringVarP(&saveBranch, "branch", "b", "", "branch to save to, created if it doesn't exist")
+	saveCmd.Flags().BoolVarP(&saveAppend, "append", "", false, "append data to the end of the dataset's existing data")
+	saveCmd.Flags().BoolVarP(&savePatch, "patch", "", false, "insert & update rows by primary key instead of replacing data")
+	saveCmd.Flags().BoolVarP(&savePatchDeletes, "patch-deletes", "", false, "when patching, remove rows that only have their primary key set")
+	saveCmd.Flags().StringSliceVarP(&saveKey, "key", "k", nil, "fields that identify each row, recorded as the dataset's primary key")
+	saveCmd.Flags().BoolVarP(&saveDryRun, "dry-run", "", false, "show the changes a save would make without saving")
 	RootCmd.AddCommand(saveCmd)
 }
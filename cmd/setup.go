@@ -23,6 +23,7 @@ var (
 	setupRegistry       string
 	setupIPFSConfigData string
 	setupConfigData     string
+	setupStoreType      string
 )
 
 // setupCmd represents the setup command
@@ -78,6 +79,7 @@ func init() {
 	setupCmd.Flags().BoolVarP(&setupRemove, "remove", "", false, "permanently remove qri, overrides all setup options")
 	setupCmd.Flags().StringVarP(&setupRegistry, "registry", "", "", "override default registry URL")
 	setupCmd.Flags().StringVarP(&setupPeername, "peername", "", "", "choose your desired peername")
+	setupCmd.Flags().StringVarP(&setupStoreType, "store", "", "", "content store type, either ipfs or fs. fs stores run without IPFS")
 	setupCmd.Flags().StringVarP(&setupIPFSConfigData, "ipfs-config", "", "", "json-encoded configuration data, specify a filepath with '@' prefix")
 	setupCmd.Flags().StringVarP(&setupConfigData, "conifg-data", "", "", "json-encoded configuration data, specify a filepath with '@' prefix")
 	// setupCmd.Flags().StringVarP(&setupProfileData, "profile", "", "", "json-encoded user profile data, specify a filepath with '@' prefix")
@@ -166,12 +168,17 @@ func doSetup(configData, IPFSConfigData, registry string, anon bool) (err error)
 		cfg.Registry.Location = registry
 	}
 
+	if setupStoreType != "" {
+		cfg.Store.Type = setupStoreType
+	}
+
 	p := core.SetupParams{
 		Config:         cfg,
 		QriRepoPath:    QriRepoPath,
 		ConfigFilepath: configFilepath(),
-		SetupIPFS:      setupIPFS,
-		IPFSFsPath:     IpfsFsPath,
+		// fs stores don't need IPFS
+		SetupIPFS:  setupIPFS && cfg.Store.Type == "ipfs",
+		IPFSFsPath: IpfsFsPath,
 	}
 
 	if IPFSConfigData != "" {
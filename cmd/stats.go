@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/qri/core"
+	"github.com/qri-io/qri/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsCmdLimit  int
+	statsCmdOffset int
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "show how often datasets are used",
+	Long: `
+Stats shows usage counters qri keeps for each dataset in your repo: reads
+through the api, reads with qri data, info requests served to peers, and adds
+of your datasets by peers. Counters are kept per dataset name & accumulate
+across versions. With no arguments stats lists all datasets, most used first.`,
+	Example: `  # list the most used datasets in your repo
+  $ qri stats
+
+  # show counters for a single dataset
+  $ qri stats me/world_bank_population`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		req, err := analyticsRequests(false)
+		ExitIfErr(err)
+
+		outformat := cmd.Flag("format").Value.String()
+		var res interface{}
+
+		if len(args) == 0 {
+			p := &core.ListParams{Limit: statsCmdLimit, Offset: statsCmdOffset}
+			list := []*repo.DatasetStats{}
+			err = req.List(p, &list)
+			ExitIfErr(err)
+			if len(list) == 0 && outformat == "" {
+				printInfo("no dataset usage recorded")
+				return
+			}
+			res = list
+		} else {
+			ref, err := repo.ParseDatasetRef(args[0])
+			ExitIfErr(err)
+			stats := &repo.DatasetStats{}
+			err = req.Get(&ref, stats)
+			ExitIfErr(err)
+			res = []*repo.DatasetStats{stats}
+		}
+
+		switch outformat {
+		case "":
+			for i, s := range res.([]*repo.DatasetStats) {
+				printDatasetStats(i, s)
+			}
+		case dataset.JSONDataFormat.String():
+			data, err := json.MarshalIndent(res, "", "  ")
+			ExitIfErr(err)
+			fmt.Printf("%s\n", string(data))
+		default:
+			ErrExit(fmt.Errorf("unrecognized format: %s", outformat))
+		}
+	},
+}
+
+func printDatasetStats(i int, s *repo.DatasetStats) {
+	printInfo("%d  %s/%s  %d total", i+1, s.Peername, s.Name, s.Total())
+	printInfo("    api reads:          %d", s.Counts[repo.StatAPIReads])
+	printInfo("    data reads:         %d", s.Counts[repo.StatDataReads])
+	printInfo("    peer info requests: %d", s.Counts[repo.StatPeerInfoRequests])
+	printInfo("    peer adds:          %d", s.Counts[repo.StatPeerAdds])
+}
+
+func init() {
+	statsCmd.Flags().IntVarP(&statsCmdLimit, "limit", "l", 25, "limit results, default 25")
+	statsCmd.Flags().IntVarP(&statsCmdOffset, "offset", "o", 0, "offset results, default 0")
+	statsCmd.Flags().StringP("format", "f", "", "set output format [json]")
+	RootCmd.AddCommand(statsCmd)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/qri/core"
+	"github.com/qri-io/qri/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tagCmdDelete bool
+)
+
+// tagCmd represents the tag command
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "name versions of your datasets",
+	Long: `
+Tag gives a name to a version of one of your datasets, so you can refer to it
+with peername/dataset_name@tag instead of copying a hash. Tags are listed with
+your datasets when peers request them, so tags work for your peers too.
+
+With a dataset & tag, tag names the latest version of the dataset, or the
+version given by a path. Tagging an existing tag moves it. With just a dataset
+tag lists the dataset's tags, with no arguments tag lists every tag qri knows
+about.`,
+	Example: `  # tag the latest version of a dataset
+  $ qri tag me/census v2018
+
+  # tag an older version
+  $ qri tag me/census@/ipfs/QmdWJ7RnFj3SdWW85mR4AYP17C8dRPD9eUPyTqUxVyGMgD v2017
+
+  # read data from a tagged version
+  $ qri data me/census@v2017
+
+  # remove a tag
+  $ qri tag --delete me/census@v2017`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 2 {
+			ErrExit(fmt.Errorf("wrong number of arguments. expected qri tag [dataset] [tag]"))
+		}
+
+		req, err := tagRequests(false)
+		ExitIfErr(err)
+
+		if tagCmdDelete {
+			if len(args) != 1 {
+				ErrExit(fmt.Errorf("please provide a tag to delete, eg: me/dataset_name@tag"))
+			}
+			ref, err := repo.ParseDatasetRef(args[0])
+			ExitIfErr(err)
+			ok := false
+			err = req.Untag(&ref, &ok)
+			ExitIfErr(err)
+			printSuccess("removed tag %s@%s", ref.AliasString(), ref.Tag)
+			return
+		}
+
+		if len(args) == 2 {
+			ref, err := repo.ParseDatasetRef(args[0])
+			ExitIfErr(err)
+			res := &repo.DatasetRef{}
+			err = req.Tag(&core.TagParams{Ref: ref, Tag: args[1]}, res)
+			ExitIfErr(err)
+			printSuccess("tagged %s@%s: %s", res.AliasString(), res.Tag, res.Path)
+			return
+		}
+
+		ref := repo.DatasetRef{}
+		if len(args) == 1 {
+			ref, err = repo.ParseDatasetRef(args[0])
+			ExitIfErr(err)
+		}
+		res := []repo.DatasetRef{}
+		err = req.List(&ref, &res)
+		ExitIfErr(err)
+
+		switch outformat := cmd.Flag("format").Value.String(); outformat {
+		case "":
+			if len(res) == 0 {
+				printInfo("no tags")
+				return
+			}
+			for _, t := range res {
+				printInfo("%s@%s  %s", t.AliasString(), t.Tag, t.Path)
+			}
+		case dataset.JSONDataFormat.String():
+			data, err := json.MarshalIndent(res, "", "  ")
+			ExitIfErr(err)
+			fmt.Printf("%s\n", string(data))
+		default:
+			ErrExit(fmt.Errorf("unrecognized format: %s", outformat))
+		}
+	},
+}
+
+func init() {
+	tagCmd.Flags().BoolVarP(&tagCmdDelete, "delete", "d", false, "delete a tag")
+	tagCmd.Flags().StringP("format", "f", "", "set output format [json]")
+	RootCmd.AddCommand(tagCmd)
+}
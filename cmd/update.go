@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qri-io/qri/core"
+	"github.com/qri-io/qri/repo"
+	"github.com/spf13/cobra"
+)
+
+var updateListDue bool
+
+// updateCmd represents commands for updating datasets from their sources
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "update datasets from the urls they were created from",
+	Long: `
+Datasets created from a url remember where their data came from, along with
+how often it's expected to change, as an ISO 8601 repeating interval in the
+accrualPeriodicity field of their metadata. "R/P1W" means every week, and is
+the default for datasets created from a url.
+
+While qri connect is running, datasets you created are re-fetched from their
+url when they're due. A new version is saved only if data has changed. Update
+lets you see the schedule and run updates yourself. Datasets added from other
+peers are updated by their owners.`,
+	Example: `  # show when datasets will next update
+  $ qri update list
+
+  # update every dataset that's due
+  $ qri update run
+
+  # update a dataset now, whether it's due or not
+  $ qri update run me/world_bank_population`,
+}
+
+var updateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list datasets that update from a url",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		req, err := updateRequests(false)
+		ExitIfErr(err)
+
+		res := []core.UpdateJob{}
+		err = req.List(&updateListDue, &res)
+		ExitIfErr(err)
+
+		if len(res) == 0 {
+			printInfo("no datasets to update")
+			return
+		}
+		for _, job := range res {
+			printUpdateJob(job)
+			fmt.Println()
+		}
+	},
+}
+
+var updateRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "update datasets now",
+	Long: `
+run updates the datasets you name, or every dataset that's due if you don't
+name any.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		p := &core.UpdateRunParams{}
+		for _, arg := range args {
+			ref, err := repo.ParseDatasetRef(arg)
+			ExitIfErr(err)
+			p.Refs = append(p.Refs, ref)
+		}
+
+		req, err := updateRequests(false)
+		ExitIfErr(err)
+
+		res := []core.UpdateResult{}
+		err = req.Run(p, &res)
+		ExitIfErr(err)
+
+		if len(res) == 0 {
+			printInfo("no datasets are due to update")
+			return
+		}
+		for _, r := range res {
+			switch r.Status {
+			case core.UpdateUpdated:
+				printSuccess("%s updated: %s", r.Ref.AliasString(), r.Ref)
+			case core.UpdateUnchanged:
+				printInfo("%s is up to date", r.Ref.AliasString())
+			default:
+				printWarning("%s failed to update: %s", r.Ref.AliasString(), r.Error)
+			}
+		}
+	},
+}
+
+func printUpdateJob(job core.UpdateJob) {
+	printInfo("%s", job.Ref.AliasString())
+	printInfo("  url:          %s", job.URL)
+	if job.Periodicity == "" {
+		printInfo("  periodicity:  none, only updates when run")
+	} else {
+		printInfo("  periodicity:  %s", job.Periodicity)
+	}
+	if !job.LastChecked.IsZero() {
+		printInfo("  last checked: %s", job.LastChecked.Format(time.RFC1123))
+	}
+	switch {
+	case job.Error != "":
+		printWarning("  %s", job.Error)
+	case job.Due:
+		printInfo("  next check:   due now")
+	case !job.NextCheck.IsZero():
+		printInfo("  next check:   %s", job.NextCheck.Format(time.RFC1123))
+	}
+}
+
+func init() {
+	updateListCmd.Flags().BoolVarP(&updateListDue, "due", "", false, "only list datasets that are due to update")
+
+	updateCmd.AddCommand(updateListCmd)
+	updateCmd.AddCommand(updateRunCmd)
+	RootCmd.AddCommand(updateCmd)
+}
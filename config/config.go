@@ -19,6 +19,7 @@ type Config struct {
 	Profile  *Profile
 	Repo     *Repo
 	Store    *Store
+	Fetch    *Fetch
 	P2P      *P2P
 	Registry *Registry
 
@@ -35,6 +36,7 @@ func DefaultConfig() *Config {
 		Profile:  DefaultProfile(),
 		Repo:     DefaultRepo(),
 		Store:    DefaultStore(),
+		Fetch:    DefaultFetch(),
 		Registry: DefaultRegistry(),
 
 		CLI:     DefaultCLI(),
@@ -211,6 +213,12 @@ func (cfg Config) Validate() error {
 	if err := cfg.Store.Validate(); err != nil {
 		return err
 	}
+	// configs written before fetch settings existed don't have them
+	if cfg.Fetch != nil {
+		if err := cfg.Fetch.Validate(); err != nil {
+			return err
+		}
+	}
 	if err := cfg.P2P.Validate(); err != nil {
 		return err
 	}
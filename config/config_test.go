@@ -146,6 +146,13 @@ func TestConfigValidate(t *testing.T) {
 		t.Error("When given bad input in Store, config.Validate did not catch the error.")
 	}
 
+	// Fetch:
+	f := DefaultConfig()
+	f.Fetch.MaxBodySize = -1
+	if err := f.Validate(); err == nil {
+		t.Error("When given bad input in Fetch, config.Validate did not catch the error.")
+	}
+
 	// Logging:
 	l := DefaultConfig()
 	l.Logging.Levels["qriapi"] = "badType"
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qri-io/jsonschema"
+)
+
+// Fetch configures how qri downloads data from urls
+type Fetch struct {
+	// Timeout is the number of seconds a download can take before it's
+	// abandoned, including reading the response
+	Timeout int `json:"timeout"`
+	// MaxBodySize is the largest response to accept, in bytes
+	MaxBodySize int `json:"maxBodySize"`
+	// Retries is the number of times to retry downloads that fail for
+	// reasons that might not last, like a timeout or 503 response
+	Retries int `json:"retries"`
+	// Auth holds named credentials for sources that require them. Datasets
+	// refer to credentials by name, so secrets never end up in a dataset
+	Auth map[string]*SourceAuth `json:"auth,omitempty"`
+}
+
+// SourceAuth is a set of credentials for fetching data from a url. Token &
+// Username are exclusive
+type SourceAuth struct {
+	// Hosts lists the hosts credentials can be sent to, like "example.com"
+	// or "localhost:9000". Hosts without a port match any port. Credentials
+	// are never sent anywhere else
+	Hosts []string `json:"hosts"`
+	// Token is sent as a bearer token
+	Token string `json:"token,omitempty"`
+	// Username & Password are sent as http basic auth
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Headers are added to every request
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// DefaultFetch returns a new default Fetch configuration
+func DefaultFetch() *Fetch {
+	return &Fetch{
+		Timeout:     300,
+		MaxBodySize: 1 << 30,
+		Retries:     3,
+	}
+}
+
+// Validate validates all fields of fetch returning all errors found.
+func (cfg Fetch) Validate() error {
+	schema := jsonschema.Must(`{
+    "$schema": "http://json-schema.org/draft-06/schema#",
+    "title": "Fetch",
+    "description": "Config for downloading data from urls",
+    "type": "object",
+    "required": ["timeout", "maxBodySize", "retries"],
+    "properties": {
+      "timeout": {
+        "description": "Seconds a download can take",
+        "type": "integer",
+        "minimum": 1
+      },
+      "maxBodySize": {
+        "description": "Largest response to accept, in bytes",
+        "type": "integer",
+        "minimum": 1
+      },
+      "retries": {
+        "description": "Number of times to retry failed downloads",
+        "type": "integer",
+        "minimum": 0
+      },
+      "auth": {
+        "description": "Named credentials for sources that require them",
+        "type": "object",
+        "additionalProperties": {
+          "type": "object",
+          "required": ["hosts"],
+          "properties": {
+            "hosts": {
+              "type": "array",
+              "minItems": 1,
+              "items": { "type": "string", "minLength": 1 }
+            },
+            "token": { "type": "string" },
+            "username": { "type": "string" },
+            "password": { "type": "string" },
+            "headers": {
+              "type": "object",
+              "additionalProperties": { "type": "string" }
+            }
+          }
+        }
+      }
+    }
+  }`)
+	if err := validate(schema, &cfg); err != nil {
+		return err
+	}
+	for name, auth := range cfg.Auth {
+		if auth == nil {
+			continue
+		}
+		if auth.Token != "" && auth.Username != "" {
+			return fmt.Errorf("source auth %s: set either a token or a username, not both", name)
+		}
+		for _, host := range auth.Hosts {
+			if strings.Contains(host, "/") {
+				return fmt.Errorf("source auth %s: hosts are names with an optional port, like example.com:8080, got: %s", name, host)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestFetchValidate(t *testing.T) {
+	err := DefaultFetch().Validate()
+	if err != nil {
+		t.Errorf("error validating default fetch: %s", err)
+	}
+
+	bad := DefaultFetch()
+	bad.Timeout = 0
+	if err := bad.Validate(); err == nil {
+		t.Errorf("expected a zero timeout to be invalid")
+	}
+
+	auth := DefaultFetch()
+	auth.Auth = map[string]*SourceAuth{
+		"census": {Hosts: []string{"api.census.gov"}, Token: "secret", Headers: map[string]string{"X-Api-Version": "2"}},
+	}
+	if err := auth.Validate(); err != nil {
+		t.Errorf("error validating fetch with source auth: %s", err)
+	}
+
+	auth.Auth["census"].Hosts = nil
+	if err := auth.Validate(); err == nil {
+		t.Errorf("expected source auth without hosts to be invalid")
+	}
+	auth.Auth["census"].Hosts = []string{"https://api.census.gov"}
+	if err := auth.Validate(); err == nil {
+		t.Errorf("expected source auth with a url for a host to be invalid")
+	}
+	auth.Auth["census"].Hosts = []string{"api.census.gov"}
+
+	auth.Auth["census"].Username = "user"
+	if err := auth.Validate(); err == nil {
+		t.Errorf("expected source auth with both a token & username to be invalid")
+	}
+}
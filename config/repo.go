@@ -4,8 +4,17 @@ import "github.com/qri-io/jsonschema"
 
 // Repo configures a qri repo
 type Repo struct {
+	// Middleware names repo middleware to wrap the repo with, in the order
+	// calls pass through them. Names can carry an argument after a colon,
+	// eg: ["audit", "quota:100"]. Available middleware: read_only, audit, quota
 	Middleware []string `json:"middleware"`
-	Type       string   `json:"type"`
+	// Type of repo. "fs" keeps dataset references in a json file,
+	// "fs_leveldb" keeps references in an on-disk leveldb database
+	Type string `json:"type"`
+	// ReadOnly opens the repo with a shared lock, allowing any number of
+	// read-only processes to use the repo at once. ReadOnly is set per-process
+	// and isn't saved with configuration
+	ReadOnly bool `json:"-"`
 }
 
 // DefaultRepo creates & returns a new default repo configuration
@@ -26,7 +35,7 @@ func (cfg Repo) Validate() error {
     "required": ["middleware", "type"],
     "properties": {
       "middleware": {
-        "description": "Middleware packages that need to be applied to the repo",
+        "description": "Named middleware to wrap the repo with, in order. Names may carry an argument after a colon",
         "type": "array",
         "items": {
           "type": "string"
@@ -36,7 +45,8 @@ func (cfg Repo) Validate() error {
         "description": "Type of repository",
         "type": "string",
         "enum": [
-          "fs"
+          "fs",
+          "fs_leveldb"
         ]
       }
     }
@@ -4,7 +4,12 @@ import "github.com/qri-io/jsonschema"
 
 // Store configures a qri content addessed file store (cafs)
 type Store struct {
+	// Type of store. "ipfs" stores content in an IPFS repo, "fs" stores
+	// content in a plain directory, for running without IPFS
 	Type string `json:"type"`
+	// Path to an fs store. Defaults to a "store" directory within the
+	// qri repo. Ignored by ipfs stores, which use $IPFS_PATH
+	Path string `json:"path,omitempty"`
 }
 
 // DefaultStore returns a new default Store configuration
@@ -27,8 +32,13 @@ func (cfg Store) Validate() error {
         "description": "Type of store",
         "type": "string",
         "enum": [
-          "ipfs"
+          "ipfs",
+          "fs"
         ]
+      },
+      "path": {
+        "description": "Path to store content in, only used by fs stores",
+        "type": "string"
       }
     }
   }`)
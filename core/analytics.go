@@ -0,0 +1,76 @@
+package core
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"github.com/qri-io/qri/repo"
+)
+
+// AnalyticsRequests encapsulates business logic for reading the usage
+// counters a repo keeps for its datasets
+type AnalyticsRequests struct {
+	repo repo.Repo
+	cli  *rpc.Client
+}
+
+// CoreRequestsName implements the Requests interface
+func (AnalyticsRequests) CoreRequestsName() string { return "analytics" }
+
+// NewAnalyticsRequests creates an AnalyticsRequests pointer from either a
+// repo or an rpc.Client
+func NewAnalyticsRequests(r repo.Repo, cli *rpc.Client) *AnalyticsRequests {
+	if r != nil && cli != nil {
+		panic(fmt.Errorf("both repo and client supplied to NewAnalyticsRequests"))
+	}
+	return &AnalyticsRequests{
+		repo: r,
+		cli:  cli,
+	}
+}
+
+// List gets usage counters for all datasets, most used first
+func (r *AnalyticsRequests) List(p *ListParams, res *[]*repo.DatasetStats) error {
+	if r.cli != nil {
+		return r.cli.Call("AnalyticsRequests.List", p, res)
+	}
+
+	stats, err := r.repo.Analytics().Stats(p.Limit, p.Offset)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error listing dataset stats: %s", err.Error())
+	}
+	*res = stats
+	return nil
+}
+
+// Get gets usage counters for a single dataset. Datasets that have never
+// been used have all-zero counters
+func (r *AnalyticsRequests) Get(ref *repo.DatasetRef, res *repo.DatasetStats) error {
+	if r.cli != nil {
+		return r.cli.Call("AnalyticsRequests.Get", ref, res)
+	}
+
+	if err := repo.CanonicalizeDatasetRef(r.repo, ref); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error canonicalizing reference: %s", err.Error())
+	}
+	if ref.Peername == "" || ref.Name == "" {
+		return fmt.Errorf("peername & name are required to get dataset stats")
+	}
+
+	stats, err := r.repo.Analytics().DatasetStats(*ref)
+	if err == repo.ErrNotFound {
+		stats = &repo.DatasetStats{
+			Peername:  ref.Peername,
+			ProfileID: ref.ProfileID,
+			Name:      ref.Name,
+			Counts:    map[repo.StatType]int{},
+		}
+	} else if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error getting dataset stats: %s", err.Error())
+	}
+	*res = *stats
+	return nil
+}
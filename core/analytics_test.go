@@ -0,0 +1,61 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/qri-io/qri/repo"
+	testrepo "github.com/qri-io/qri/repo/test"
+)
+
+func TestAnalyticsRequests(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	req := NewAnalyticsRequests(mr, nil)
+
+	movies, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "movies"})
+	if err != nil {
+		t.Errorf("error getting ref: %s", err.Error())
+		return
+	}
+
+	// reading data should count against the dataset it belongs to
+	dsr := NewDatasetRequests(mr, nil)
+	if err := dsr.StructuredData(&StructuredDataParams{Path: movies.Path, Ref: movies, Limit: 1}, &StructuredData{}); err != nil {
+		t.Errorf("error reading data: %s", err.Error())
+		return
+	}
+	if err := mr.Analytics().IncrementStat(movies, repo.StatPeerAdds); err != nil {
+		t.Errorf("error incrementing stat: %s", err.Error())
+		return
+	}
+
+	got := &repo.DatasetStats{}
+	if err := req.Get(&repo.DatasetRef{Peername: "peer", Name: "movies"}, got); err != nil {
+		t.Errorf("error getting stats: %s", err.Error())
+		return
+	}
+	if got.Counts[repo.StatDataReads] != 1 || got.Counts[repo.StatPeerAdds] != 1 {
+		t.Errorf("unexpected counts: %v", got.Counts)
+	}
+
+	unused := &repo.DatasetStats{}
+	if err := req.Get(&repo.DatasetRef{Peername: "peer", Name: "cities"}, unused); err != nil {
+		t.Errorf("error getting stats for unused dataset: %s", err.Error())
+		return
+	}
+	if unused.Total() != 0 {
+		t.Errorf("expected unused dataset to have no counts. got: %v", unused.Counts)
+	}
+
+	list := []*repo.DatasetStats{}
+	if err := req.List(&ListParams{Limit: 10}, &list); err != nil {
+		t.Errorf("error listing stats: %s", err.Error())
+		return
+	}
+	if len(list) != 1 || list[0].Name != "movies" || list[0].Total() != 2 {
+		t.Errorf("expected only movies to be listed with 2 counts. got: %v", list)
+	}
+}
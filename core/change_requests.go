@@ -0,0 +1,426 @@
+package core
+
+import (
+	"fmt"
+	"net/rpc"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/dsfs"
+	"github.com/qri-io/qri/p2p"
+	"github.com/qri-io/qri/repo"
+	"github.com/qri-io/qri/repo/actions"
+)
+
+// ChangeRequestRequests encapsulates business logic for proposing changes
+// to other peers' datasets, and deciding on changes proposed to ours
+type ChangeRequestRequests struct {
+	repo repo.Repo
+	cli  *rpc.Client
+	Node *p2p.QriNode
+	// Remote is true for requests made on behalf of other machines, like
+	// api & rpc callers, which can't read from local sources
+	Remote bool
+}
+
+// CoreRequestsName implements the Requests interface
+func (ChangeRequestRequests) CoreRequestsName() string { return "change_requests" }
+
+// NewChangeRequestRequests creates a ChangeRequestRequests pointer from
+// either a repo or an rpc.Client
+func NewChangeRequestRequests(r repo.Repo, cli *rpc.Client) *ChangeRequestRequests {
+	if r != nil && cli != nil {
+		panic(fmt.Errorf("both repo and client supplied to NewChangeRequestRequests"))
+	}
+	return &ChangeRequestRequests{
+		repo: r,
+		cli:  cli,
+	}
+}
+
+// CreateChangeRequestParams defines parameters for creating a change request
+type CreateChangeRequestParams struct {
+	// Target is the dataset to propose changes to. If Target.Path is empty
+	// the latest version is requested from the network
+	Target repo.DatasetRef
+	// SaveParams describe the proposed changes. Title is required, Name &
+	// Peername are ignored
+	SaveParams
+}
+
+// Create builds a proposed version on top of a peer's dataset & sends it to
+// them as a change request. Change requests that can't be sent are kept, and
+// can be sent later with Send
+func (r *ChangeRequestRequests) Create(p *CreateChangeRequestParams, res *repo.ChangeRequest) error {
+	if r.cli != nil {
+		return r.cli.Call("ChangeRequestRequests.Create", p, res)
+	}
+
+	if p.Title == "" {
+		return fmt.Errorf("a title is required to create a change request")
+	}
+
+	pro, err := r.repo.Profile()
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error getting profile: %s", err.Error())
+	}
+
+	target := p.Target
+	if err := repo.CanonicalizeDatasetRef(r.repo, &target); err != nil {
+		return fmt.Errorf("error canonicalizing target reference: %s", err.Error())
+	}
+	if target.ProfileID == "" {
+		return fmt.Errorf("unknown peer: %s", target.Peername)
+	}
+	if target.ProfileID == pro.ID {
+		return fmt.Errorf("can't create a change request for your own dataset, use save instead")
+	}
+	if target.Path == "" {
+		if r.Node == nil {
+			return fmt.Errorf("need a dataset path or a p2p connection to find the latest version of %s", target.AliasString())
+		}
+		if err := r.latestVersion(&target); err != nil {
+			return fmt.Errorf("error finding latest version of %s: %s", target.AliasString(), err.Error())
+		}
+	}
+
+	if target.Dataset, err = fetchDataset(r.repo.Store(), target.Path); err != nil {
+		return err
+	}
+
+	dsr := &DatasetRequests{repo: actions.Dataset{r.repo}, Remote: r.Remote}
+	ds, dataf, err := dsr.prepareSave(&target, &p.SaveParams)
+	if err != nil {
+		return err
+	}
+	defer dataf.Close()
+
+	path, err := dsr.repo.WriteDataset(ds, dataf, true)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error creating proposed dataset: %s", err.Error())
+	}
+
+	target.Dataset = nil
+	proposal := repo.DatasetRef{
+		Peername:  pro.Peername,
+		ProfileID: pro.ID,
+		Name:      target.Name,
+		Path:      path.String(),
+	}
+	id, err := repo.NewChangeRequestID(target.Path, proposal.Path)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	cr := &repo.ChangeRequest{
+		ID:       id,
+		Created:  now,
+		Updated:  now,
+		Status:   repo.CRStatusOpen,
+		Title:    p.Title,
+		Message:  p.Message,
+		Target:   target,
+		Proposal: proposal,
+	}
+	if err := r.repo.ChangeRequests().PutChangeRequest(cr); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error saving change request: %s", err.Error())
+	}
+
+	if r.Node != nil && r.Node.Online {
+		if err := r.send(cr); err != nil {
+			log.Infof("change request %s wasn't sent: %s", cr.ID, err.Error())
+		}
+	}
+
+	*res = *cr
+	return nil
+}
+
+// latestVersion asks the owner of a dataset for the path of its latest
+// version by paging through their dataset list
+func (r *ChangeRequestRequests) latestVersion(ref *repo.DatasetRef) error {
+	pro, err := r.repo.Profiles().GetProfile(ref.ProfileID)
+	if err != nil {
+		return err
+	}
+	ids := pro.PeerIDs()
+	if len(ids) == 0 {
+		return fmt.Errorf("couldn't find a peer address for profile: %s", pro.ID)
+	}
+
+	for offset := 0; ; {
+		refs, err := r.Node.RequestDatasetsList(ids[0], p2p.DatasetsListParams{Offset: offset})
+		if err != nil {
+			return err
+		}
+		for _, got := range refs {
+			if got.Name == ref.Name {
+				ref.Path = got.Path
+				return nil
+			}
+		}
+		if len(refs) == 0 {
+			return fmt.Errorf("%s has no dataset named %s", ref.Peername, ref.Name)
+		}
+		offset += len(refs)
+	}
+}
+
+// Send delivers a change request to the owner of the dataset it targets.
+// Only open change requests made by this repo's profile can be sent
+func (r *ChangeRequestRequests) Send(id *string, res *repo.ChangeRequest) error {
+	if r.cli != nil {
+		return r.cli.Call("ChangeRequestRequests.Send", id, res)
+	}
+
+	if r.Node == nil || !r.Node.Online {
+		return fmt.Errorf("sending change requests requires a p2p connection")
+	}
+
+	cr, err := r.repo.ChangeRequests().GetChangeRequest(*id)
+	if err != nil {
+		return fmt.Errorf("error getting change request: %s", err.Error())
+	}
+	pro, err := r.repo.Profile()
+	if err != nil {
+		return fmt.Errorf("error getting profile: %s", err.Error())
+	}
+	if cr.Incoming(pro.ID) {
+		return fmt.Errorf("can only send change requests made by %s", pro.Peername)
+	}
+	if cr.Status != repo.CRStatusOpen {
+		return fmt.Errorf("change request is already %s", cr.Status)
+	}
+
+	if err := r.send(cr); err != nil {
+		return fmt.Errorf("error sending change request: %s", err.Error())
+	}
+	*res = *cr
+	return nil
+}
+
+// send delivers a change request, recording that it was sent
+func (r *ChangeRequestRequests) send(cr *repo.ChangeRequest) error {
+	if err := r.Node.SendChangeRequest(cr); err != nil {
+		return err
+	}
+	cr.Sent = true
+	cr.Updated = time.Now()
+	return r.repo.ChangeRequests().PutChangeRequest(cr)
+}
+
+// ListChangeRequestsParams defines parameters for listing change requests
+type ListChangeRequestsParams struct {
+	// Incoming lists only change requests made to this repo's datasets
+	Incoming bool
+	// Outgoing lists only change requests made by this repo's profile
+	Outgoing bool
+	// Status limits results to change requests with a given status
+	Status repo.ChangeRequestStatus
+	Limit  int
+	Offset int
+}
+
+// List lists change requests, most recently updated first
+func (r *ChangeRequestRequests) List(p *ListChangeRequestsParams, res *[]*repo.ChangeRequest) error {
+	if r.cli != nil {
+		return r.cli.Call("ChangeRequestRequests.List", p, res)
+	}
+
+	if p.Incoming && p.Outgoing {
+		return fmt.Errorf("can't limit results to both incoming & outgoing change requests")
+	}
+	// ensure valid limit value
+	if p.Limit <= 0 {
+		p.Limit = 25
+	}
+	// ensure valid offset value
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+
+	pro, err := r.repo.Profile()
+	if err != nil {
+		return fmt.Errorf("error getting profile: %s", err.Error())
+	}
+	all, err := r.repo.ChangeRequests().ListChangeRequests(-1, 0)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error listing change requests: %s", err.Error())
+	}
+
+	crs := make([]*repo.ChangeRequest, 0, len(all))
+	for _, cr := range all {
+		incoming := cr.Incoming(pro.ID)
+		if (p.Incoming && !incoming) || (p.Outgoing && incoming) {
+			continue
+		}
+		if p.Status != "" && cr.Status != p.Status {
+			continue
+		}
+		crs = append(crs, cr)
+	}
+
+	*res = repo.PageChangeRequests(crs, p.Limit, p.Offset)
+	return nil
+}
+
+// Get fetches a change request by ID
+func (r *ChangeRequestRequests) Get(id *string, res *repo.ChangeRequest) error {
+	if r.cli != nil {
+		return r.cli.Call("ChangeRequestRequests.Get", id, res)
+	}
+
+	cr, err := r.repo.ChangeRequests().GetChangeRequest(*id)
+	if err != nil {
+		return fmt.Errorf("error getting change request: %s", err.Error())
+	}
+	*res = *cr
+	return nil
+}
+
+// Accept merges the proposed version of an incoming change request by
+// fast-forwarding the target dataset to it. The target dataset must not
+// have changed since the change request was made
+func (r *ChangeRequestRequests) Accept(id *string, res *repo.DatasetRef) error {
+	if r.cli != nil {
+		return r.cli.Call("ChangeRequestRequests.Accept", id, res)
+	}
+
+	cr, err := r.incoming(*id)
+	if err != nil {
+		return err
+	}
+
+	current, err := r.repo.GetRef(repo.DatasetRef{Peername: cr.Target.Peername, Name: cr.Target.Name})
+	if err != nil {
+		return fmt.Errorf("error getting %s: %s", cr.Target.AliasString(), err.Error())
+	}
+	if current.Path != cr.Target.Path {
+		return fmt.Errorf("%s has changed since this change request was made, it can no longer be accepted", cr.Target.AliasString())
+	}
+
+	store := r.repo.Store()
+	ds, err := fetchDataset(store, cr.Proposal.Path)
+	if err != nil {
+		return err
+	}
+	if ds.PreviousPath != cr.Target.Path {
+		return fmt.Errorf("proposed version doesn't build on %s", cr.Target.Path)
+	}
+
+	next := repo.DatasetRef{
+		Peername:  current.Peername,
+		ProfileID: current.ProfileID,
+		Name:      current.Name,
+		Path:      cr.Proposal.Path,
+	}
+	if err := r.repo.DeleteRef(current); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error removing previous reference: %s", err.Error())
+	}
+	if err := r.repo.PutRef(next); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error putting dataset reference: %s", err.Error())
+	}
+	if err := r.repo.LogEvent(repo.ETDsCreated, next); err != nil {
+		log.Debug(err.Error())
+	}
+
+	if err := r.decide(cr, repo.CRStatusAccepted); err != nil {
+		return err
+	}
+
+	next.Dataset = ds
+	*res = next
+	return nil
+}
+
+// Reject declines an incoming change request
+func (r *ChangeRequestRequests) Reject(id *string, res *repo.ChangeRequest) error {
+	if r.cli != nil {
+		return r.cli.Call("ChangeRequestRequests.Reject", id, res)
+	}
+
+	cr, err := r.incoming(*id)
+	if err != nil {
+		return err
+	}
+	if err := r.decide(cr, repo.CRStatusRejected); err != nil {
+		return err
+	}
+	*res = *cr
+	return nil
+}
+
+// incoming gets an open change request made to one of this repo's datasets
+func (r *ChangeRequestRequests) incoming(id string) (*repo.ChangeRequest, error) {
+	cr, err := r.repo.ChangeRequests().GetChangeRequest(id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting change request: %s", err.Error())
+	}
+	pro, err := r.repo.Profile()
+	if err != nil {
+		return nil, fmt.Errorf("error getting profile: %s", err.Error())
+	}
+	if !cr.Incoming(pro.ID) {
+		return nil, fmt.Errorf("only the owner of %s can decide on this change request", cr.Target.AliasString())
+	}
+	if cr.Status != repo.CRStatusOpen {
+		return nil, fmt.Errorf("change request is already %s", cr.Status)
+	}
+	return cr, nil
+}
+
+// decide records the status of a change request, notifying its author
+// if we're connected
+func (r *ChangeRequestRequests) decide(cr *repo.ChangeRequest, status repo.ChangeRequestStatus) error {
+	cr.Status = status
+	cr.Updated = time.Now()
+	if err := r.repo.ChangeRequests().PutChangeRequest(cr); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error saving change request: %s", err.Error())
+	}
+
+	if r.Node != nil && r.Node.Online {
+		if err := r.Node.SendChangeRequestStatus(cr); err != nil {
+			log.Infof("couldn't notify %s that change request %s was %s: %s", cr.Proposal.Peername, cr.ID, status, err.Error())
+		}
+	}
+	return nil
+}
+
+// fetchDataset makes sure the dataset package at path is in the store,
+// pinning it if the store supports pinning
+func fetchDataset(store cafs.Filestore, path string) (*dataset.Dataset, error) {
+	key := datastore.NewKey(strings.TrimSuffix(path, "/"+dsfs.PackageFileDataset.String()))
+
+	if fetcher, ok := store.(cafs.Fetcher); ok {
+		if _, err := fetcher.Fetch(cafs.SourceAny, key); err != nil {
+			return nil, fmt.Errorf("error fetching dataset %s: %s", path, err.Error())
+		}
+	} else if has, err := store.Has(key); err != nil || !has {
+		return nil, fmt.Errorf("dataset %s isn't in the store, and this store can't fetch content", key.String())
+	}
+
+	if pinner, ok := store.(cafs.Pinner); ok {
+		if err := pinner.Pin(key, true); err != nil {
+			log.Debug(err.Error())
+			return nil, fmt.Errorf("error pinning dataset %s: %s", path, err.Error())
+		}
+	}
+
+	ds, err := dsfs.LoadDataset(store, datastore.NewKey(path))
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error loading dataset %s: %s", path, err.Error())
+	}
+	return ds, nil
+}
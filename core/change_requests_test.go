@@ -0,0 +1,110 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/qri-io/qri/repo"
+	"github.com/qri-io/qri/repo/profile"
+	testrepo "github.com/qri-io/qri/repo/test"
+)
+
+func TestChangeRequestRequests(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	req := NewChangeRequestRequests(mr, nil)
+
+	other := &profile.Profile{ID: profile.IDB58MustDecode("QmYCvbfNbCwFR45HiNP45rwJgvatpiW38D961L5qAhUM5Y"), Peername: "other"}
+	if err := mr.Profiles().PutProfile(other); err != nil {
+		t.Errorf("error putting profile: %s", err.Error())
+		return
+	}
+	movies, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "movies"})
+	if err != nil {
+		t.Errorf("error getting ref: %s", err.Error())
+		return
+	}
+
+	// propose a change to a copy of movies owned by another peer
+	cr := &repo.ChangeRequest{}
+	p := &CreateChangeRequestParams{
+		Target: repo.DatasetRef{Peername: "other", Name: "movies", Path: movies.Path},
+		SaveParams: SaveParams{
+			Title:            "add a title",
+			MetadataFilename: "meta.json",
+			Metadata:         bytes.NewReader([]byte(`{"title":"movies!"}`)),
+		},
+	}
+	if err := req.Create(p, cr); err != nil {
+		t.Errorf("error creating change request: %s", err.Error())
+		return
+	}
+	if cr.Status != repo.CRStatusOpen || cr.Sent {
+		t.Errorf("expected an open, unsent change request. got status: %s, sent: %t", cr.Status, cr.Sent)
+	}
+	if cr.Target.ProfileID != other.ID {
+		t.Errorf("expected target to be owned by other. got: %s", cr.Target.ProfileID)
+	}
+
+	p.Target = movies
+	if err := req.Create(p, &repo.ChangeRequest{}); err == nil {
+		t.Errorf("expected creating a change request for our own dataset to error")
+	}
+
+	// pretend other sent us the same proposal for our copy of movies
+	incoming := &repo.ChangeRequest{
+		ID:       "incoming",
+		Status:   repo.CRStatusOpen,
+		Title:    cr.Title,
+		Target:   movies,
+		Proposal: repo.DatasetRef{Peername: "other", ProfileID: other.ID, Name: "movies", Path: cr.Proposal.Path},
+	}
+	if err := mr.ChangeRequests().PutChangeRequest(incoming); err != nil {
+		t.Errorf("error putting change request: %s", err.Error())
+		return
+	}
+
+	list := []*repo.ChangeRequest{}
+	if err := req.List(&ListChangeRequestsParams{Incoming: true}, &list); err != nil {
+		t.Errorf("error listing change requests: %s", err.Error())
+		return
+	}
+	if len(list) != 1 || list[0].ID != incoming.ID {
+		t.Errorf("expected only the incoming change request. got: %v", list)
+	}
+
+	if err := req.Accept(&cr.ID, &repo.DatasetRef{}); err == nil {
+		t.Errorf("expected accepting an outgoing change request to error")
+	}
+
+	ref := &repo.DatasetRef{}
+	if err := req.Accept(&incoming.ID, ref); err != nil {
+		t.Errorf("error accepting change request: %s", err.Error())
+		return
+	}
+	if ref.Path != cr.Proposal.Path {
+		t.Errorf("expected movies to fast-forward to %s. got: %s", cr.Proposal.Path, ref.Path)
+	}
+	if ref.Dataset == nil || ref.Dataset.Meta == nil || ref.Dataset.Meta.Title != "movies!" {
+		t.Errorf("expected accepted dataset to include proposed changes")
+	}
+	if got, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "movies"}); err != nil || got.Path != cr.Proposal.Path {
+		t.Errorf("expected movies reference to be updated")
+	}
+
+	if err := req.Reject(&incoming.ID, &repo.ChangeRequest{}); err == nil {
+		t.Errorf("expected rejecting an accepted change request to error")
+	}
+
+	got := &repo.ChangeRequest{}
+	if err := req.Get(&incoming.ID, got); err != nil {
+		t.Errorf("error getting change request: %s", err.Error())
+		return
+	}
+	if got.Status != repo.CRStatusAccepted {
+		t.Errorf("expected change request to be accepted. got: %s", got.Status)
+	}
+}
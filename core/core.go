@@ -28,6 +28,10 @@ func Receivers(node *p2p.QriNode) []Requests {
 	// TODO - horrible hack for meow
 	dsr := NewDatasetRequests(r, nil)
 	dsr.Node = node
+	dsr.Remote = true
+	crr := NewChangeRequestRequests(r, nil)
+	crr.Node = node
+	crr.Remote = true
 
 	return []Requests{
 		dsr,
@@ -35,5 +39,11 @@ func Receivers(node *p2p.QriNode) []Requests {
 		NewPeerRequests(node, nil),
 		NewProfileRequests(r, nil),
 		NewSearchRequests(r, nil),
+		NewRepoRequests(r, nil),
+		crr,
+		NewAnalyticsRequests(r, nil),
+		NewTagRequests(r, nil),
+		NewGraphRequests(r, nil),
+		NewUpdateRequests(r, nil),
 	}
 }
@@ -6,14 +6,11 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"net/rpc"
-	"path/filepath"
 	"strings"
 
 	"github.com/ipfs/go-datastore"
 	"github.com/qri-io/cafs"
-	ipfs "github.com/qri-io/cafs/ipfs"
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/dataset/detect"
 	"github.com/qri-io/dataset/dsfs"
@@ -33,6 +30,9 @@ type DatasetRequests struct {
 	repo actions.Dataset
 	cli  *rpc.Client
 	Node *p2p.QriNode
+	// Remote is true for requests made on behalf of other machines, like
+	// api & rpc callers, which can't read from local sources
+	Remote bool
 }
 
 // Repo exposes the DatasetRequest's repo
@@ -222,7 +222,8 @@ func (r *DatasetRequests) Get(p *repo.DatasetRef, res *repo.DatasetRef) (err err
 type InitParams struct {
 	Peername          string    // name of peer creating this dataset. required.
 	Name              string    // variable name for referring to this dataset. required.
-	URL               string    // url to download data from. either Url or Data is required
+	URL               string    // url to download data from, read by the Source registered for its scheme. either Url or Data is required
+	SourceAuth        string    // name of configured credentials to download URL with. optional.
 	DataFilename      string    // filename of data file. extension is used for filetype detection
 	Data              io.Reader // reader of structured data. either Url or Data is required
 	MetadataFilename  string    // filename of metadata file. optional.
@@ -248,14 +249,21 @@ func (r *DatasetRequests) Init(p *InitParams, res *repo.DatasetRef) error {
 		filename = p.DataFilename
 	)
 
+	var src *SourceData
 	if p.URL != "" {
-		res, err := http.Get(p.URL)
+		if err := checkRemoteSource(r.Remote, p.URL); err != nil {
+			return err
+		}
+		f, err := newFetcher(p.SourceAuth)
 		if err != nil {
-			return fmt.Errorf("error fetching url: %s", err.Error())
+			return err
 		}
-		filename = filepath.Base(p.URL)
-		defer res.Body.Close()
-		rdr = res.Body
+		if src, err = f.fetch(p.URL, nil); err != nil {
+			return err
+		}
+		filename = src.Filename
+		defer src.Body.Close()
+		rdr = src.Body
 	} else if p.Data != nil {
 		rdr = p.Data
 	} else {
@@ -268,14 +276,7 @@ func (r *DatasetRequests) Init(p *InitParams, res *repo.DatasetRef) error {
 		}
 	}
 
-	// TODO - need a better strategy for huge files
-	data, err := ioutil.ReadAll(rdr)
-	if err != nil {
-		log.Debug(err.Error())
-		return fmt.Errorf("error reading file: %s", err.Error())
-	}
-
-	// read structure from InitParams, or detect from data
+	// read structure from InitParams, or detect from the start of data
 	st := &dataset.Structure{}
 	if p.Structure != nil {
 		if err := json.NewDecoder(p.Structure).Decode(st); err != nil {
@@ -283,22 +284,23 @@ func (r *DatasetRequests) Init(p *InitParams, res *repo.DatasetRef) error {
 			return fmt.Errorf("error parsing structure json: %s", err.Error())
 		}
 	} else {
-		st, err = detect.FromReader(filename, bytes.NewReader(data))
+		detected, data, err := detectStructure(filename, rdr)
 		if err != nil {
 			log.Debug(err.Error())
-			return fmt.Errorf("error determining dataset schema: %s", err.Error())
+			return err
 		}
+		st, rdr = detected, data
 	}
 
 	// Ensure that dataset contains valid field names
-	if err = validate.Structure(st); err != nil {
+	if err := validate.Structure(st); err != nil {
 		log.Debug(err.Error())
 		return fmt.Errorf("invalid structure: %s", err.Error())
 	}
 
-	datakey, err := store.Put(cafs.NewMemfileBytes("data."+st.Format.String(), data), false)
+	datakey, err := putData(store, st, rdr)
 	if err != nil {
-		return fmt.Errorf("error putting data file in store: %s", err.Error())
+		return err
 	}
 
 	dataexists, err := repo.HasPath(r.repo, datakey)
@@ -327,17 +329,29 @@ func (r *DatasetRequests) Init(p *InitParams, res *repo.DatasetRef) error {
 	if p.URL != "" {
 		ds.Meta.DownloadPath = p.URL
 		// if we're adding from a dataset url, set a default accrual periodicity of once a week
-		// this'll set us up to re-check urls over time
-		// TODO - make this configurable via a param?
-		ds.Meta.AccrualPeriodicity = "R/P1W"
+		// this'll set us up to re-check urls over time. the update scheduler in
+		// `qri connect` re-fetches the url when it's due
+		if ds.Meta.AccrualPeriodicity == "" {
+			ds.Meta.AccrualPeriodicity = defaultAccrualPeriodicity
+		}
 	}
 
-	dataf := cafs.NewMemfileBytes("data."+st.Format.String(), data)
-	*res, err = r.repo.CreateDataset(name, ds, dataf, true)
+	ds.DataPath = datakey.String()
+
+	dataf, err := storedData(store, st, datakey.String())
+	if err != nil {
+		return fmt.Errorf("error loading data from store: %s", err.Error())
+	}
+	defer dataf.Close()
+
+	*res, err = r.repo.SaveDataset(name, ds, dataf, true)
 	if err != nil {
 		log.Debugf("error creating dataset: %s\n", err.Error())
 		return err
 	}
+	if src != nil {
+		cacheSourceValidators(r.repo.Repo, *res, src.Validators, st.Checksum)
+	}
 
 	return r.repo.ReadDataset(res)
 }
@@ -347,6 +361,7 @@ type SaveParams struct {
 	Name              string    // dataset name
 	Peername          string    // peername
 	URL               string    // string of url to get new data. optional.
+	SourceAuth        string    // name of configured credentials to download URL with. optional, defaults to the credentials URL was last fetched with
 	DataFilename      string    // filename for new data. optional.
 	Data              io.Reader // stream of complete dataset update.
 	MetadataFilename  string    // filename for new data. optional.
@@ -355,96 +370,293 @@ type SaveParams struct {
 	Structure         io.Reader // stream of complete dataset update.
 	Title             string    // save message title. required.
 	Message           string    // save message. optional.
+	Branch            string    // branch to save to. optional, defaults to repo.DefaultBranch
+	PreviousPath      string    // version this save builds on. optional, defaults to the head of Branch
+	Append            bool      // add entries in Data to the end of the previous version's data. optional.
+	Patch             bool      // insert or update entries in Data by primary key. optional.
+	PatchDeletes      bool      // when patching, remove entries that only have their primary key set in Data. optional.
+	PrimaryKey        []string  // fields identifying each entry, recorded in the structure's schema. optional.
 }
 
-// Save adds a history entry, updating a dataset
-// TODO - need to make sure users aren't forking by referncing commits other than tip
-// TODO - currently, if a user adds metadata or structure, but does not add
-// data, we load the data from the previous commit
-// this means that the SAME data is getting saved to the store
-// this bad!
-// should amend dsfs.CreateDataset to compare the data being added,
-// and not add if the hash already exists
-// but still use the hash to add to dataset.DataPath
+// Save adds a history entry, updating a dataset. Saves extend the head of a
+// branch, which is the dataset's reference unless p.Branch names another
+// line of history. Saving to a new branch forks history at p.PreviousPath,
+// or the head of the default branch. Saves to an existing branch that
+// don't build on its head fail with repo.ErrFork
 func (r *DatasetRequests) Save(p *SaveParams, res *repo.DatasetRef) (err error) {
 	if r.cli != nil {
 		return r.cli.Call("DatasetRequests.Save", p, res)
 	}
 
-	var (
-		data     []byte
-		dataf    cafs.File
-		ds       = &dataset.Dataset{}
-		store    = r.repo.Store()
-		filename = p.DataFilename
-	)
+	prevReq, prev, branch, err := r.saveBase(p)
+	if err != nil {
+		return err
+	}
+
+	ds, dataf, err := r.prepareSave(prev, p)
+	if err != nil {
+		return err
+	}
+	defer dataf.Close()
+
+	if branch != "" {
+		return r.saveBranch(branch, prevReq, ds, dataf, res)
+	}
+
+	ref, err := r.repo.SaveDataset(p.Name, ds, dataf, true)
+	if err != nil {
+		fmt.Printf("create ds error: %s\n", err.Error())
+		return err
+	}
+	ref.Dataset = ds
+
+	// *res = repo.DatasetRef{
+	// 	Peername: p.Peername,
+	// 	Name:     p.Name,
+	// 	Path:     dspath.String(),
+	// 	Dataset:  ds,
+	// }
+	*res = ref
 
+	return nil
+}
+
+// saveBase resolves the version a save builds on, returning the reference
+// being saved to, the previous version & the branch being saved to, which
+// is empty for the default branch
+func (r *DatasetRequests) saveBase(p *SaveParams) (*repo.DatasetRef, *repo.DatasetRef, string, error) {
 	prevReq := &repo.DatasetRef{Name: p.Name, Peername: p.Peername}
 
-	if err = repo.CanonicalizeDatasetRef(r.repo, prevReq); err != nil {
-		return fmt.Errorf("error canonicalizing previous dataset reference: %s", err.Error())
+	if err := repo.CanonicalizeDatasetRef(r.repo, prevReq); err != nil {
+		return nil, nil, "", fmt.Errorf("error canonicalizing previous dataset reference: %s", err.Error())
 	}
 
-	prev := &repo.DatasetRef{}
+	branch := p.Branch
+	if branch == repo.DefaultBranch {
+		branch = ""
+	}
+	if branch != "" {
+		if err := r.branchHead(branch, prevReq, p.PreviousPath); err != nil {
+			return nil, nil, "", err
+		}
+	} else if p.PreviousPath != "" && p.PreviousPath != prevReq.Path {
+		return nil, nil, "", fmt.Errorf("%s: %s isn't the latest version of %s. use --branch to save to a new branch", repo.ErrFork.Error(), p.PreviousPath, prevReq.AliasString())
+	}
 
+	prev := &repo.DatasetRef{}
 	if err := r.Get(prevReq, prev); err != nil {
-		return fmt.Errorf("error getting previous dataset: %s", err.Error())
+		return nil, nil, "", fmt.Errorf("error getting previous dataset: %s", err.Error())
+	}
+	return prevReq, prev, branch, nil
+}
+
+// branchHead sets ref.Path to the version a save to branch builds on. Saves
+// to existing branches build on the branch head, new branches fork from
+// previousPath, or the dataset's latest version
+func (r *DatasetRequests) branchHead(branch string, ref *repo.DatasetRef, previousPath string) error {
+	if err := repo.ValidateBranch(branch); err != nil {
+		return err
+	}
+	if _, err := r.repo.Tags().GetTag(repo.DatasetRef{Peername: ref.Peername, Name: ref.Name, Tag: branch}); err == nil {
+		return fmt.Errorf("'%s' is already a tag of %s", branch, ref.AliasString())
+	}
+
+	b, err := r.repo.Branches().GetBranch(repo.DatasetRef{Peername: ref.Peername, Name: ref.Name, Branch: branch})
+	switch err {
+	case nil:
+		if previousPath != "" && previousPath != b.Path {
+			return fmt.Errorf("%s: %s isn't the latest version of %s@%s", repo.ErrFork.Error(), previousPath, ref.AliasString(), branch)
+		}
+		ref.Path = b.Path
+	case repo.ErrNotFound:
+		if previousPath != "" {
+			if err := inHistory(r.repo.Store(), ref.Path, previousPath); err != nil {
+				return err
+			}
+			ref.Path = previousPath
+		}
+	default:
+		log.Debug(err.Error())
+		return fmt.Errorf("error getting branch: %s", err.Error())
+	}
+	return nil
+}
+
+// saveBranch writes a new version of a dataset & moves a branch to it,
+// leaving the dataset's reference alone
+func (r *DatasetRequests) saveBranch(branch string, prev *repo.DatasetRef, ds *dataset.Dataset, dataf cafs.File, res *repo.DatasetRef) error {
+	path, err := r.repo.WriteDataset(ds, dataf, true)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error creating dataset: %s", err.Error())
 	}
 
-	if p.URL == "" && p.Data == nil && p.Metadata == nil && p.Structure == nil {
-		return fmt.Errorf("to save update, need a URL or data file, metadata file, or structure file")
+	ref := repo.DatasetRef{
+		Peername:  prev.Peername,
+		ProfileID: prev.ProfileID,
+		Name:      prev.Name,
+		Branch:    branch,
+		Path:      path.String(),
+	}
+	if err := r.repo.Branches().PutBranch(ref); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error saving branch: %s", err.Error())
+	}
+	if err := r.repo.LogEvent(repo.ETDsCreated, ref); err != nil {
+		log.Debug(err.Error())
+	}
+
+	ref.Dataset = ds
+	*res = ref
+	return nil
+}
+
+// prepareSave builds the next version of a dataset from save parameters,
+// returning the new dataset & its data file. New data is streamed into the
+// store as it's read. If there's no new data, or new data matches the
+// previous version, the previous version's data is used without reading it.
+// Appending adds entries from new data to the end of the previous version's
+// data, and patching inserts or replaces entries by primary key. Saves from
+// a url the dataset's data was last fetched from make a conditional request,
+// returning ErrSourceNotModified if the url reports no changes or gives the
+// same data. The returned dataset's structure describes the stored data,
+// so it can be written without reading data again. Callers must close the
+// returned data file
+func (r *DatasetRequests) prepareSave(prev *repo.DatasetRef, p *SaveParams) (*dataset.Dataset, cafs.File, error) {
+	var (
+		rdr      io.Reader
+		ds       = &dataset.Dataset{}
+		store    = r.repo.Store()
+		filename = p.DataFilename
+		dataPath = prev.Dataset.DataPath
+	)
+
+	if p.URL == "" && p.Data == nil && p.Metadata == nil && p.Structure == nil && len(p.PrimaryKey) == 0 {
+		return nil, nil, fmt.Errorf("to save update, need a URL or data file, metadata file, or structure file")
 	}
 
 	if p.URL != "" && p.Data != nil {
-		return fmt.Errorf("to save update, need either a URL or data file")
+		return nil, nil, fmt.Errorf("to save update, need either a URL or data file")
 	}
 
-	if p.URL != "" {
-		res, err := http.Get(p.URL)
-		if err != nil {
-			return fmt.Errorf("error fetching url: %s", err.Error())
+	if p.Append && p.Patch {
+		return nil, nil, fmt.Errorf("can't append & patch at the same time")
+	}
+	if p.Append || p.Patch {
+		action, doing := "append", "appending"
+		if p.Patch {
+			action, doing = "patch", "patching"
+		}
+		if p.URL == "" && p.Data == nil {
+			return nil, nil, fmt.Errorf("a URL or data file is required to %s", action)
+		}
+		if p.Structure != nil {
+			return nil, nil, fmt.Errorf("can't change structure while %s", doing)
+		}
+		if prev.Dataset.Structure == nil || prev.Dataset.DataPath == "" {
+			return nil, nil, fmt.Errorf("%s has no data to %s", prev.AliasString(), action)
 		}
-		filename = filepath.Base(p.URL)
-		defer res.Body.Close()
-		p.Data = res.Body
 	}
 
-	if p.Data != nil {
-		// TODO - need a better strategy for huge files
-		data, err = ioutil.ReadAll(p.Data)
-		if err != nil {
-			return fmt.Errorf("error reading file: %s", err.Error())
+	var src *SourceData
+	if p.URL != "" {
+		if err := checkRemoteSource(r.Remote, p.URL); err != nil {
+			return nil, nil, err
 		}
-	} else {
-		// load data cause we need something to compare the structure to
-		datafile, err := dsfs.LoadData(store, prev.Dataset)
-		if err != nil {
-			return fmt.Errorf("error loading previous data from filestore: %s", err)
+		// download paths can come from other peers' versions & api metadata,
+		// only refresh local sources this machine added data from itself
+		refresh := prev.Dataset.Meta != nil && prev.Dataset.Meta.DownloadPath == p.URL
+		if refresh && IsLocalSource(p.URL) && !fetchedLocally(r.repo.Repo, prev, p.URL) {
+			return nil, nil, fmt.Errorf("%s wasn't added from %s on this machine, local sources are only re-read for datasets added from them here", prev.AliasString(), p.URL)
 		}
-		// TODO - need a better strategy for huge files
-		data, err = ioutil.ReadAll(datafile)
+		// ask the url if data has changed, unless only some of it's being used
+		var validators *repo.SourceValidators
+		if !p.Append && !p.Patch {
+			validators = sourceValidators(r.repo.Repo, prev, p.URL)
+		}
+		f, err := newFetcher(sourceAuth(r.repo.Repo, prev, p.URL, p.SourceAuth))
 		if err != nil {
-			return fmt.Errorf("error reading file after file was loaded from filestore: %s", err)
+			return nil, nil, err
+		}
+		if src, err = f.fetch(p.URL, validators); err != nil {
+			return nil, nil, err
+		}
+		if src.NotModified {
+			return nil, nil, ErrSourceNotModified
 		}
-		// TODO - need a filename with an extension because that is how
-		// we determine the schema in line 421 in detect.FromReader
-		// however, when reading from IPFS, the datafile.Filename
-		// is an ipfs hash, with no extention
-		// using this janky way of constructing a fake filename
-		// for us to use later when we detect the schema
-		filename = "data." + prev.Dataset.Structure.Format.String()
+		filename = src.Filename
+		defer src.Body.Close()
+		rdr = src.Body
+	} else if p.Data != nil {
+		rdr = p.Data
 	}
 
-	// read structure from SaveParams, or detect from data
-	st := &dataset.Structure{}
+	// read structure from SaveParams, or detect from the start of new data
+	var st *dataset.Structure
 	if p.Structure != nil {
+		st = &dataset.Structure{}
 		if err := json.NewDecoder(p.Structure).Decode(st); err != nil {
-			return fmt.Errorf("error parsing structure json: %s", err.Error())
+			return nil, nil, fmt.Errorf("error parsing structure json: %s", err.Error())
 		}
-	} else {
-		st, err = detect.FromReader(filename, bytes.NewReader(data))
+	}
+
+	var (
+		appended int
+		patched  *PatchStats
+	)
+	if rdr != nil && p.Append {
+		key, combined, added, err := appendData(store, prev.Dataset, rdr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error appending data: %s", err.Error())
+		}
+		st, dataPath, appended = combined, key.String(), added
+	} else if rdr != nil && p.Patch {
+		key := p.PrimaryKey
+		if len(key) == 0 {
+			var err error
+			if key, err = PrimaryKey(prev.Dataset.Structure); err != nil {
+				return nil, nil, err
+			}
+		}
+		if len(key) == 0 {
+			return nil, nil, fmt.Errorf("%s has no primary key, set one to patch", prev.AliasString())
+		}
+		dk, patchedSt, stats, err := patchData(store, prev.Dataset, key, p.PatchDeletes, rdr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error patching data: %s", err.Error())
+		}
+		st, dataPath, patched = patchedSt, dk.String(), &stats
+	} else if rdr != nil {
+		if st == nil {
+			detected, data, err := detectStructure(filename, rdr)
+			if err != nil {
+				return nil, nil, err
+			}
+			st, rdr = detected, data
+		}
+		key, err := putData(store, st, rdr)
 		if err != nil {
-			return fmt.Errorf("error determining dataset schema: %s", err.Error())
+			return nil, nil, err
+		}
+		if prev.Dataset.Structure == nil || st.Checksum != prev.Dataset.Structure.Checksum {
+			dataPath = key.String()
+		}
+	}
+	if src != nil {
+		// validators only apply to versions with this checksum, so it's safe
+		// to record them before the version is saved. appended & patched data
+		// isn't the data fetched, so only the credentials used are kept
+		v, checksum := src.Validators, st.Checksum
+		if p.Append || p.Patch {
+			v, checksum = repo.SourceValidators{URL: v.URL, Auth: v.Auth}, ""
+		}
+		cacheSourceValidators(r.repo.Repo, *prev, v, checksum)
+
+		// refreshing from the same url with nothing else to change
+		unchanged := dataPath == prev.Dataset.DataPath && !p.Append && !p.Patch
+		sameURL := prev.Dataset.Meta != nil && prev.Dataset.Meta.DownloadPath == p.URL
+		if unchanged && sameURL && p.Metadata == nil && p.Structure == nil && len(p.PrimaryKey) == 0 {
+			return nil, nil, ErrSourceNotModified
 		}
 	}
 
@@ -452,15 +664,17 @@ func (r *DatasetRequests) Save(p *SaveParams, res *repo.DatasetRef) (err error)
 	mt := &dataset.Meta{}
 	if p.Metadata != nil {
 		if err := json.NewDecoder(p.Metadata).Decode(mt); err != nil {
-			return fmt.Errorf("error parsing metadata json: %s", err.Error())
+			return nil, nil, fmt.Errorf("error parsing metadata json: %s", err.Error())
 		}
 	}
 	if p.URL != "" {
 		mt.DownloadPath = p.URL
 		// if we're adding from a dataset url, set a default accrual periodicity of once a week
-		// this'll set us up to re-check urls over time
-		// TODO - make this configurable via a param?
-		mt.AccrualPeriodicity = "R/P1W"
+		// this'll set us up to re-check urls over time. periodicities that are
+		// already set are kept
+		if mt.AccrualPeriodicity == "" && (prev.Dataset.Meta == nil || prev.Dataset.Meta.AccrualPeriodicity == "") {
+			mt.AccrualPeriodicity = defaultAccrualPeriodicity
+		}
 	}
 	changes := &dataset.Dataset{
 		Commit:    &dataset.Commit{Title: p.Title, Message: p.Message},
@@ -471,43 +685,59 @@ func (r *DatasetRequests) Save(p *SaveParams, res *repo.DatasetRef) (err error)
 	// add all previous fields and any changes
 	ds.Assign(prev.Dataset, changes)
 	ds.PreviousPath = prev.Path
+	if ds.Structure == nil {
+		return nil, nil, fmt.Errorf("a structure or data file is required, %s has no structure", prev.AliasString())
+	}
 
 	// ds.Assign clobbers empty commit messages with the previous
 	// commit message. So if the peer hasn't provided a message at this point
-	// let's maintain that going into CreateDataset
+	// let's maintain that going into SaveDataset
 	if p.Title == "" {
 		ds.Commit.Title = ""
 	}
 	if p.Message == "" {
 		ds.Commit.Message = ""
 	}
+	if len(p.PrimaryKey) > 0 {
+		if _, err := newEntryKeyer(ds.Structure, p.PrimaryKey); err != nil {
+			return nil, nil, err
+		}
+		if err := SetPrimaryKey(ds.Structure, p.PrimaryKey); err != nil {
+			return nil, nil, err
+		}
+	}
+	if patched != nil {
+		if ds.Commit.Title == "" {
+			ds.Commit.Title = "patched: " + patched.String()
+		}
+		if ds.Commit.Message == "" {
+			ds.Commit.Message = fmt.Sprintf("%d rows inserted, %d rows updated & %d rows deleted", patched.Inserts, patched.Updates, patched.Deletes)
+		}
+	}
+	if p.Append {
+		if ds.Commit.Title == "" {
+			ds.Commit.Title = fmt.Sprintf("appended %d rows", appended)
+		}
+		if ds.Commit.Message == "" {
+			ds.Commit.Message = fmt.Sprintf("appended %d rows to %d existing rows", appended, prev.Dataset.Structure.Entries)
+		}
+	}
 
 	// Assign will assign any previous paths to the current paths
-	// the dsdiff (called in dsfs.CreateDataset), will compare the paths
+	// the dsdiff (called when writing the dataset), will compare the paths
 	// see that they are the same, and claim there are no differences
 	// since we will potentially have changes in the Meta and Structure
 	// we want the differ to have to compare each field
 	// so we reset the paths
 	ds.Meta.SetPath("")
 	ds.Structure.SetPath("")
+	ds.DataPath = dataPath
 
-	dataf = cafs.NewMemfileBytes("data."+st.Format.String(), data)
-	ref, err := r.repo.CreateDataset(p.Name, ds, dataf, true)
+	dataf, err := storedData(store, ds.Structure, dataPath)
 	if err != nil {
-		fmt.Printf("create ds error: %s\n", err.Error())
-		return err
+		return nil, nil, fmt.Errorf("error loading data from store: %s", err.Error())
 	}
-	ref.Dataset = ds
-
-	// *res = repo.DatasetRef{
-	// 	Peername: p.Peername,
-	// 	Name:     p.Name,
-	// 	Path:     dspath.String(),
-	// 	Dataset:  ds,
-	// }
-	*res = ref
-
-	return nil
+	return ds, dataf, nil
 }
 
 // RenameParams defines parameters for Dataset renaming
@@ -558,6 +788,15 @@ func (r *DatasetRequests) Rename(p *RenameParams, res *repo.DatasetRef) (err err
 		return err
 	}
 
+	if err := repo.RenameTags(r.repo.Tags(), p.Current, p.New); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error moving tags: %s", err.Error())
+	}
+	if err := repo.RenameBranches(r.repo.Branches(), p.Current, p.New); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error moving branches: %s", err.Error())
+	}
+
 	ds, err := dsfs.LoadDataset(r.repo.Store(), datastore.NewKey(p.Current.Path))
 	if err != nil {
 		log.Debug(err.Error())
@@ -611,6 +850,15 @@ func (r *DatasetRequests) Remove(p *repo.DatasetRef, ok *bool) (err error) {
 		return
 	}
 
+	if err = repo.DeleteTags(r.repo.Tags(), ref); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error removing tags: %s", err.Error())
+	}
+	if err = repo.DeleteBranches(r.repo.Branches(), ref); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error removing branches: %s", err.Error())
+	}
+
 	*ok = true
 	return nil
 }
@@ -623,6 +871,9 @@ type StructuredDataParams struct {
 	Path          string
 	Limit, Offset int
 	All           bool
+	// Ref names the dataset Path belongs to, counting the read against it
+	// in usage stats. optional
+	Ref repo.DatasetRef
 }
 
 // StructuredData combines data with it's hashed path
@@ -699,6 +950,12 @@ func (r *DatasetRequests) StructuredData(p *StructuredDataParams, data *Structur
 		return fmt.Errorf("error closing row buffer: %s", err.Error())
 	}
 
+	if p.Ref.Name != "" {
+		if err := repo.RecordStat(r.repo, p.Ref, repo.StatDataReads); err != nil {
+			log.Debugf("error recording data read: %s", err.Error())
+		}
+	}
+
 	*data = StructuredData{
 		Path: ds.DataPath,
 		Data: buf.Bytes(),
@@ -721,23 +978,24 @@ func (r *DatasetRequests) Add(ref *repo.DatasetRef, res *repo.DatasetRef) (err e
 			return err
 		}
 	}
+	owner := *ref
 
-	fs, ok := r.repo.Store().(*ipfs.Filestore)
-	if !ok {
-		return fmt.Errorf("can only add datasets when running an IPFS filestore")
-	}
-
+	fs := r.repo.Store()
 	key := datastore.NewKey(strings.TrimSuffix(ref.Path, "/"+dsfs.PackageFileDataset.String()))
 
-	_, err = fs.Fetch(cafs.SourceAny, key)
-	if err != nil {
-		return fmt.Errorf("error fetching file: %s", err.Error())
+	if fetcher, ok := fs.(cafs.Fetcher); ok {
+		if _, err = fetcher.Fetch(cafs.SourceAny, key); err != nil {
+			return fmt.Errorf("error fetching file: %s", err.Error())
+		}
+	} else if has, err := fs.Has(key); err != nil || !has {
+		return fmt.Errorf("dataset %s isn't in the store, and this store can't fetch content", key.String())
 	}
 
-	err = fs.Pin(key, true)
-	if err != nil {
-		log.Debug(err.Error())
-		return fmt.Errorf("error pinning root key: %s", err.Error())
+	if pinner, ok := fs.(cafs.Pinner); ok {
+		if err = pinner.Pin(key, true); err != nil {
+			log.Debug(err.Error())
+			return fmt.Errorf("error pinning root key: %s", err.Error())
+		}
 	}
 
 	path := datastore.NewKey(key.String() + "/" + dsfs.PackageFileDataset.String())
@@ -765,6 +1023,14 @@ func (r *DatasetRequests) Add(ref *repo.DatasetRef, res *repo.DatasetRef) (err e
 
 	ref.Dataset = ds
 
+	if r.Node != nil && r.Node.Online && owner.ProfileID != "" && owner.ProfileID != profile.ID {
+		go func() {
+			if err := r.Node.AnnounceDatasetAdded(owner); err != nil {
+				log.Debugf("error announcing dataset add: %s", err.Error())
+			}
+		}()
+	}
+
 	*res = *ref
 	return
 }
@@ -211,6 +211,201 @@ func TestDatasetRequestsSave(t *testing.T) {
 	}
 }
 
+func TestDatasetRequestsSaveBranch(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	movies, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "movies"})
+	if err != nil {
+		t.Errorf("error getting ref: %s", err.Error())
+		return
+	}
+	meta := func(title string) *bytes.Reader {
+		return bytes.NewReader([]byte(`{"title":"` + title + `"}`))
+	}
+
+	req := NewDatasetRequests(mr, nil)
+	fix := &repo.DatasetRef{}
+	if err := req.Save(&SaveParams{Name: "movies", Peername: "peer", Branch: "fix", Metadata: meta("fix")}, fix); err != nil {
+		t.Errorf("error saving to branch: %s", err.Error())
+		return
+	}
+	if fix.Branch != "fix" || fix.Dataset.PreviousPath != movies.Path {
+		t.Errorf("expected branch fix to start from %s. got: %s from %s", movies.Path, fix.Branch, fix.Dataset.PreviousPath)
+	}
+	if head, _ := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "movies"}); head.Path != movies.Path {
+		t.Errorf("expected saving to a branch to leave the dataset reference alone")
+	}
+
+	bad := []struct {
+		p   *SaveParams
+		err string
+	}{
+		{&SaveParams{Name: "movies", Peername: "peer", Branch: "fix", PreviousPath: movies.Path, Metadata: meta("stale")}, repo.ErrFork.Error() + ": " + movies.Path + " isn't the latest version of peer/movies@fix"},
+		{&SaveParams{Name: "movies", Peername: "peer", PreviousPath: fix.Path, Metadata: meta("stale")}, repo.ErrFork.Error() + ": " + fix.Path + " isn't the latest version of peer/movies. use --branch to save to a new branch"},
+		{&SaveParams{Name: "movies", Peername: "peer", Branch: repo.DefaultBranch + " ", Metadata: meta("bad")}, "invalid branch 'main '. branch names must start with a letter or number, and contain only letters, numbers, '_', '.' or '-'"},
+	}
+	for i, c := range bad {
+		err := req.Save(c.p, &repo.DatasetRef{})
+		if err == nil || err.Error() != c.err {
+			t.Errorf("case %d error mismatch: expected: %s, got: %s", i, c.err, err)
+		}
+	}
+
+	next := &repo.DatasetRef{}
+	if err := req.Save(&SaveParams{Name: "movies", Peername: "peer", Branch: "fix", PreviousPath: fix.Path, Metadata: meta("fix 2")}, next); err != nil {
+		t.Errorf("error saving to branch head: %s", err.Error())
+		return
+	}
+	ref := repo.DatasetRef{Peername: "peer", Name: "movies", Tag: "fix"}
+	if err := repo.CanonicalizeDatasetRef(mr, &ref); err != nil {
+		t.Errorf("error canonicalizing branch reference: %s", err.Error())
+		return
+	}
+	if ref.Path != next.Path || ref.Branch != "fix" {
+		t.Errorf("expected peer/movies@fix to resolve to %s. got: %s", next.Path, ref.Path)
+	}
+}
+
+func TestDatasetRequestsSaveAppend(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	req := NewDatasetRequests(mr, nil)
+	header := "city,pop,avg_age,in_usa\n"
+
+	res := &repo.DatasetRef{}
+	p := &SaveParams{
+		Name:         "cities",
+		Peername:     "peer",
+		DataFilename: "more_cities.csv",
+		Data:         bytes.NewReader([]byte(header + "boston,700000,35.5,true\nmontreal,1700000,41.2,false\n")),
+		Append:       true,
+	}
+	if err := req.Save(p, res); err != nil {
+		t.Errorf("error appending: %s", err.Error())
+		return
+	}
+	if res.Dataset.Structure.Entries != 7 {
+		t.Errorf("expected 7 entries after append. got: %d", res.Dataset.Structure.Entries)
+	}
+	if res.Dataset.Commit.Title != "appended 2 rows" {
+		t.Errorf("expected commit title to record appended rows. got: %s", res.Dataset.Commit.Title)
+	}
+
+	bad := []struct {
+		p   *SaveParams
+		err string
+	}{
+		{&SaveParams{Name: "cities", Peername: "peer", Metadata: bytes.NewReader([]byte(`{"title":"append"}`)), Append: true}, "a URL or data file is required to append"},
+		{&SaveParams{Name: "cities", Peername: "peer", Data: bytes.NewReader([]byte(header)), Append: true}, "error appending data: no entries to append"},
+	}
+	for i, c := range bad {
+		err := req.Save(c.p, &repo.DatasetRef{})
+		if err == nil || err.Error() != c.err {
+			t.Errorf("case %d error mismatch: expected: %s, got: %s", i, c.err, err)
+		}
+	}
+
+	invalid := &SaveParams{Name: "cities", Peername: "peer", Data: bytes.NewReader([]byte(header + "boston,lots,young,yes\n")), Append: true}
+	if err := req.Save(invalid, &repo.DatasetRef{}); err == nil {
+		t.Errorf("expected appending data that doesn't match the schema to error")
+	}
+}
+
+func TestDatasetRequestsSavePatch(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	req := NewDatasetRequests(mr, nil)
+	header := "city,pop,avg_age,in_usa\n"
+	patch := header + "toronto,41000000,55.5,false\nboston,700000,35.5,true\nchatham,,,\n"
+
+	err = req.Save(&SaveParams{Name: "cities", Peername: "peer", Data: bytes.NewReader([]byte(patch)), Patch: true}, &repo.DatasetRef{})
+	if err == nil || err.Error() != "peer/cities has no primary key, set one to patch" {
+		t.Errorf("expected patching without a primary key to error. got: %v", err)
+	}
+
+	res := &repo.DatasetRef{}
+	p := &SaveParams{
+		Name:         "cities",
+		Peername:     "peer",
+		Data:         bytes.NewReader([]byte(patch)),
+		Patch:        true,
+		PatchDeletes: true,
+		PrimaryKey:   []string{"city"},
+	}
+	if err := req.Save(p, res); err != nil {
+		t.Errorf("error patching: %s", err.Error())
+		return
+	}
+	if res.Dataset.Structure.Entries != 5 {
+		t.Errorf("expected 5 entries after patch. got: %d", res.Dataset.Structure.Entries)
+	}
+	if expect := "patched: 1 inserted, 1 updated, 1 deleted"; res.Dataset.Commit.Title != expect {
+		t.Errorf("commit title mismatch. expected: %s, got: %s", expect, res.Dataset.Commit.Title)
+	}
+	if key, err := PrimaryKey(res.Dataset.Structure); err != nil || len(key) != 1 || key[0] != "city" {
+		t.Errorf("expected primary key to be recorded in schema. got: %v, %v", key, err)
+	}
+
+	// the primary key is kept, so it's not needed again
+	again := &SaveParams{Name: "cities", Peername: "peer", Data: bytes.NewReader([]byte(header + "boston,700000,35.5,true\n")), Patch: true}
+	if err := req.Save(again, &repo.DatasetRef{}); err == nil || err.Error() != "error patching data: patch makes no changes" {
+		t.Errorf("expected repeated patch to make no changes. got: %v", err)
+	}
+}
+
+func TestDatasetRequestsSaveDryRun(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	req := NewDatasetRequests(mr, nil)
+	before, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "cities"})
+	if err != nil {
+		t.Errorf("error getting reference: %s", err.Error())
+		return
+	}
+
+	res := &SavePreview{}
+	p := &SaveParams{
+		Name:     "cities",
+		Peername: "peer",
+		Data:     bytes.NewReader([]byte("city,pop,avg_age,in_usa\nboston,700000,35.5,true\n")),
+		Append:   true,
+	}
+	if err := req.SaveDryRun(p, res); err != nil {
+		t.Errorf("error previewing save: %s", err.Error())
+		return
+	}
+	if res.Dataset.Structure.Entries != 6 {
+		t.Errorf("expected 6 entries in preview. got: %d", res.Dataset.Structure.Entries)
+	}
+	if res.Diff["data"] == nil {
+		t.Errorf("expected preview to diff data")
+	}
+
+	after, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "cities"})
+	if err != nil {
+		t.Errorf("error getting reference: %s", err.Error())
+		return
+	}
+	if after.Path != before.Path {
+		t.Errorf("expected dry run to leave reference alone. was: %s, now: %s", before.Path, after.Path)
+	}
+	if has, err := mr.Store().Has(datastore.NewKey(res.Dataset.DataPath)); err != nil || has {
+		t.Errorf("expected dry run data to stay out of the store. got: %t, %v", has, err)
+	}
+}
+
 func TestDatasetRequestsRename(t *testing.T) {
 	mr, err := testrepo.NewTestRepo()
 	if err != nil {
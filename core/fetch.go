@@ -0,0 +1,205 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"strings"
+	"time"
+
+	"github.com/qri-io/qri/config"
+	"github.com/qri-io/qri/repo"
+)
+
+// ErrSourceNotModified is returned when saving from a url that reports its
+// data hasn't changed since the dataset's latest version, or that gives the
+// same data as the latest version
+var ErrSourceNotModified = fmt.Errorf("source data hasn't changed since the latest version")
+
+// IsSourceNotModified reports whether err is ErrSourceNotModified, whether
+// it was returned directly or over rpc
+func IsSourceNotModified(err error) bool {
+	return err == ErrSourceNotModified || err == rpc.ServerError(ErrSourceNotModified.Error())
+}
+
+// fetchBackoff is the wait before retrying a failed download, doubling
+// with each retry
+var fetchBackoff = time.Second
+
+// fetcher downloads data from urls, enforcing the limits of a
+// config.Fetch. Urls are read by the Source registered for their scheme
+type fetcher struct {
+	timeout     time.Duration
+	maxBodySize int64
+	retries     int
+	backoff     time.Duration
+	// authName names the credentials in auth, if any
+	authName string
+	auth     *config.SourceAuth
+}
+
+// newFetcher creates a fetcher from the global configuration. If authName
+// isn't empty, requests are made with the configured credentials of that
+// name
+func newFetcher(authName string) (*fetcher, error) {
+	cfg := config.DefaultFetch()
+	if Config != nil && Config.Fetch != nil {
+		cfg = Config.Fetch
+	}
+	f := &fetcher{
+		timeout:     time.Duration(cfg.Timeout) * time.Second,
+		maxBodySize: int64(cfg.MaxBodySize),
+		retries:     cfg.Retries,
+		backoff:     fetchBackoff,
+	}
+	if authName != "" {
+		auth := cfg.Auth[authName]
+		if auth == nil {
+			return nil, fmt.Errorf("source auth '%s' isn't configured, add it to fetch.auth in your config", authName)
+		}
+		f.authName, f.auth = authName, auth
+	}
+	return f, nil
+}
+
+// fetch reads a url, retrying temporary failures. If prev is given the
+// read is conditional. Callers must close the body of the result
+func (f *fetcher) fetch(uri string, prev *repo.SourceValidators) (*SourceData, error) {
+	src, err := sourceFor(uri)
+	if err != nil {
+		return nil, err
+	}
+	if f.auth != nil && !IsLocalSource(uri) {
+		host, err := sourceHost(src, uri)
+		if err != nil {
+			return nil, err
+		}
+		if !authAllows(f.auth, host) {
+			return nil, fmt.Errorf("source auth '%s' can't be sent to %s, add it to the auth's hosts to allow it", f.authName, host)
+		}
+	}
+	req := &SourceRequest{URI: uri, Auth: f.auth, Prev: prev, Timeout: f.timeout}
+
+	wait := f.backoff
+	for attempt := 0; ; attempt++ {
+		data, err := src.Open(req)
+		if err == nil {
+			return f.limit(uri, data)
+		}
+		if !temporary(err) || attempt >= f.retries {
+			return nil, err
+		}
+		log.Debugf("retrying %s in %s: %s", uri, wait, err.Error())
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// limit caps the body of data read from uri at the max body size, & records
+// the uri & credentials it was read with
+func (f *fetcher) limit(uri string, data *SourceData) (*SourceData, error) {
+	data.Validators.URL = uri
+	data.Validators.Auth = f.authName
+	if data.NotModified {
+		return data, nil
+	}
+	if data.Size > f.maxBodySize {
+		data.Body.Close()
+		return nil, fmt.Errorf("error fetching url: response is larger than the %d byte limit", f.maxBodySize)
+	}
+	data.Body = &cappedBody{ReadCloser: data.Body, limit: f.maxBodySize, remaining: f.maxBodySize}
+	return data, nil
+}
+
+// cappedBody errors once more than limit bytes are read from a response
+type cappedBody struct {
+	io.ReadCloser
+	limit, remaining int64
+}
+
+func (b *cappedBody) Read(p []byte) (int, error) {
+	// read one byte past the limit to find responses that exceed it
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n, fmt.Errorf("error fetching url: response is larger than the %d byte limit", b.limit)
+	}
+	return n, err
+}
+
+// authAllows reports whether credentials can be sent to host. Hosts
+// configured without a port match any port
+func authAllows(auth *config.SourceAuth, host string) bool {
+	host = strings.ToLower(host)
+	name := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		name = h
+	}
+	for _, allowed := range auth.Hosts {
+		allowed = strings.ToLower(allowed)
+		if allowed == host || allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceAuth names the credentials to fetch url with for a dataset: name if
+// it's given, otherwise the credentials the dataset last fetched url with
+func sourceAuth(r repo.Repo, ref *repo.DatasetRef, url, name string) string {
+	if name != "" {
+		return name
+	}
+	cache, ok := repo.AsSourceCache(r)
+	if !ok {
+		return ""
+	}
+	v, err := cache.SourceValidators(*ref)
+	if err != nil || v.URL != url {
+		return ""
+	}
+	return v.Auth
+}
+
+// sourceValidators gets the cached validators of a dataset's source, if
+// they were recorded fetching the data of ref's version from url
+func sourceValidators(r repo.Repo, ref *repo.DatasetRef, url string) *repo.SourceValidators {
+	cache, ok := repo.AsSourceCache(r)
+	if !ok || ref.Dataset == nil || ref.Dataset.Structure == nil {
+		return nil
+	}
+	v, err := cache.SourceValidators(*ref)
+	if err != nil || v.URL != url || v.Checksum != ref.Dataset.Structure.Checksum {
+		return nil
+	}
+	return &v
+}
+
+// fetchedLocally reports whether this repo fetched a dataset's data from
+// url itself
+func fetchedLocally(r repo.Repo, ref *repo.DatasetRef, url string) bool {
+	cache, ok := repo.AsSourceCache(r)
+	if !ok {
+		return false
+	}
+	v, err := cache.SourceValidators(*ref)
+	return err == nil && v.URL == url
+}
+
+// cacheSourceValidators records the validators & credentials name of a
+// dataset's source, along with the checksum of the data fetched. Local
+// sources are always recorded, marking them as fetched on this machine
+func cacheSourceValidators(r repo.Repo, ref repo.DatasetRef, v repo.SourceValidators, checksum string) {
+	cache, ok := repo.AsSourceCache(r)
+	if !ok || v.ETag == "" && v.LastModified == "" && v.Auth == "" && !IsLocalSource(v.URL) {
+		return
+	}
+	v.Checksum = checksum
+	if err := cache.PutSourceValidators(ref, v); err != nil {
+		log.Debug(err.Error())
+	}
+}
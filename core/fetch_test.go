@@ -0,0 +1,216 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qri-io/qri/config"
+	"github.com/qri-io/qri/repo"
+	testrepo "github.com/qri-io/qri/repo/test"
+)
+
+func TestFetch(t *testing.T) {
+	defer func(d time.Duration) { fetchBackoff = d }(fetchBackoff)
+	fetchBackoff = time.Millisecond
+
+	failures := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky.csv":
+			if failures < 2 {
+				failures++
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/missing.csv":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/cached.csv":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+		case "/chunked.csv":
+			// flushing before writing leaves the response without a content length
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte("a,b\n1,2\n"))
+	}))
+	defer s.Close()
+
+	f, err := newFetcher("")
+	if err != nil {
+		t.Errorf("error creating fetcher: %s", err.Error())
+		return
+	}
+	src, err := f.fetch(s.URL+"/flaky.csv", nil)
+	if err != nil {
+		t.Errorf("expected transient failures to be retried. got: %s", err.Error())
+		return
+	}
+	src.Body.Close()
+	if src.Filename != "flaky.csv" {
+		t.Errorf("filename mismatch. expected: flaky.csv, got: %s", src.Filename)
+	}
+
+	if _, err := f.fetch(s.URL+"/missing.csv", nil); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a 404 to error. got: %v", err)
+	}
+
+	failures = 0
+	f.retries = 1
+	if _, err := f.fetch(s.URL+"/flaky.csv", nil); err == nil {
+		t.Errorf("expected running out of retries to error")
+	}
+
+	f, _ = newFetcher("")
+	f.maxBodySize = 4
+	if _, err := f.fetch(s.URL+"/data.csv", nil); err == nil || !strings.Contains(err.Error(), "4 byte limit") {
+		t.Errorf("expected a content length past the size limit to error. got: %v", err)
+	}
+	src, err = f.fetch(s.URL+"/chunked.csv", nil)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+		return
+	}
+	if _, err := ioutil.ReadAll(src.Body); err == nil || !strings.Contains(err.Error(), "4 byte limit") {
+		t.Errorf("expected reading past the size limit to error. got: %v", err)
+	}
+	src.Body.Close()
+
+	f, _ = newFetcher("")
+	src, err = f.fetch(s.URL+"/cached.csv", nil)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+		return
+	}
+	src.Body.Close()
+	if src.Validators.ETag != `"v1"` {
+		t.Errorf("expected etag to be recorded. got: %s", src.Validators.ETag)
+	}
+	src, err = f.fetch(s.URL+"/cached.csv", &src.Validators)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+		return
+	}
+	if !src.NotModified {
+		t.Errorf("expected conditional request to report data isn't modified")
+	}
+}
+
+func TestSaveNotModified(t *testing.T) {
+	etag := `"v1"`
+	data := "species,count\nblue jay,4\n"
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(data))
+	}))
+	defer s.Close()
+
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	req := NewDatasetRequests(mr, nil)
+	url := s.URL + "/birds.csv"
+	created := &repo.DatasetRef{}
+	if err := req.Init(&InitParams{Peername: "peer", Name: "birds", URL: url}, created); err != nil {
+		t.Errorf("error creating dataset: %s", err.Error())
+		return
+	}
+
+	if err := req.Save(&SaveParams{Peername: "peer", Name: "birds", URL: url}, &repo.DatasetRef{}); err != ErrSourceNotModified {
+		t.Errorf("expected saving an unmodified source to return ErrSourceNotModified. got: %v", err)
+	}
+	if !IsSourceNotModified(rpc.ServerError(ErrSourceNotModified.Error())) || IsSourceNotModified(fmt.Errorf("oh no")) {
+		t.Errorf("expected only ErrSourceNotModified to be recognized over rpc")
+	}
+
+	etag, data = `"v2"`, "species,count\nblue jay,5\n"
+	res := &repo.DatasetRef{}
+	if err := req.Save(&SaveParams{Peername: "peer", Name: "birds", URL: url}, res); err != nil {
+		t.Errorf("error saving modified source: %s", err.Error())
+		return
+	}
+	if res.Path == created.Path {
+		t.Errorf("expected modified source to save a new version")
+	}
+}
+
+func TestSourceAuth(t *testing.T) {
+	data := "species,count\nblue jay,4\n"
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/elsewhere.csv" {
+			http.Redirect(w, r, strings.Replace(s.URL, "127.0.0.1", "localhost", 1)+"/birds.csv", http.StatusFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Api-Version") != "2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer s.Close()
+
+	defer func(auth map[string]*config.SourceAuth) { Config.Fetch.Auth = auth }(Config.Fetch.Auth)
+	Config.Fetch.Auth = map[string]*config.SourceAuth{
+		"birds": {Hosts: []string{strings.TrimPrefix(s.URL, "http://")}, Token: "secret", Headers: map[string]string{"X-Api-Version": "2"}},
+		"other": {Hosts: []string{"example.com"}, Token: "secret"},
+	}
+
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	req := NewDatasetRequests(mr, nil)
+	url := s.URL + "/birds.csv"
+
+	// credentials are only sent to the hosts they're for
+	if err := req.Init(&InitParams{Peername: "peer", Name: "birds", URL: url, SourceAuth: "other"}, &repo.DatasetRef{}); err == nil || !strings.Contains(err.Error(), "can't be sent to") {
+		t.Errorf("expected credentials for another host to be refused. got: %v", err)
+	}
+	if err := req.Init(&InitParams{Peername: "peer", Name: "birds", URL: s.URL + "/elsewhere.csv", SourceAuth: "birds"}, &repo.DatasetRef{}); err == nil || !strings.Contains(err.Error(), "redirected") {
+		t.Errorf("expected credentials not to follow redirects to another host. got: %v", err)
+	}
+
+	if err := req.Init(&InitParams{Peername: "peer", Name: "birds", URL: url}, &repo.DatasetRef{}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected fetching without credentials to be unauthorized. got: %v", err)
+	}
+	if err := req.Init(&InitParams{Peername: "peer", Name: "birds", URL: url, SourceAuth: "nope"}, &repo.DatasetRef{}); err == nil || !strings.Contains(err.Error(), "isn't configured") {
+		t.Errorf("expected unknown credentials to error. got: %v", err)
+	}
+
+	created := &repo.DatasetRef{}
+	if err := req.Init(&InitParams{Peername: "peer", Name: "birds", URL: url, SourceAuth: "birds"}, created); err != nil {
+		t.Errorf("error creating dataset with credentials: %s", err.Error())
+		return
+	}
+	if created.Dataset.Meta.DownloadPath != url {
+		t.Errorf("download path mismatch. expected: %s, got: %s", url, created.Dataset.Meta.DownloadPath)
+	}
+
+	// later saves from the same url use the same credentials
+	data = "species,count\nblue jay,5\n"
+	res := &repo.DatasetRef{}
+	if err := req.Save(&SaveParams{Peername: "peer", Name: "birds", URL: url}, res); err != nil {
+		t.Errorf("error saving with remembered credentials: %s", err.Error())
+		return
+	}
+	if res.Path == created.Path {
+		t.Errorf("expected save to create a new version")
+	}
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"github.com/qri-io/dataset/dsgraph"
+	"github.com/qri-io/qri/repo"
+)
+
+// GraphRequests encapsulates business logic for showing how datasets,
+// their versions & transform inputs depend on one another
+type GraphRequests struct {
+	repo repo.Repo
+	cli  *rpc.Client
+}
+
+// CoreRequestsName implements the Requests interface
+func (GraphRequests) CoreRequestsName() string { return "graph" }
+
+// NewGraphRequests creates a GraphRequests pointer from either a repo or an
+// rpc.Client
+func NewGraphRequests(r repo.Repo, cli *rpc.Client) *GraphRequests {
+	if r != nil && cli != nil {
+		panic(fmt.Errorf("both repo and client supplied to NewGraphRequests"))
+	}
+	return &GraphRequests{
+		repo: r,
+		cli:  cli,
+	}
+}
+
+// GraphNodeTypes lists the node types a graph can be filtered by
+var GraphNodeTypes = []dsgraph.NodeType{
+	dsgraph.NtDataset,
+	dsgraph.NtData,
+	dsgraph.NtCommit,
+	dsgraph.NtTransform,
+}
+
+// GraphParams defines parameters for the Graph method
+type GraphParams struct {
+	// Ref is the dataset to start from. Graphs start from every dataset in
+	// the repo if Ref has no name
+	Ref repo.DatasetRef
+	// Depth limits the number of links followed, 0 follows all links
+	Depth int
+	// Types limits the graph to nodes of the given types, see GraphNodeTypes.
+	// All types are included if Types is empty
+	Types []string
+}
+
+// Graph builds a view of the datasets & components reachable from a
+// dataset, following version history & transform inputs
+func (r *GraphRequests) Graph(p *GraphParams, res *repo.GraphView) error {
+	if r.cli != nil {
+		return r.cli.Call("GraphRequests.Graph", p, res)
+	}
+
+	if p.Depth < 0 {
+		return fmt.Errorf("depth can't be negative")
+	}
+	types, err := graphNodeTypes(p.Types)
+	if err != nil {
+		return err
+	}
+
+	var refs []repo.DatasetRef
+	if p.Ref.Name != "" {
+		ref := p.Ref
+		if err := repo.CanonicalizeDatasetRef(r.repo, &ref); err != nil {
+			log.Debug(err.Error())
+			return fmt.Errorf("error canonicalizing reference: %s", err.Error())
+		}
+		if ref.Path == "" {
+			return fmt.Errorf("unknown dataset: %s", ref.AliasString())
+		}
+		refs = []repo.DatasetRef{ref}
+	} else {
+		count, err := r.repo.RefCount()
+		if err != nil {
+			log.Debug(err.Error())
+			return fmt.Errorf("error counting datasets: %s", err.Error())
+		}
+		if refs, err = r.repo.References(count, 0); err != nil {
+			log.Debug(err.Error())
+			return fmt.Errorf("error listing datasets: %s", err.Error())
+		}
+	}
+
+	nodes, err := repo.Graph(r.repo)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error building repo graph: %s", err.Error())
+	}
+
+	*res = *repo.NewGraphView(nodes, refs, p.Depth, types...)
+	return nil
+}
+
+// graphNodeTypes checks type names against GraphNodeTypes
+func graphNodeTypes(names []string) ([]dsgraph.NodeType, error) {
+	known := map[string]dsgraph.NodeType{}
+	for _, t := range GraphNodeTypes {
+		known[string(t)] = t
+	}
+
+	types := make([]dsgraph.NodeType, 0, len(names))
+	for _, name := range names {
+		t, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown node type '%s'. types are: %v", name, GraphNodeTypes)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
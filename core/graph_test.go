@@ -0,0 +1,69 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/qri-io/dataset/dsgraph"
+	"github.com/qri-io/qri/repo"
+	testrepo "github.com/qri-io/qri/repo/test"
+)
+
+func TestGraphRequests(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	movies, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "movies"})
+	if err != nil {
+		t.Errorf("error getting ref: %s", err.Error())
+		return
+	}
+	req := NewGraphRequests(mr, nil)
+
+	bad := []*GraphParams{
+		{Depth: -1},
+		{Types: []string{"not_a_type"}},
+		{Ref: repo.DatasetRef{Peername: "me", Name: "not_a_dataset"}},
+	}
+	for i, p := range bad {
+		if err := req.Graph(p, &repo.GraphView{}); err == nil {
+			t.Errorf("case %d: expected graph to error", i)
+		}
+	}
+
+	all := &repo.GraphView{}
+	if err := req.Graph(&GraphParams{}, all); err != nil {
+		t.Errorf("error graphing repo: %s", err.Error())
+		return
+	}
+
+	got := &repo.GraphView{}
+	if err := req.Graph(&GraphParams{Ref: repo.DatasetRef{Peername: "me", Name: "movies"}, Depth: 1}, got); err != nil {
+		t.Errorf("error graphing dataset: %s", err.Error())
+		return
+	}
+	if len(got.Nodes) == 0 || got.Nodes[0].Path != movies.Path || got.Nodes[0].Name != "peer/movies" {
+		t.Errorf("expected graph to start at peer/movies. got: %v", got.Nodes)
+		return
+	}
+	if len(got.Nodes) >= len(all.Nodes) {
+		t.Errorf("expected graph of one dataset to be smaller than graph of the repo")
+	}
+	for _, n := range got.Nodes {
+		if n.Depth > 1 {
+			t.Errorf("expected depth 1 graph to exclude %s at depth %d", n.Path, n.Depth)
+		}
+	}
+
+	datasets := &repo.GraphView{}
+	if err := req.Graph(&GraphParams{Types: []string{string(dsgraph.NtDataset)}}, datasets); err != nil {
+		t.Errorf("error graphing datasets: %s", err.Error())
+		return
+	}
+	for _, n := range datasets.Nodes {
+		if n.Type != dsgraph.NtDataset {
+			t.Errorf("expected only dataset nodes. got: %s %s", n.Type, n.Path)
+		}
+	}
+}
@@ -97,3 +97,68 @@ func (d *HistoryRequests) Log(params *LogParams, res *[]repo.DatasetRef) (err er
 	*res = rlog
 	return nil
 }
+
+// BranchLog is the history of a single branch of a dataset
+type BranchLog struct {
+	// Branch is the branch name, repo.DefaultBranch for the dataset's
+	// reference
+	Branch string `json:"branch"`
+	// Versions lists versions only this branch holds, newest first
+	Versions []repo.DatasetRef `json:"versions"`
+	// ForkPath is the version this branch diverged from, empty if the branch
+	// shares no history with branches listed before it
+	ForkPath string `json:"forkPath,omitempty"`
+}
+
+// Graph returns the history of every branch of a dataset, for showing
+// divergent lines of history. The default branch is listed first, followed
+// by named branches, each listing versions until it joins history that's
+// already been listed
+func (d *HistoryRequests) Graph(ref *repo.DatasetRef, res *[]BranchLog) (err error) {
+	if d.cli != nil {
+		return d.cli.Call("HistoryRequests.Graph", ref, res)
+	}
+
+	if err = repo.CanonicalizeProfile(d.repo, ref); err != nil {
+		log.Debug(err.Error())
+		return err
+	}
+	if ref.Peername == "" || ref.Name == "" {
+		return fmt.Errorf("peername/name is required")
+	}
+
+	head, err := d.repo.GetRef(repo.DatasetRef{Peername: ref.Peername, ProfileID: ref.ProfileID, Name: ref.Name})
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error getting reference '%s': %s", ref.AliasString(), err.Error())
+	}
+	branches, err := d.repo.Branches().ListBranches(head)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error listing branches: %s", err.Error())
+	}
+	head.Branch = repo.DefaultBranch
+	branches = append([]repo.DatasetRef{head}, branches...)
+
+	seen := map[string]bool{}
+	graph := make([]BranchLog, 0, len(branches))
+	for _, b := range branches {
+		bl := BranchLog{Branch: b.Branch, Versions: []repo.DatasetRef{}}
+		for v := b; v.Path != "" && v.Path != "/"; v.Path = v.Dataset.PreviousPath {
+			if seen[v.Path] {
+				bl.ForkPath = v.Path
+				break
+			}
+			if err = d.repo.ReadDataset(&v); err != nil {
+				log.Debug(err.Error())
+				return fmt.Errorf("error loading branch %s: %s", b.Branch, err.Error())
+			}
+			seen[v.Path] = true
+			bl.Versions = append(bl.Versions, v)
+		}
+		graph = append(graph, bl)
+	}
+
+	*res = graph
+	return nil
+}
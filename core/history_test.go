@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/qri-io/qri/repo"
@@ -45,3 +46,44 @@ func TestHistoryRequestsLog(t *testing.T) {
 		}
 	}
 }
+
+func TestHistoryRequestsGraph(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	movies, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "movies"})
+	if err != nil {
+		t.Errorf("error getting ref: %s", err.Error())
+		return
+	}
+
+	dsr := NewDatasetRequests(mr, nil)
+	fix := &repo.DatasetRef{}
+	if err := dsr.Save(&SaveParams{Name: "movies", Peername: "peer", Branch: "fix", Metadata: bytes.NewReader([]byte(`{"title":"fix"}`))}, fix); err != nil {
+		t.Errorf("error saving to branch: %s", err.Error())
+		return
+	}
+
+	req := NewHistoryRequests(mr, nil)
+	if err := req.Graph(&repo.DatasetRef{}, &[]BranchLog{}); err == nil {
+		t.Errorf("expected graphing without a dataset name to error")
+	}
+
+	graph := []BranchLog{}
+	if err := req.Graph(&repo.DatasetRef{Peername: "me", Name: "movies"}, &graph); err != nil {
+		t.Errorf("error graphing history: %s", err.Error())
+		return
+	}
+	if len(graph) != 2 {
+		t.Errorf("expected 2 branches. got: %d", len(graph))
+		return
+	}
+	if graph[0].Branch != repo.DefaultBranch || len(graph[0].Versions) != 1 || graph[0].Versions[0].Path != movies.Path {
+		t.Errorf("expected default branch to hold %s. got: %v", movies.Path, graph[0])
+	}
+	if graph[1].Branch != "fix" || len(graph[1].Versions) != 1 || graph[1].Versions[0].Path != fix.Path || graph[1].ForkPath != movies.Path {
+		t.Errorf("expected branch fix to fork from %s. got: %v", movies.Path, graph[1])
+	}
+}
@@ -0,0 +1,230 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/multiformats/go-multihash"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/detect"
+	"github.com/qri-io/dataset/dsio"
+	"github.com/qri-io/dataset/validate"
+)
+
+// detectPrefixSize is the number of bytes read from the start of a data
+// source to detect its structure
+var detectPrefixSize = 64 * 1024
+
+// detectStructure determines the structure of data by reading at most
+// detectPrefixSize bytes. It returns a reader that produces the complete
+// data, including the prefix read for detection
+func detectStructure(filename string, r io.Reader) (*dataset.Structure, io.Reader, error) {
+	prefix := make([]byte, detectPrefixSize)
+	n, err := io.ReadFull(r, prefix)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, nil, fmt.Errorf("error reading data: %s", err.Error())
+	}
+	prefix = prefix[:n]
+	data := io.MultiReader(bytes.NewReader(prefix), r)
+
+	sample := prefix
+	if n == detectPrefixSize {
+		// there's more data to come, don't show detection a partial entry
+		if i := bytes.LastIndexByte(sample, '\n'); i > 0 {
+			sample = sample[:i+1]
+		}
+	}
+	st, err := detect.FromReader(filename, bytes.NewReader(sample))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error determining dataset schema: %s", err.Error())
+	}
+	return st, data, nil
+}
+
+// putData streams data into a store unpinned, setting the checksum, length
+// & entry count of st as data is written. Data is read exactly once & never
+// held in memory
+func putData(store cafs.Filestore, st *dataset.Structure, r io.Reader) (datastore.Key, error) {
+	var (
+		hash    = sha256.New()
+		length  = &byteCounter{}
+		pr, pw  = io.Pipe()
+		counted = make(chan error, 1)
+		entries int
+	)
+
+	// count entries as data passes through on its way to the store
+	go func() {
+		var err error
+		entries, err = countEntries(st, pr)
+		// keep reading so writes to the pipe never block
+		io.Copy(ioutil.Discard, pr)
+		counted <- err
+	}()
+
+	tee := io.TeeReader(r, io.MultiWriter(hash, length, pw))
+	key, err := store.Put(cafs.NewMemfileReader("data."+st.Format.String(), tee), false)
+	pw.CloseWithError(err)
+	countErr := <-counted
+	if err != nil {
+		log.Debug(err.Error())
+		return datastore.NewKey(""), fmt.Errorf("error putting data file in store: %s", err.Error())
+	}
+	if countErr != nil {
+		log.Debug(countErr.Error())
+		return datastore.NewKey(""), fmt.Errorf("error reading data entries: %s", countErr.Error())
+	}
+
+	sum, err := multihash.Encode(hash.Sum(nil), multihash.SHA2_256)
+	if err != nil {
+		return datastore.NewKey(""), fmt.Errorf("error calculating data checksum: %s", err.Error())
+	}
+	st.Checksum = multihash.Multihash(sum).B58String()
+	st.Length = int(length.n)
+	st.Entries = entries
+	return key, nil
+}
+
+// appendData writes the entries of prev's data followed by entries read
+// from r to a store, returning the combined data's key & structure along
+// with the number of entries appended. Appended data must be in the same
+// format as prev, including any header row, and every entry must be valid
+// according to prev's schema
+func appendData(store cafs.Filestore, prev *dataset.Dataset, r io.Reader) (datastore.Key, *dataset.Structure, int, error) {
+	// store appended entries on their own first, so they can be
+	// checked before anything is combined
+	added := &dataset.Structure{}
+	added.Assign(prev.Structure)
+	addedKey, err := putData(store, added, r)
+	if err != nil {
+		return datastore.NewKey(""), nil, 0, err
+	}
+	if added.Entries == 0 {
+		return datastore.NewKey(""), nil, 0, fmt.Errorf("no entries to append")
+	}
+	if err := validateStoredData(store, prev.Structure, addedKey.String()); err != nil {
+		return datastore.NewKey(""), nil, 0, err
+	}
+
+	st := &dataset.Structure{}
+	st.Assign(prev.Structure)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeEntries(store, st, pw, prev.DataPath, addedKey.String()))
+	}()
+	key, err := putData(store, st, pr)
+	// unblock the writer if the store stopped reading early
+	pr.Close()
+	if err != nil {
+		return datastore.NewKey(""), nil, 0, err
+	}
+	return key, st, added.Entries, nil
+}
+
+// validateStoredData checks data in a store against a structure's schema
+func validateStoredData(store cafs.Filestore, st *dataset.Structure, path string) error {
+	f, err := store.Get(datastore.NewKey(path))
+	if err != nil {
+		return fmt.Errorf("error loading data: %s", err.Error())
+	}
+	defer f.Close()
+
+	er, err := dsio.NewEntryReader(st, f)
+	if err != nil {
+		return fmt.Errorf("error reading data: %s", err.Error())
+	}
+	errs, err := validate.EntryReader(er)
+	if err != nil {
+		return fmt.Errorf("error validating data: %s", err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("data doesn't match the dataset's schema, %d validation error(s). first error: %s", len(errs), errs[0].Error())
+	}
+	return nil
+}
+
+// writeEntries writes the entries of stored data files to w, one after
+// another
+func writeEntries(store cafs.Filestore, st *dataset.Structure, w io.Writer, paths ...string) error {
+	ew, err := dsio.NewEntryWriter(st, w)
+	if err != nil {
+		return fmt.Errorf("error allocating data writer: %s", err.Error())
+	}
+	for _, path := range paths {
+		f, err := store.Get(datastore.NewKey(path))
+		if err != nil {
+			return fmt.Errorf("error loading data: %s", err.Error())
+		}
+		er, err := dsio.NewEntryReader(st, f)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("error reading data: %s", err.Error())
+		}
+		for {
+			ent, err := er.ReadEntry()
+			if err != nil {
+				if err.Error() == "EOF" {
+					break
+				}
+				f.Close()
+				return fmt.Errorf("error reading data: %s", err.Error())
+			}
+			if err := ew.WriteEntry(ent); err != nil {
+				f.Close()
+				return fmt.Errorf("error writing data: %s", err.Error())
+			}
+		}
+		f.Close()
+	}
+	return ew.Close()
+}
+
+// countEntries reads every entry from r
+func countEntries(st *dataset.Structure, r io.Reader) (int, error) {
+	er, err := dsio.NewEntryReader(st, r)
+	if err != nil {
+		return 0, err
+	}
+	for count := 0; ; count++ {
+		if _, err := er.ReadEntry(); err != nil {
+			if err.Error() == "EOF" {
+				return count, nil
+			}
+			return count, err
+		}
+	}
+}
+
+// storedData opens data that's already in a store, named for its format.
+// callers must close the returned file
+func storedData(store cafs.Filestore, st *dataset.Structure, path string) (cafs.File, error) {
+	f, err := store.Get(datastore.NewKey(path))
+	if err != nil {
+		return nil, err
+	}
+	return namedFile{File: f, name: "data." + st.Format.String()}, nil
+}
+
+// namedFile renames a file
+type namedFile struct {
+	cafs.File
+	name string
+}
+
+func (f namedFile) FileName() string { return f.name }
+func (f namedFile) FullPath() string { return f.name }
+
+// byteCounter is an io.Writer that counts bytes written
+type byteCounter struct {
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,271 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/multiformats/go-multihash"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/qri/repo"
+	"github.com/qri-io/qri/repo/fs"
+	"github.com/qri-io/qri/repo/profile"
+	testrepo "github.com/qri-io/qri/repo/test"
+)
+
+func TestDetectStructure(t *testing.T) {
+	defer func(size int) { detectPrefixSize = size }(detectPrefixSize)
+	detectPrefixSize = 20
+
+	data := "city,pop\ntoronto,40000000\nnew york,8500000\nchicago,300000\n"
+	st, rdr, err := detectStructure("cities.csv", strings.NewReader(data))
+	if err != nil {
+		t.Errorf("error detecting structure: %s", err.Error())
+		return
+	}
+	if st.Format != dataset.CSVDataFormat {
+		t.Errorf("expected csv format. got: %s", st.Format)
+	}
+	got, err := ioutil.ReadAll(rdr)
+	if err != nil {
+		t.Errorf("error reading data: %s", err.Error())
+		return
+	}
+	if string(got) != data {
+		t.Errorf("expected complete data to be readable after detection. got: %q", string(got))
+	}
+
+	if _, _, err := detectStructure("cities.foo", strings.NewReader(data)); err == nil {
+		t.Errorf("expected unknown format to error")
+	}
+}
+
+func TestPutData(t *testing.T) {
+	store := cafs.NewMapstore()
+	data := []byte("city,pop\ntoronto,40000000\nnew york,8500000\nchicago,300000\n")
+
+	st, rdr, err := detectStructure("cities.csv", bytes.NewReader(data))
+	if err != nil {
+		t.Errorf("error detecting structure: %s", err.Error())
+		return
+	}
+	key, err := putData(store, st, rdr)
+	if err != nil {
+		t.Errorf("error putting data: %s", err.Error())
+		return
+	}
+
+	sum, err := multihash.Sum(data, multihash.SHA2_256, -1)
+	if err != nil {
+		t.Errorf("error calculating checksum: %s", err.Error())
+		return
+	}
+	if st.Checksum != sum.B58String() {
+		t.Errorf("checksum mismatch. expected: %s, got: %s", sum.B58String(), st.Checksum)
+	}
+	if st.Length != len(data) {
+		t.Errorf("length mismatch. expected: %d, got: %d", len(data), st.Length)
+	}
+	if st.Entries != 3 {
+		t.Errorf("entries mismatch. expected: %d, got: %d", 3, st.Entries)
+	}
+
+	f, err := store.Get(key)
+	if err != nil {
+		t.Errorf("error getting data: %s", err.Error())
+		return
+	}
+	stored, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Errorf("error reading data: %s", err.Error())
+		return
+	}
+	if !bytes.Equal(stored, data) {
+		t.Errorf("stored data mismatch. got: %q", string(stored))
+	}
+}
+
+func TestPutDataMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large data test in short mode")
+	}
+	size := int64(rowSize * 3000000)
+	store := &sampleStore{every: 4 << 20}
+
+	st, rdr, err := detectStructure("rows.csv", io.LimitReader(&rowReader{}, size))
+	if err != nil {
+		t.Errorf("error detecting structure: %s", err.Error())
+		return
+	}
+	runtime.GC()
+	store.base = heapAlloc()
+	if _, err := putData(store, st, rdr); err != nil {
+		t.Errorf("error putting data: %s", err.Error())
+		return
+	}
+	if int64(st.Length) != size {
+		t.Errorf("length mismatch. expected: %d, got: %d", size, st.Length)
+	}
+	if grew := int64(store.max) - int64(store.base); grew > size/4 {
+		t.Errorf("expected putting %d bytes to use constant memory. heap grew by %d bytes", size, grew)
+	}
+}
+
+func TestSaveMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large data test in short mode")
+	}
+	size := int64(rowSize * 3000000)
+
+	path, err := ioutil.TempDir("", "qri_test_save_memory")
+	if err != nil {
+		t.Errorf("error creating temp dir: %s", err.Error())
+		return
+	}
+	defer os.RemoveAll(path)
+	store, err := fsrepo.NewFilestore(path)
+	if err != nil {
+		t.Errorf("error creating filestore: %s", err.Error())
+		return
+	}
+	tr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	pro, err := tr.Profile()
+	if err != nil {
+		t.Errorf("error getting profile: %s", err.Error())
+		return
+	}
+	mr, err := repo.NewMemRepo(pro, store, profile.NewMemStore())
+	if err != nil {
+		t.Errorf("error allocating repo: %s", err.Error())
+		return
+	}
+	mr.SetPrivateKey(tr.PrivateKey())
+	req := NewDatasetRequests(mr, nil)
+
+	initp := &InitParams{
+		Name:         "rows",
+		DataFilename: "rows.csv",
+		Data:         io.LimitReader(&rowReader{}, rowSize*50),
+	}
+	if err := req.Init(initp, &repo.DatasetRef{}); err != nil {
+		t.Errorf("error initializing dataset: %s", err.Error())
+		return
+	}
+
+	runtime.GC()
+	base := heapAlloc()
+	sampler := &heapSampler{done: make(chan struct{})}
+	go sampler.run(time.Millisecond * 10)
+
+	savep := &SaveParams{
+		Peername:     pro.Peername,
+		Name:         "rows",
+		DataFilename: "rows.csv",
+		Data:         io.LimitReader(&rowReader{}, size),
+	}
+	res := &repo.DatasetRef{}
+	err = req.Save(savep, res)
+	max := sampler.stop()
+	if err != nil {
+		t.Errorf("error saving dataset: %s", err.Error())
+		return
+	}
+	if int64(res.Dataset.Structure.Length) != size {
+		t.Errorf("length mismatch. expected: %d, got: %d", size, res.Dataset.Structure.Length)
+	}
+	if grew := int64(max) - int64(base); grew > size/4 {
+		t.Errorf("expected saving %d bytes to use constant memory. heap grew by %d bytes", size, grew)
+	}
+}
+
+// heapSampler records the largest heap size seen until stopped
+type heapSampler struct {
+	lock sync.Mutex
+	max  uint64
+	done chan struct{}
+}
+
+func (s *heapSampler) run(every time.Duration) {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-time.After(every):
+			h := heapAlloc()
+			s.lock.Lock()
+			if h > s.max {
+				s.max = h
+			}
+			s.lock.Unlock()
+		}
+	}
+}
+
+func (s *heapSampler) stop() uint64 {
+	close(s.done)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.max
+}
+
+// rowSize is the length of each row rowReader produces
+const rowSize = 22
+
+// rowReader produces an endless csv file of rowSize-byte rows
+type rowReader struct {
+	row []byte
+	n   int
+}
+
+func (r *rowReader) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		if len(r.row) == 0 {
+			r.row = []byte(fmt.Sprintf("row_%08d,%08d\n", r.n, r.n))
+			r.n++
+		}
+		c := copy(p[n:], r.row)
+		r.row = r.row[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// sampleStore is a cafs.Filestore that discards files put into it, sampling
+// heap size as they're read
+type sampleStore struct {
+	cafs.Filestore
+	every     int64
+	base, max uint64
+}
+
+func (s *sampleStore) Put(f cafs.File, pin bool) (datastore.Key, error) {
+	for {
+		if _, err := io.CopyN(ioutil.Discard, f, s.every); err == io.EOF {
+			return datastore.NewKey("/map/discarded"), nil
+		} else if err != nil {
+			return datastore.NewKey(""), err
+		}
+		if h := heapAlloc(); h > s.max {
+			s.max = h
+		}
+	}
+}
+
+func heapAlloc() uint64 {
+	ms := &runtime.MemStats{}
+	runtime.ReadMemStats(ms)
+	return ms.HeapAlloc
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Period is an ISO 8601 duration, eg. "P1W" or "PT12H". Years, months &
+// days are kept apart from clock time so they follow the calendar
+type Period struct {
+	Years, Months, Days int
+	Time                time.Duration
+}
+
+var periodRegexp = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParsePeriod reads an ISO 8601 duration
+func ParsePeriod(s string) (Period, error) {
+	p := Period{}
+	m := periodRegexp.FindStringSubmatch(s)
+	if m == nil || strings.HasSuffix(s, "T") {
+		return p, fmt.Errorf("invalid duration: '%s'", s)
+	}
+
+	num := func(i int) int {
+		n, _ := strconv.Atoi(m[i])
+		return n
+	}
+	p.Years = num(1)
+	p.Months = num(2)
+	p.Days = num(3)*7 + num(4)
+	p.Time = time.Duration(num(5))*time.Hour + time.Duration(num(6))*time.Minute
+	if m[7] != "" {
+		secs, _ := strconv.ParseFloat(m[7], 64)
+		p.Time += time.Duration(secs * float64(time.Second))
+	}
+
+	if p.IsZero() {
+		return p, fmt.Errorf("invalid duration: '%s' is zero", s)
+	}
+	return p, nil
+}
+
+// IsZero is true if a period has no length
+func (p Period) IsZero() bool {
+	return p.Years == 0 && p.Months == 0 && p.Days == 0 && p.Time == 0
+}
+
+// AddTo gives the time a period after t
+func (p Period) AddTo(t time.Time) time.Time {
+	return t.AddDate(p.Years, p.Months, p.Days).Add(p.Time)
+}
+
+// RepeatingInterval is an ISO 8601 repeating interval, the format of
+// dataset.Meta.AccrualPeriodicity. "R/P1W" repeats every week forever,
+// "R5/2018-01-01T00:00:00Z/P1D" repeats daily five times from the start
+// of 2018
+type RepeatingInterval struct {
+	// Repetitions is the number of times the interval repeats, -1 repeats
+	// forever
+	Repetitions int
+	// Start is the first repetition, zero if the interval doesn't say
+	Start  time.Time
+	Period Period
+}
+
+// ParseRepeatingInterval reads an ISO 8601 repeating interval. A lone
+// duration is read as repeating forever
+func ParseRepeatingInterval(s string) (*RepeatingInterval, error) {
+	parts := strings.Split(s, "/")
+	ri := &RepeatingInterval{Repetitions: -1}
+	if len(parts) == 1 {
+		p, err := ParsePeriod(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		ri.Period = p
+		return ri, nil
+	}
+
+	if len(parts) > 3 || !strings.HasPrefix(parts[0], "R") {
+		return nil, fmt.Errorf("invalid repeating interval: '%s'", s)
+	}
+	if n := parts[0][1:]; n != "" {
+		reps, err := strconv.Atoi(n)
+		if err != nil || reps < 0 {
+			return nil, fmt.Errorf("invalid repetitions: '%s'", parts[0])
+		}
+		ri.Repetitions = reps
+	}
+	if len(parts) == 3 {
+		start, err := time.Parse(time.RFC3339, parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval start: '%s'", parts[1])
+		}
+		ri.Start = start
+	}
+
+	p, err := ParsePeriod(parts[len(parts)-1])
+	if err != nil {
+		return nil, err
+	}
+	ri.Period = p
+	return ri, nil
+}
+
+// Next gives the first repetition after last. Intervals with a start
+// repeat in step with it, others repeat a period after last
+func (ri *RepeatingInterval) Next(last time.Time) time.Time {
+	if ri.Start.IsZero() {
+		return ri.Period.AddTo(last)
+	}
+	next := ri.Start
+	for !next.After(last) {
+		next = ri.Period.AddTo(next)
+	}
+	return next
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRepeatingInterval(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		in     string
+		reps   int
+		start  time.Time
+		period Period
+		err    string
+	}{
+		{"R/P1W", -1, time.Time{}, Period{Days: 7}, ""},
+		{"P1Y2M3D", -1, time.Time{}, Period{Years: 1, Months: 2, Days: 3}, ""},
+		{"R5/2018-01-01T00:00:00Z/PT1H30M", 5, start, Period{Time: 90 * time.Minute}, ""},
+		{"R/PT0.5S", -1, time.Time{}, Period{Time: 500 * time.Millisecond}, ""},
+		{"R/P", 0, time.Time{}, Period{}, "invalid duration: 'P' is zero"},
+		{"R/P1DT", 0, time.Time{}, Period{}, "invalid duration: 'P1DT'"},
+		{"R/1W", 0, time.Time{}, Period{}, "invalid duration: '1W'"},
+		{"Rx/P1D", 0, time.Time{}, Period{}, "invalid repetitions: 'Rx'"},
+		{"R/yesterday/P1D", 0, time.Time{}, Period{}, "invalid interval start: 'yesterday'"},
+		{"X/P1D", 0, time.Time{}, Period{}, "invalid repeating interval: 'X/P1D'"},
+	}
+
+	for i, c := range cases {
+		got, err := ParseRepeatingInterval(c.in)
+		if !(err == nil && c.err == "" || err != nil && err.Error() == c.err) {
+			t.Errorf("case %d error mismatch. expected: %s, got: %v", i, c.err, err)
+			continue
+		}
+		if c.err != "" {
+			continue
+		}
+		if got.Repetitions != c.reps || !got.Start.Equal(c.start) || got.Period != c.period {
+			t.Errorf("case %d mismatch. expected: %d %s %v, got: %d %s %v", i, c.reps, c.start, c.period, got.Repetitions, got.Start, got.Period)
+		}
+	}
+}
+
+func TestRepeatingIntervalNext(t *testing.T) {
+	last := time.Date(2018, 1, 31, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		in     string
+		expect time.Time
+	}{
+		{"R/P1D", time.Date(2018, 2, 1, 12, 0, 0, 0, time.UTC)},
+		{"R/P1M", time.Date(2018, 3, 3, 12, 0, 0, 0, time.UTC)},
+		{"R/2018-01-01T00:00:00Z/P1W", time.Date(2018, 2, 5, 0, 0, 0, 0, time.UTC)},
+		{"R/2018-06-01T00:00:00Z/P1W", time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for i, c := range cases {
+		ri, err := ParseRepeatingInterval(c.in)
+		if err != nil {
+			t.Errorf("case %d unexpected error: %s", i, err.Error())
+			continue
+		}
+		if got := ri.Next(last); !got.Equal(c.expect) {
+			t.Errorf("case %d mismatch. expected: %s, got: %s", i, c.expect, got)
+		}
+	}
+}
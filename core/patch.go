@@ -0,0 +1,314 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/dsio"
+	"github.com/qri-io/jsonschema"
+)
+
+// primaryKeyword is the schema keyword that lists the fields identifying
+// each entry of a dataset, following the primaryKey of frictionless
+// data's table schema
+const primaryKeyword = "primaryKey"
+
+// PatchStats counts changes a patch made to a dataset
+type PatchStats struct {
+	Inserts int `json:"inserts"`
+	Updates int `json:"updates"`
+	Deletes int `json:"deletes"`
+}
+
+// String summarizes a patch, for use in commit titles
+func (s PatchStats) String() string {
+	return fmt.Sprintf("%d inserted, %d updated, %d deleted", s.Inserts, s.Updates, s.Deletes)
+}
+
+// PrimaryKey reads the fields identifying each entry of a dataset from a
+// structure's schema. Fields are column titles for datasets of arrays, and
+// object keys for datasets of objects
+func PrimaryKey(st *dataset.Structure) ([]string, error) {
+	if st == nil || st.Schema == nil {
+		return nil, nil
+	}
+	sch, err := schemaMap(st.Schema)
+	if err != nil {
+		return nil, err
+	}
+	switch key := sch[primaryKeyword].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{key}, nil
+	case []interface{}:
+		fields := make([]string, len(key))
+		for i, f := range key {
+			s, ok := f.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s: %v", primaryKeyword, key)
+			}
+			fields[i] = s
+		}
+		return fields, nil
+	default:
+		return nil, fmt.Errorf("invalid %s: %v", primaryKeyword, key)
+	}
+}
+
+// SetPrimaryKey declares the fields identifying each entry of a dataset in
+// a structure's schema
+func SetPrimaryKey(st *dataset.Structure, fields []string) error {
+	if st.Schema == nil {
+		return fmt.Errorf("a schema is required to set a primary key")
+	}
+	sch, err := schemaMap(st.Schema)
+	if err != nil {
+		return err
+	}
+	sch[primaryKeyword] = fields
+	data, err := json.Marshal(sch)
+	if err != nil {
+		return err
+	}
+	rs := &jsonschema.RootSchema{}
+	if err := rs.UnmarshalJSON(data); err != nil {
+		return fmt.Errorf("error reading schema: %s", err.Error())
+	}
+	st.Schema = rs
+	return nil
+}
+
+func schemaMap(rs *jsonschema.RootSchema) (map[string]interface{}, error) {
+	data, err := json.Marshal(rs)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding schema: %s", err.Error())
+	}
+	sch := map[string]interface{}{}
+	if err := json.Unmarshal(data, &sch); err != nil {
+		return nil, fmt.Errorf("error decoding schema: %s", err.Error())
+	}
+	return sch, nil
+}
+
+// entryKeyer builds the primary key of entries
+type entryKeyer struct {
+	fields []string
+	// columns holds the index of each field for datasets of arrays
+	columns []int
+}
+
+// newEntryKeyer creates a keyer for a structure. Fields of datasets of
+// arrays are found by column title
+func newEntryKeyer(st *dataset.Structure, fields []string) (*entryKeyer, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("a primary key is required to patch a dataset")
+	}
+	k := &entryKeyer{fields: fields}
+
+	sch, err := schemaMap(st.Schema)
+	if err != nil {
+		return nil, err
+	}
+	items, _ := sch["items"].(map[string]interface{})
+	if items == nil || items["type"] != "array" {
+		return k, nil
+	}
+	cols, _ := items["items"].([]interface{})
+	for _, f := range fields {
+		idx := -1
+		for i, c := range cols {
+			if col, ok := c.(map[string]interface{}); ok && col["title"] == f {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("primary key field '%s' isn't a column of this dataset", f)
+		}
+		k.columns = append(k.columns, idx)
+	}
+	return k, nil
+}
+
+// key gives the primary key of an entry, and whether the entry holds only
+// its key, flagging it for removal
+func (k *entryKeyer) key(ent dsio.Entry) (key string, keyOnly bool, err error) {
+	vals := make([]string, len(k.fields))
+	keyOnly = true
+	switch v := ent.Value.(type) {
+	case []interface{}:
+		if k.columns == nil {
+			return "", false, fmt.Errorf("entry %d is an array, expected an object", ent.Index)
+		}
+		isKey := map[int]bool{}
+		for i, col := range k.columns {
+			if col >= len(v) || empty(v[col]) {
+				return "", false, fmt.Errorf("entry %d is missing primary key field '%s'", ent.Index, k.fields[i])
+			}
+			vals[i] = fmt.Sprintf("%v", v[col])
+			isKey[col] = true
+		}
+		for i, val := range v {
+			if !isKey[i] && !empty(val) {
+				keyOnly = false
+			}
+		}
+	case map[string]interface{}:
+		isKey := map[string]bool{}
+		for i, f := range k.fields {
+			if empty(v[f]) {
+				return "", false, fmt.Errorf("entry %d is missing primary key field '%s'", ent.Index, f)
+			}
+			vals[i] = fmt.Sprintf("%v", v[f])
+			isKey[f] = true
+		}
+		for f, val := range v {
+			if !isKey[f] && !empty(val) {
+				keyOnly = false
+			}
+		}
+	default:
+		return "", false, fmt.Errorf("entry %d must be an array or object to have a primary key", ent.Index)
+	}
+	return strings.Join(vals, "\x00"), keyOnly, nil
+}
+
+func empty(v interface{}) bool {
+	return v == nil || v == ""
+}
+
+// patchRow is a single entry of a patch
+type patchRow struct {
+	ent    dsio.Entry
+	delete bool
+	used   bool
+}
+
+// patchData applies the entries of a patch to prev's data, writing the
+// result to a store. Patch entries are matched to existing entries by the
+// fields in key. Matches replace existing entries, and entries with new
+// keys are added to the end of the data. If deletes is true, patch entries
+// that hold nothing but their key remove the matching entry instead.
+// Patches are held in memory, existing data is streamed
+func patchData(store cafs.Filestore, prev *dataset.Dataset, key []string, deletes bool, r io.Reader) (datastore.Key, *dataset.Structure, PatchStats, error) {
+	stats := PatchStats{}
+	keyer, err := newEntryKeyer(prev.Structure, key)
+	if err != nil {
+		return datastore.NewKey(""), nil, stats, err
+	}
+
+	// read the patch
+	rows := map[string]*patchRow{}
+	order := []string{}
+	er, err := dsio.NewEntryReader(prev.Structure, r)
+	if err != nil {
+		return datastore.NewKey(""), nil, stats, fmt.Errorf("error reading patch: %s", err.Error())
+	}
+	for {
+		ent, err := er.ReadEntry()
+		if err != nil {
+			if err.Error() == "EOF" {
+				break
+			}
+			return datastore.NewKey(""), nil, stats, fmt.Errorf("error reading patch: %s", err.Error())
+		}
+		k, keyOnly, err := keyer.key(ent)
+		if err != nil {
+			return datastore.NewKey(""), nil, stats, fmt.Errorf("error reading patch: %s", err.Error())
+		}
+		if _, dup := rows[k]; dup {
+			return datastore.NewKey(""), nil, stats, fmt.Errorf("patch has more than one entry for key %s", strings.Replace(k, "\x00", ", ", -1))
+		}
+		rows[k] = &patchRow{ent: ent, delete: deletes && keyOnly}
+		order = append(order, k)
+	}
+	if len(rows) == 0 {
+		return datastore.NewKey(""), nil, stats, fmt.Errorf("patch has no entries")
+	}
+
+	st := &dataset.Structure{}
+	st.Assign(prev.Structure)
+	pr, pw := io.Pipe()
+	// the writer keeps its own counts, sending them once it's done so
+	// they're never read while it's running
+	counted := make(chan PatchStats, 1)
+	go func() {
+		stats := PatchStats{}
+		defer func() { counted <- stats }()
+		pw.CloseWithError(func() error {
+			f, err := store.Get(datastore.NewKey(prev.DataPath))
+			if err != nil {
+				return fmt.Errorf("error loading data: %s", err.Error())
+			}
+			defer f.Close()
+			er, err := dsio.NewEntryReader(st, f)
+			if err != nil {
+				return fmt.Errorf("error reading data: %s", err.Error())
+			}
+			ew, err := dsio.NewEntryWriter(st, pw)
+			if err != nil {
+				return fmt.Errorf("error allocating data writer: %s", err.Error())
+			}
+
+			for {
+				ent, err := er.ReadEntry()
+				if err != nil {
+					if err.Error() == "EOF" {
+						break
+					}
+					return fmt.Errorf("error reading data: %s", err.Error())
+				}
+				k, _, err := keyer.key(ent)
+				if err != nil {
+					return err
+				}
+				if row, ok := rows[k]; ok && !row.used {
+					row.used = true
+					if row.delete {
+						stats.Deletes++
+						continue
+					}
+					if !reflect.DeepEqual(row.ent.Value, ent.Value) {
+						stats.Updates++
+					}
+					ent.Value = row.ent.Value
+				}
+				if err := ew.WriteEntry(ent); err != nil {
+					return fmt.Errorf("error writing data: %s", err.Error())
+				}
+			}
+
+			for _, k := range order {
+				if row := rows[k]; !row.used && !row.delete {
+					stats.Inserts++
+					if err := ew.WriteEntry(row.ent); err != nil {
+						return fmt.Errorf("error writing data: %s", err.Error())
+					}
+				}
+			}
+			return ew.Close()
+		}())
+	}()
+
+	dataKey, err := putData(store, st, pr)
+	// unblock the writer if the store stopped reading early
+	pr.Close()
+	stats = <-counted
+	if err != nil {
+		return datastore.NewKey(""), nil, stats, err
+	}
+	if stats == (PatchStats{}) {
+		return datastore.NewKey(""), nil, stats, fmt.Errorf("patch makes no changes")
+	}
+	if err := validateStoredData(store, st, dataKey.String()); err != nil {
+		return datastore.NewKey(""), nil, stats, err
+	}
+	return dataKey, st, stats, nil
+}
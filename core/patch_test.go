@@ -0,0 +1,69 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/dsio"
+	"github.com/qri-io/jsonschema"
+)
+
+func TestPrimaryKey(t *testing.T) {
+	st := &dataset.Structure{Format: dataset.JSONDataFormat, Schema: dataset.BaseSchemaArray}
+	if key, err := PrimaryKey(st); err != nil || key != nil {
+		t.Errorf("expected no primary key. got: %v, %v", key, err)
+	}
+	if err := SetPrimaryKey(st, []string{"id", "date"}); err != nil {
+		t.Errorf("error setting primary key: %s", err.Error())
+		return
+	}
+	key, err := PrimaryKey(st)
+	if err != nil {
+		t.Errorf("error reading primary key: %s", err.Error())
+		return
+	}
+	if len(key) != 2 || key[0] != "id" || key[1] != "date" {
+		t.Errorf("primary key mismatch. expected: [id date], got: %v", key)
+	}
+}
+
+func TestEntryKeyer(t *testing.T) {
+	rs := &jsonschema.RootSchema{}
+	if err := rs.UnmarshalJSON([]byte(`{"type":"array","items":{"type":"array","items":[{"title":"id","type":"string"},{"title":"count","type":"integer"}]}}`)); err != nil {
+		t.Errorf("error reading schema: %s", err.Error())
+		return
+	}
+	st := &dataset.Structure{Format: dataset.CSVDataFormat, Schema: rs}
+
+	if _, err := newEntryKeyer(st, []string{"missing"}); err == nil {
+		t.Errorf("expected a key that isn't a column to error")
+	}
+	k, err := newEntryKeyer(st, []string{"id"})
+	if err != nil {
+		t.Errorf("error creating keyer: %s", err.Error())
+		return
+	}
+
+	cases := []struct {
+		ent     dsio.Entry
+		key     string
+		keyOnly bool
+		err     string
+	}{
+		{dsio.Entry{Value: []interface{}{"a", "1"}}, "a", false, ""},
+		{dsio.Entry{Value: []interface{}{"b", ""}}, "b", true, ""},
+		{dsio.Entry{Value: map[string]interface{}{"id": "c", "count": nil}}, "c", true, ""},
+		{dsio.Entry{Index: 3, Value: []interface{}{"", "1"}}, "", false, "entry 3 is missing primary key field 'id'"},
+		{dsio.Entry{Index: 4, Value: "d"}, "", false, "entry 4 must be an array or object to have a primary key"},
+	}
+	for i, c := range cases {
+		key, keyOnly, err := k.key(c.ent)
+		if !(err == nil && c.err == "" || err != nil && err.Error() == c.err) {
+			t.Errorf("case %d error mismatch. expected: %s, got: %v", i, c.err, err)
+			continue
+		}
+		if key != c.key || keyOnly != c.keyOnly {
+			t.Errorf("case %d mismatch. expected: %s, %t. got: %s, %t", i, c.key, c.keyOnly, key, keyOnly)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/dsfs"
+	"github.com/qri-io/dsdiff"
+	"github.com/qri-io/qri/repo"
+	"github.com/qri-io/qri/repo/actions"
+)
+
+// SavePreview is the result of a dry-run save
+type SavePreview struct {
+	// Dataset is the version saving would create. Paths within it point to
+	// content held in memory for the preview, and won't match the paths
+	// of a real save
+	Dataset *dataset.Dataset `json:"dataset"`
+	// Diff lists changes from the previous version, by component
+	Diff map[string]*dsdiff.SubDiff `json:"diff"`
+}
+
+// SaveDryRun runs a save without changing the repo, returning the version
+// the save would create & its differences from the version it builds on.
+// New content is written to memory instead of the store, and no references
+// are updated
+func (r *DatasetRequests) SaveDryRun(p *SaveParams, res *SavePreview) error {
+	if r.cli != nil {
+		return r.cli.Call("DatasetRequests.SaveDryRun", p, res)
+	}
+
+	_, prev, _, err := r.saveBase(p)
+	if err != nil {
+		return err
+	}
+
+	pr := &previewRepo{Repo: r.repo.Repo, store: newPreviewStore(r.repo.Store())}
+	preview := &DatasetRequests{repo: actions.Dataset{pr}, Remote: r.Remote}
+	ds, dataf, err := preview.prepareSave(prev, p)
+	if err != nil {
+		return err
+	}
+	defer dataf.Close()
+
+	path, err := preview.repo.WriteDataset(ds, dataf, false)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error creating dataset: %s", err.Error())
+	}
+	next, err := dsfs.LoadDataset(pr.store, path)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error loading dataset: %s", err.Error())
+	}
+
+	diffs := map[string]*dsdiff.SubDiff{}
+	dp := &DiffParams{
+		DsLeft:  prev.Dataset,
+		DsRight: next,
+		DiffComponents: map[string]bool{
+			"structure": true,
+			"data":      true,
+			"meta":      true,
+			"transform": true,
+			"visConfig": true,
+		},
+	}
+	if err := preview.Diff(dp, &diffs); err != nil {
+		return err
+	}
+
+	*res = SavePreview{Dataset: next, Diff: diffs}
+	return nil
+}
+
+// previewRepo wraps a repo, keeping new content in memory & refusing
+// to change references
+type previewRepo struct {
+	repo.Repo
+	store *previewStore
+}
+
+// Store gives the preview's store
+func (r *previewRepo) Store() cafs.Filestore {
+	return r.store
+}
+
+// Analytics gives throwaway analytics, so previews aren't counted as use
+func (r *previewRepo) Analytics() repo.Analytics {
+	return repo.NewMemAnalytics()
+}
+
+// PutRef errors, previews can't add references
+func (r *previewRepo) PutRef(ref repo.DatasetRef) error {
+	return fmt.Errorf("can't add references while previewing a save")
+}
+
+// DeleteRef errors, previews can't remove references
+func (r *previewRepo) DeleteRef(ref repo.DatasetRef) error {
+	return fmt.Errorf("can't remove references while previewing a save")
+}
+
+// SourceValidators gives the credentials name from the underlying repo's
+// source cache, without cache validators. Previews always download urls, so
+// they can show what a save would change
+func (r *previewRepo) SourceValidators(ref repo.DatasetRef) (repo.SourceValidators, error) {
+	cache, ok := repo.AsSourceCache(r.Repo)
+	if !ok {
+		return repo.SourceValidators{}, repo.ErrNotFound
+	}
+	v, err := cache.SourceValidators(ref)
+	if err != nil {
+		return v, err
+	}
+	return repo.SourceValidators{URL: v.URL, Auth: v.Auth}, nil
+}
+
+// PutSourceValidators does nothing, previews don't change the source cache
+func (r *previewRepo) PutSourceValidators(ref repo.DatasetRef, v repo.SourceValidators) error {
+	return nil
+}
+
+// previewStore is a cafs.Filestore that reads from an underlying store &
+// writes to memory, leaving the underlying store untouched
+type previewStore struct {
+	store  cafs.Filestore
+	writes cafs.Filestore
+}
+
+func newPreviewStore(store cafs.Filestore) *previewStore {
+	return &previewStore{store: store, writes: cafs.NewMapstore()}
+}
+
+// PathPrefix returns the prefix on paths of content written to memory
+func (s *previewStore) PathPrefix() string {
+	return s.writes.PathPrefix()
+}
+
+// Put adds a file to memory. Pinning is ignored
+func (s *previewStore) Put(file cafs.File, pin bool) (datastore.Key, error) {
+	return s.writes.Put(file, false)
+}
+
+// Get fetches a file from memory, or the underlying store
+func (s *previewStore) Get(key datastore.Key) (cafs.File, error) {
+	if f, err := s.writes.Get(key); err == nil {
+		return f, nil
+	}
+	return s.store.Get(key)
+}
+
+// Has checks memory, then the underlying store for a key
+func (s *previewStore) Has(key datastore.Key) (bool, error) {
+	if has, err := s.writes.Has(key); err == nil && has {
+		return true, nil
+	}
+	return s.store.Has(key)
+}
+
+// Delete removes a file from memory. Content in the underlying store
+// can't be deleted
+func (s *previewStore) Delete(key datastore.Key) error {
+	return s.writes.Delete(key)
+}
+
+// NewAdder creates an adder that adds files to memory
+func (s *previewStore) NewAdder(pin, wrap bool) (cafs.Adder, error) {
+	return s.writes.NewAdder(false, wrap)
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/rpc"
+
+	"github.com/qri-io/qri/repo"
+)
+
+// RepoRequests encapsulates business logic for maintaining a qri repository
+type RepoRequests struct {
+	repo repo.Repo
+	cli  *rpc.Client
+}
+
+// CoreRequestsName implements the Requests interface
+func (r RepoRequests) CoreRequestsName() string { return "repo" }
+
+// NewRepoRequests creates a RepoRequests pointer from either a repo
+// or an rpc.Client
+func NewRepoRequests(r repo.Repo, cli *rpc.Client) *RepoRequests {
+	if r != nil && cli != nil {
+		panic(fmt.Errorf("both repo and client supplied to NewRepoRequests"))
+	}
+	return &RepoRequests{
+		repo: r,
+		cli:  cli,
+	}
+}
+
+// GCParams defines parameters for the GC method
+type GCParams struct {
+	// DryRun reports reclaimable content without removing anything
+	DryRun bool
+}
+
+// GC removes content from the repo's store that no dataset reference
+// depends on
+func (r *RepoRequests) GC(p *GCParams, res *repo.GCResult) error {
+	if r.cli != nil {
+		return r.cli.Call("RepoRequests.GC", p, res)
+	}
+
+	result, err := repo.GarbageCollect(r.repo, p.DryRun)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error collecting garbage: %s", err.Error())
+	}
+	*res = *result
+	return nil
+}
+
+// FsckParams defines parameters for the Fsck method
+type FsckParams struct {
+	// Repair drops references to datasets that can't be loaded, and re-pins
+	// referenced datasets
+	Repair bool
+}
+
+// Fsck checks the integrity of the repo, optionally repairing problems
+func (r *RepoRequests) Fsck(p *FsckParams, res *repo.FsckReport) error {
+	if r.cli != nil {
+		return r.cli.Call("RepoRequests.Fsck", p, res)
+	}
+
+	report, err := repo.Fsck(r.repo, p.Repair)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error checking repo: %s", err.Error())
+	}
+	*res = *report
+	return nil
+}
+
+// Stats reports the size of the repo: references, versions, distinct
+// blocks, bytes per dataset, pinned bytes & growth over time
+func (r *RepoRequests) Stats(in *bool, res *repo.Summary) error {
+	if r.cli != nil {
+		return r.cli.Call("RepoRequests.Stats", in, res)
+	}
+
+	stats, err := repo.Stats(context.Background(), r.repo)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error calculating repo stats: %s", err.Error())
+	}
+	*res = *stats
+	return nil
+}
@@ -0,0 +1,301 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/qri/repo"
+	testrepo "github.com/qri-io/qri/repo/test"
+)
+
+func TestGC(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	req := NewRepoRequests(mr, nil)
+
+	res := &repo.GCResult{}
+	if err := req.GC(&GCParams{DryRun: true}, res); err != nil {
+		t.Errorf("error collecting garbage: %s", err.Error())
+		return
+	}
+	if len(res.Garbage) != 0 {
+		t.Errorf("expected no garbage in a fresh repo. got: %v", res.Garbage)
+	}
+
+	ref, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "movies"})
+	if err != nil {
+		t.Errorf("error getting ref: %s", err.Error())
+		return
+	}
+	if err := mr.DeleteRef(ref); err != nil {
+		t.Errorf("error deleting ref: %s", err.Error())
+		return
+	}
+
+	res = &repo.GCResult{}
+	if err := req.GC(&GCParams{DryRun: true}, res); err != nil {
+		t.Errorf("error collecting garbage: %s", err.Error())
+		return
+	}
+	if len(res.Garbage) == 0 || res.Bytes == 0 {
+		t.Errorf("expected dry run to report reclaimable content")
+	}
+	if has, _ := mr.Store().Has(datastore.NewKey(ref.Path)); !has {
+		t.Errorf("dry run shouldn't remove content")
+	}
+
+	res = &repo.GCResult{}
+	if err := req.GC(&GCParams{}, res); err != nil {
+		t.Errorf("error collecting garbage: %s", err.Error())
+		return
+	}
+	if has, _ := mr.Store().Has(datastore.NewKey(ref.Path)); has {
+		t.Errorf("expected unreferenced dataset to be removed")
+	}
+
+	live, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "cities"})
+	if err != nil {
+		t.Errorf("error getting ref: %s", err.Error())
+		return
+	}
+	if has, _ := mr.Store().Has(datastore.NewKey(live.Path)); !has {
+		t.Errorf("expected referenced dataset to be kept")
+	}
+}
+
+func TestGCChangeRequests(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	req := NewRepoRequests(mr, nil)
+
+	movies, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "movies"})
+	if err != nil {
+		t.Errorf("error getting ref: %s", err.Error())
+		return
+	}
+	cities, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "cities"})
+	if err != nil {
+		t.Errorf("error getting ref: %s", err.Error())
+		return
+	}
+	cr := &repo.ChangeRequest{
+		ID:       "proposal",
+		Status:   repo.CRStatusOpen,
+		Target:   cities,
+		Proposal: repo.DatasetRef{Peername: "other", Name: "cities", Path: movies.Path},
+	}
+	if err := mr.ChangeRequests().PutChangeRequest(cr); err != nil {
+		t.Errorf("error putting change request: %s", err.Error())
+		return
+	}
+	if err := mr.DeleteRef(movies); err != nil {
+		t.Errorf("error deleting ref: %s", err.Error())
+		return
+	}
+
+	if err := req.GC(&GCParams{}, &repo.GCResult{}); err != nil {
+		t.Errorf("error collecting garbage: %s", err.Error())
+		return
+	}
+	if has, _ := mr.Store().Has(datastore.NewKey(movies.Path)); !has {
+		t.Errorf("expected open change request proposal to be kept")
+	}
+
+	cr.Status = repo.CRStatusRejected
+	if err := mr.ChangeRequests().PutChangeRequest(cr); err != nil {
+		t.Errorf("error putting change request: %s", err.Error())
+		return
+	}
+	if err := req.GC(&GCParams{}, &repo.GCResult{}); err != nil {
+		t.Errorf("error collecting garbage: %s", err.Error())
+		return
+	}
+	if has, _ := mr.Store().Has(datastore.NewKey(movies.Path)); has {
+		t.Errorf("expected rejected change request proposal to be removed")
+	}
+}
+
+func TestFsck(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	req := NewRepoRequests(mr, nil)
+
+	res := &repo.FsckReport{}
+	if err := req.Fsck(&FsckParams{}, res); err != nil {
+		t.Errorf("error checking repo: %s", err.Error())
+		return
+	}
+	if res.Refs != 4 {
+		t.Errorf("ref count mismatch. expected: %d, got: %d", 4, res.Refs)
+	}
+	if len(res.Issues) != 0 {
+		t.Errorf("expected no issues in a fresh repo. got: %v", res.Issues)
+	}
+
+	ref, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "movies"})
+	if err != nil {
+		t.Errorf("error getting ref: %s", err.Error())
+		return
+	}
+	if err := mr.Store().Delete(datastore.NewKey(ref.Path)); err != nil {
+		t.Errorf("error deleting dataset: %s", err.Error())
+		return
+	}
+
+	res = &repo.FsckReport{}
+	if err := req.Fsck(&FsckParams{}, res); err != nil {
+		t.Errorf("error checking repo: %s", err.Error())
+		return
+	}
+	if len(res.Issues) != 1 || res.Issues[0].Type != repo.FsckDanglingRef {
+		t.Errorf("expected a single dangling ref issue. got: %v", res.Issues)
+		return
+	}
+
+	res = &repo.FsckReport{}
+	if err := req.Fsck(&FsckParams{Repair: true}, res); err != nil {
+		t.Errorf("error repairing repo: %s", err.Error())
+		return
+	}
+	if res.Unrepaired() != 0 {
+		t.Errorf("expected all issues to be repaired. got: %v", res.Issues)
+	}
+	if _, err := mr.GetRef(ref); err != repo.ErrNotFound {
+		t.Errorf("expected dangling ref to be dropped. got: %v", err)
+	}
+}
+
+// pinStore tracks pins on top of another store
+type pinStore struct {
+	cafs.Filestore
+	pins   map[string]bool
+	pinErr error
+}
+
+func (s *pinStore) Pin(key datastore.Key, recursive bool) error {
+	if s.pinErr != nil {
+		return s.pinErr
+	}
+	s.pins[key.String()] = true
+	return nil
+}
+
+func (s *pinStore) Unpin(key datastore.Key, recursive bool) error {
+	delete(s.pins, key.String())
+	return nil
+}
+
+func (s *pinStore) IsPinned(key datastore.Key) (bool, error) {
+	return s.pins[key.String()], nil
+}
+
+// pinRepo swaps the store of a repo
+type pinRepo struct {
+	repo.Repo
+	store *pinStore
+}
+
+func (r pinRepo) Store() cafs.Filestore {
+	return r.store
+}
+
+func TestFsckPins(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	store := &pinStore{Filestore: mr.Store(), pins: map[string]bool{}, pinErr: fmt.Errorf("store is full")}
+	req := NewRepoRequests(pinRepo{Repo: mr, store: store}, nil)
+
+	res := &repo.FsckReport{}
+	if err := req.Fsck(&FsckParams{}, res); err != nil {
+		t.Errorf("error checking repo: %s", err.Error())
+		return
+	}
+	if len(res.Issues) != 4 {
+		t.Errorf("expected an issue for each unpinned dataset. got: %v", res.Issues)
+	}
+	for _, is := range res.Issues {
+		if is.Type != repo.FsckUnpinned {
+			t.Errorf("expected unpinned issue. got: %s", is.Type)
+		}
+	}
+
+	res = &repo.FsckReport{}
+	if err := req.Fsck(&FsckParams{Repair: true}, res); err != nil {
+		t.Errorf("error repairing repo: %s", err.Error())
+		return
+	}
+	if res.Unrepaired() != 4 {
+		t.Errorf("expected failed pins to be reported unrepaired. got: %v", res.Issues)
+	}
+
+	store.pinErr = nil
+	res = &repo.FsckReport{}
+	if err := req.Fsck(&FsckParams{Repair: true}, res); err != nil {
+		t.Errorf("error repairing repo: %s", err.Error())
+		return
+	}
+	if len(res.Issues) != 4 || res.Unrepaired() != 0 {
+		t.Errorf("expected 4 repaired issues. got: %v", res.Issues)
+	}
+
+	res = &repo.FsckReport{}
+	if err := req.Fsck(&FsckParams{}, res); err != nil {
+		t.Errorf("error checking repo: %s", err.Error())
+		return
+	}
+	if len(res.Issues) != 0 {
+		t.Errorf("expected no issues once pinned. got: %v", res.Issues)
+	}
+}
+
+func TestStats(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	req := NewRepoRequests(mr, nil)
+
+	res := &repo.Summary{}
+	if err := req.Stats(nil, res); err != nil {
+		t.Errorf("error calculating stats: %s", err.Error())
+		return
+	}
+	if res.Refs != 4 || res.Versions != 4 {
+		t.Errorf("expected 4 refs & 4 versions. got: %d, %d", res.Refs, res.Versions)
+	}
+	if res.Blocks == 0 || res.DataBlocks != 4 || res.Bytes == 0 {
+		t.Errorf("expected blocks to be counted. got: %d blocks, %d data blocks, %d bytes", res.Blocks, res.DataBlocks, res.Bytes)
+	}
+	if len(res.Datasets) != 4 {
+		t.Errorf("expected stats for 4 datasets. got: %d", len(res.Datasets))
+		return
+	}
+	var total int64
+	for _, ds := range res.Datasets {
+		if ds.Versions != 1 || ds.OwnBytes == 0 {
+			t.Errorf("expected %s to have 1 version & its own blocks. got: %d, %d", ds.Ref.AliasString(), ds.Versions, ds.OwnBytes)
+		}
+		total += ds.OwnBytes
+	}
+	if total > res.Bytes {
+		t.Errorf("dataset bytes %d exceed repo bytes %d", total, res.Bytes)
+	}
+	if len(res.Growth) == 0 || res.Growth[len(res.Growth)-1].TotalVersions != 4 {
+		t.Errorf("expected growth to record 4 versions. got: %v", res.Growth)
+	}
+}
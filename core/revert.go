@@ -0,0 +1,96 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/dsfs"
+	"github.com/qri-io/qri/repo"
+)
+
+// RevertParams defines parameters for the Revert method
+type RevertParams struct {
+	// Ref is the version to revert to. Ref.Path is required
+	Ref     repo.DatasetRef
+	Branch  string // branch to revert. optional, defaults to repo.DefaultBranch
+	Title   string // commit title. optional, defaults to naming the version reverted to
+	Message string // commit message. optional.
+}
+
+// Revert saves a new version of a dataset with the data, structure & meta
+// of an earlier version. Other components, like transforms & viz, are kept
+// as they are at the head. The new version builds on the head of the branch
+// being reverted, so history is kept. The version reverted to must be in
+// the history of that head
+func (r *DatasetRequests) Revert(p *RevertParams, res *repo.DatasetRef) error {
+	if r.cli != nil {
+		return r.cli.Call("DatasetRequests.Revert", p, res)
+	}
+
+	target := p.Ref.Path
+	if target == "" {
+		return fmt.Errorf("a version to revert to is required, eg: me/dataset@/ipfs/Qm...")
+	}
+
+	prevReq, prev, branch, err := r.saveBase(&SaveParams{Peername: p.Ref.Peername, Name: p.Ref.Name, Branch: p.Branch})
+	if err != nil {
+		return err
+	}
+	if target == prev.Path {
+		return fmt.Errorf("%s is already the latest version of %s", target, prev.AliasString())
+	}
+
+	store := r.repo.Store()
+	if err := inHistory(store, prev.Path, target); err != nil {
+		return err
+	}
+	old, err := dsfs.LoadDataset(store, datastore.NewKey(target))
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error loading dataset version '%s': %s", target, err.Error())
+	}
+	if old.Structure == nil {
+		return fmt.Errorf("version %s has no structure to revert to", target)
+	}
+
+	// only data, structure & meta are reverted, everything else stays as
+	// it is at the head
+	ds := &dataset.Dataset{}
+	ds.Assign(prev.Dataset)
+	ds.Meta, ds.Structure, ds.DataPath = old.Meta, old.Structure, old.DataPath
+	ds.PreviousPath = prev.Path
+	ds.Commit = &dataset.Commit{Title: p.Title, Message: p.Message}
+	if ds.Commit.Title == "" {
+		ds.Commit.Title = fmt.Sprintf("revert to %s", target)
+	}
+	if ds.Commit.Message == "" && old.Commit != nil && old.Commit.Title != "" {
+		ds.Commit.Message = fmt.Sprintf("reverts to the version titled '%s'", old.Commit.Title)
+	}
+
+	// reset paths so components are written as part of the new version,
+	// the way a save's are
+	if ds.Meta != nil {
+		ds.Meta.SetPath("")
+	}
+	ds.Structure.SetPath("")
+
+	dataf, err := storedData(store, ds.Structure, old.DataPath)
+	if err != nil {
+		return fmt.Errorf("error loading data from store: %s", err.Error())
+	}
+	defer dataf.Close()
+
+	if branch != "" {
+		return r.saveBranch(branch, prevReq, ds, dataf, res)
+	}
+
+	ref, err := r.repo.SaveDataset(prevReq.Name, ds, dataf, true)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error saving dataset: %s", err.Error())
+	}
+	ref.Dataset = ds
+	*res = ref
+	return nil
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/qri-io/qri/repo"
+	testrepo "github.com/qri-io/qri/repo/test"
+)
+
+func TestDatasetRequestsRevert(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	req := NewDatasetRequests(mr, nil)
+
+	v1 := &repo.DatasetRef{}
+	if err := req.Get(&repo.DatasetRef{Peername: "peer", Name: "cities"}, v1); err != nil {
+		t.Errorf("error getting dataset: %s", err.Error())
+		return
+	}
+
+	v2 := &repo.DatasetRef{}
+	save := &SaveParams{
+		Peername: "peer",
+		Name:     "cities",
+		Data:     bytes.NewReader([]byte("city,pop,avg_age,in_usa\nboston,700000,35.5,true\n")),
+		Append:   true,
+	}
+	if err := req.Save(save, v2); err != nil {
+		t.Errorf("error saving dataset: %s", err.Error())
+		return
+	}
+
+	res := &repo.DatasetRef{}
+	if err := req.Revert(&RevertParams{Ref: repo.DatasetRef{Peername: "peer", Name: "cities", Path: v1.Path}}, res); err != nil {
+		t.Errorf("error reverting dataset: %s", err.Error())
+		return
+	}
+	if res.Path == v1.Path || res.Path == v2.Path {
+		t.Errorf("expected revert to create a new version")
+	}
+	if res.Dataset.PreviousPath != v2.Path {
+		t.Errorf("previous path mismatch. expected: %s, got: %s", v2.Path, res.Dataset.PreviousPath)
+	}
+	if res.Dataset.Structure.Checksum != v1.Dataset.Structure.Checksum {
+		t.Errorf("expected reverted data to match the version reverted to")
+	}
+	if res.Dataset.Structure.Entries != v1.Dataset.Structure.Entries {
+		t.Errorf("entries mismatch. expected: %d, got: %d", v1.Dataset.Structure.Entries, res.Dataset.Structure.Entries)
+	}
+	if res.Dataset.Commit.Title != "revert to "+v1.Path {
+		t.Errorf("commit title mismatch. got: %s", res.Dataset.Commit.Title)
+	}
+
+	head, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "cities"})
+	if err != nil {
+		t.Errorf("error getting reference: %s", err.Error())
+		return
+	}
+	if head.Path != res.Path {
+		t.Errorf("expected revert to move the dataset's reference. expected: %s, got: %s", res.Path, head.Path)
+	}
+
+	movies, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "movies"})
+	if err != nil {
+		t.Errorf("error getting reference: %s", err.Error())
+		return
+	}
+	bad := []struct {
+		ref repo.DatasetRef
+		err string
+	}{
+		{repo.DatasetRef{Peername: "peer", Name: "cities"}, "a version to revert to is required, eg: me/dataset@/ipfs/Qm..."},
+		{repo.DatasetRef{Peername: "peer", Name: "cities", Path: res.Path}, res.Path + " is already the latest version of peer/cities"},
+		{repo.DatasetRef{Peername: "peer", Name: "cities", Path: movies.Path}, "version " + movies.Path + " isn't in this dataset's history"},
+	}
+	for i, c := range bad {
+		err := req.Revert(&RevertParams{Ref: c.ref}, &repo.DatasetRef{})
+		if err == nil || err.Error() != c.err {
+			t.Errorf("case %d error mismatch. expected: %s, got: %v", i, c.err, err)
+		}
+	}
+
+	branched := &repo.DatasetRef{}
+	save = &SaveParams{
+		Peername: "peer",
+		Name:     "cities",
+		Branch:   "fix",
+		Data:     bytes.NewReader([]byte("city,pop,avg_age,in_usa\nparis,2200000,40.5,false\n")),
+		Append:   true,
+	}
+	if err := req.Save(save, branched); err != nil {
+		t.Errorf("error saving to branch: %s", err.Error())
+		return
+	}
+	if err := req.Revert(&RevertParams{Ref: repo.DatasetRef{Peername: "peer", Name: "cities", Path: v2.Path}, Branch: "fix", Title: "undo paris"}, res); err != nil {
+		t.Errorf("error reverting branch: %s", err.Error())
+		return
+	}
+	if res.Branch != "fix" || res.Dataset.PreviousPath != branched.Path {
+		t.Errorf("expected revert to extend the branch. got branch: %s, previous path: %s", res.Branch, res.Dataset.PreviousPath)
+	}
+	if res.Dataset.Commit.Title != "undo paris" {
+		t.Errorf("commit title mismatch. expected: undo paris, got: %s", res.Dataset.Commit.Title)
+	}
+	if head, err = mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "cities"}); err != nil || head.Path == res.Path {
+		t.Errorf("expected reverting a branch to leave the dataset's reference alone. got: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultS3Endpoint is the object store s3 urls read from if they don't
+	// set an endpoint
+	defaultS3Endpoint = "https://s3.amazonaws.com"
+	// defaultS3Region is the region requests are signed for if s3 urls
+	// don't set one
+	defaultS3Region = "us-east-1"
+	// emptyPayloadHash is the sha256 hash of an empty request body
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// s3Source reads objects from s3-compatible object stores. urls name a
+// bucket & key, eg: s3://bucket/path/to/data.csv. endpoint & region params
+// point to stores other than AWS, like a local minio server:
+// s3://bucket/data.csv?endpoint=http://localhost:9000. Credentials are an
+// access key id & secret, given as the username & password of a source auth.
+// Requests without credentials are anonymous
+type s3Source struct{}
+
+// Host implements the HostSource interface, requests go to the endpoint
+func (s3Source) Host(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("error parsing url: %s", err.Error())
+	}
+	ep, err := s3Endpoint(u)
+	if err != nil {
+		return "", err
+	}
+	return ep.Host, nil
+}
+
+// Open implements the Source interface
+func (s3Source) Open(req *SourceRequest) (*SourceData, error) {
+	u, err := url.Parse(req.URI)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing url: %s", err.Error())
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || key == "" {
+		return nil, fmt.Errorf("s3 urls need a bucket & key, eg: s3://bucket/data.csv")
+	}
+
+	region := u.Query().Get("region")
+	if region == "" {
+		region = defaultS3Region
+	}
+	ep, err := s3Endpoint(u)
+	if err != nil {
+		return nil, err
+	}
+
+	// objects are addressed by path, which works for any bucket name &
+	// s3-compatible store
+	objectPath := strings.TrimSuffix(ep.Path, "/") + "/" + bucket + "/" + key
+	r, err := http.NewRequest("GET", ep.Scheme+"://"+ep.Host, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching url: %s", err.Error())
+	}
+	r.URL.Path, r.URL.RawPath = objectPath, s3Escape(objectPath, false)
+
+	setConditional(r, req.Prev)
+	if a := req.Auth; a != nil {
+		if a.Token != "" || a.Username == "" {
+			return nil, fmt.Errorf("s3 credentials need a username & password, set to an access key id & secret")
+		}
+		for k, v := range a.Headers {
+			r.Header.Set(k, v)
+		}
+		signS3(r, a.Username, a.Password, region, time.Now())
+	}
+	return doHTTP(req, r)
+}
+
+// s3Endpoint reads the object store an s3 url points to
+func s3Endpoint(u *url.URL) (*url.URL, error) {
+	endpoint := u.Query().Get("endpoint")
+	if endpoint == "" {
+		endpoint = defaultS3Endpoint
+	} else if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	ep, err := url.Parse(endpoint)
+	if err != nil || ep.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %s", endpoint)
+	}
+	return ep, nil
+}
+
+// signS3 signs a request with AWS signature version 4, covering the host &
+// every header set on the request. requests must not have a body
+func signS3(r *http.Request, keyID, secret, region string, now time.Time) {
+	now = now.UTC()
+	date, stamp := now.Format("20060102"), now.Format("20060102T150405Z")
+	r.Header.Set("X-Amz-Date", stamp)
+	r.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	names := []string{"host"}
+	for name, vals := range r.Header {
+		name = strings.ToLower(name)
+		headers[name] = strings.TrimSpace(strings.Join(vals, ","))
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := r.URL.Query()
+	params := make([]string, 0, len(query))
+	for k, vals := range query {
+		for _, v := range vals {
+			params = append(params, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	sort.Strings(params)
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		strings.Join(params, "&"),
+		canonicalHeaders,
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + secret)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	r.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", keyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape percent-encodes everything but unreserved characters, the way
+// AWS signatures expect. slashes are kept unless escapeSlash is set
+func s3Escape(s string, escapeSlash bool) string {
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !escapeSlash {
+			buf = append(buf, c)
+			continue
+		}
+		buf = append(buf, fmt.Sprintf("%%%02X", c)...)
+	}
+	return string(buf)
+}
@@ -46,7 +46,7 @@ func (d *SearchRequests) Search(p *repo.SearchParams, res *[]repo.DatasetRef) er
 	// 		return err
 	// 	}
 
-	if searchable, ok := d.repo.(repo.Searchable); ok {
+	if searchable, ok := repo.AsSearchable(d.repo); ok {
 		results, err := searchable.Search(*p)
 		if err != nil {
 			log.Debug(err.Error())
@@ -71,7 +71,7 @@ func (d *SearchRequests) Reindex(p *ReindexSearchParams, done *bool) error {
 		return d.cli.Call("SearchRequests.Reindex", p, done)
 	}
 
-	if fsr, ok := d.repo.(*fsrepo.Repo); ok {
+	if fsr, ok := repo.Unwrap(d.repo).(*fsrepo.Repo); ok {
 		err := fsr.UpdateSearchIndex(d.repo.Store())
 		if err != nil {
 			log.Debug(err.Error())
@@ -0,0 +1,323 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qri-io/qri/config"
+	"github.com/qri-io/qri/repo"
+)
+
+// Source reads data from urls with the scheme it's registered for, like
+// "https" or "s3". Datasets record the url they're read from as their
+// download path, so later saves & updates read from the same source.
+// Sources must be safe for concurrent use
+type Source interface {
+	// Open reads the data at req.URI. Errors with a Temporary method that
+	// returns true are retried. Callers close the body of the result
+	Open(req *SourceRequest) (*SourceData, error)
+}
+
+// LocalSource is an opt-in interface for sources that read from the machine
+// qri is running on. Local sources can't be used over the api, so remote
+// callers can't read local files
+type LocalSource interface {
+	Local() bool
+}
+
+// HostSource is an opt-in interface for sources that send requests to a
+// host other than the host of their urls. Credentials are only sent to
+// the hosts they're configured for
+type HostSource interface {
+	Host(uri string) (string, error)
+}
+
+// SourceRequest asks a source for data
+type SourceRequest struct {
+	URI string
+	// Auth is nil if no credentials are configured for URI
+	Auth *config.SourceAuth
+	// Prev holds validators from the last read of URI, if any. Sources that
+	// can tell data hasn't changed since then respond with NotModified set
+	Prev *repo.SourceValidators
+	// Timeout is how long reading data can take, including reading the body
+	Timeout time.Duration
+}
+
+// SourceData is data read from a source
+type SourceData struct {
+	// Body is nil if NotModified is set
+	Body io.ReadCloser
+	// Filename is used to detect the format of data
+	Filename string
+	// Size is the length of Body in bytes, or -1 if it isn't known
+	Size int64
+	// NotModified is true if a conditional request found data hasn't
+	// changed
+	NotModified bool
+	// Validators are used to make conditional requests for data later on.
+	// the url & credentials name are filled in by qri
+	Validators repo.SourceValidators
+}
+
+var (
+	sourcesLock sync.Mutex
+	sources     = map[string]Source{}
+)
+
+// RegisterSource makes a source available for urls of a scheme. It panics
+// if called twice with the same scheme
+func RegisterSource(scheme string, s Source) {
+	sourcesLock.Lock()
+	defer sourcesLock.Unlock()
+
+	scheme = strings.ToLower(scheme)
+	if s == nil {
+		panic(fmt.Errorf("core: source %s is nil", scheme))
+	}
+	if _, exists := sources[scheme]; exists {
+		panic(fmt.Errorf("core: source %s is already registered", scheme))
+	}
+	sources[scheme] = s
+}
+
+// SourceSchemes lists the url schemes of all registered sources, sorted
+func SourceSchemes() []string {
+	sourcesLock.Lock()
+	defer sourcesLock.Unlock()
+
+	schemes := make([]string, 0, len(sources))
+	for scheme := range sources {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// IsLocalSource reports whether uri is read by a LocalSource
+func IsLocalSource(uri string) bool {
+	s, err := sourceFor(uri)
+	if err != nil {
+		return false
+	}
+	local, ok := s.(LocalSource)
+	return ok && local.Local()
+}
+
+// ErrLocalSource is returned when requests made on behalf of other machines
+// ask to read from a local source
+var ErrLocalSource = fmt.Errorf("local sources like file:// urls can only be read by local requests")
+
+// checkRemoteSource refuses local sources for remote requests
+func checkRemoteSource(remote bool, uri string) error {
+	if remote && IsLocalSource(uri) {
+		return ErrLocalSource
+	}
+	return nil
+}
+
+// sourceHost gives the host a source sends requests for uri to
+func sourceHost(src Source, uri string) (string, error) {
+	if hs, ok := src.(HostSource); ok {
+		return hs.Host(uri)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("error parsing url: %s", err.Error())
+	}
+	return u.Host, nil
+}
+
+// sourceFor gets the source registered for the scheme of uri
+func sourceFor(uri string) (Source, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing url: %s", err.Error())
+	}
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("url %s has no scheme. use file:// for local files", uri)
+	}
+
+	sourcesLock.Lock()
+	defer sourcesLock.Unlock()
+	s, ok := sources[strings.ToLower(u.Scheme)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported url scheme: %s", u.Scheme)
+	}
+	return s, nil
+}
+
+// temporaryError marks a failure that may not last, like a timeout or 503
+// response
+type temporaryError struct {
+	error
+}
+
+// Temporary implements the interface checked by temporary
+func (temporaryError) Temporary() bool { return true }
+
+// temporary reports whether an error may not last
+func temporary(err error) bool {
+	t, ok := err.(interface {
+		Temporary() bool
+	})
+	return ok && t.Temporary()
+}
+
+func init() {
+	RegisterSource("http", httpSource{})
+	RegisterSource("https", httpSource{})
+	RegisterSource("file", fileSource{})
+	RegisterSource("s3", s3Source{})
+	RegisterSource("sqlite", sqliteSource{})
+}
+
+// httpSource reads http & https urls. Credentials are sent as a bearer
+// token or basic auth
+type httpSource struct{}
+
+// Open implements the Source interface
+func (httpSource) Open(req *SourceRequest) (*SourceData, error) {
+	r, err := http.NewRequest("GET", req.URI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching url: %s", err.Error())
+	}
+	if a := req.Auth; a != nil {
+		for key, val := range a.Headers {
+			r.Header.Set(key, val)
+		}
+		if a.Token != "" {
+			r.Header.Set("Authorization", "Bearer "+a.Token)
+		} else if a.Username != "" {
+			r.SetBasicAuth(a.Username, a.Password)
+		}
+	}
+	setConditional(r, req.Prev)
+	return doHTTP(req, r)
+}
+
+// setConditional asks for a response only if data has changed since prev
+func setConditional(r *http.Request, prev *repo.SourceValidators) {
+	if prev == nil {
+		return
+	}
+	if prev.ETag != "" {
+		r.Header.Set("If-None-Match", prev.ETag)
+	}
+	if prev.LastModified != "" {
+		r.Header.Set("If-Modified-Since", prev.LastModified)
+	}
+}
+
+// doHTTP makes a request for a source, marking failures worth retrying
+// as temporary
+func doHTTP(req *SourceRequest, r *http.Request) (*SourceData, error) {
+	cli := &http.Client{Timeout: req.Timeout}
+	var refused error
+	if req.Auth != nil {
+		// credentials can't follow redirects to hosts they aren't for
+		cli.CheckRedirect = func(next *http.Request, via []*http.Request) error {
+			if next.URL.Host != via[0].URL.Host {
+				refused = fmt.Errorf("error fetching url: %s redirected to %s, which credentials aren't sent to", req.URI, next.URL.Host)
+				return refused
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return nil
+		}
+	}
+	res, err := cli.Do(r)
+	if refused != nil {
+		return nil, refused
+	}
+	if err != nil {
+		// connection failures & timeouts may not last
+		return nil, temporaryError{fmt.Errorf("error fetching url: %s", err.Error())}
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && req.Prev != nil:
+		res.Body.Close()
+		return &SourceData{NotModified: true, Validators: *req.Prev}, nil
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		res.Body.Close()
+		return nil, temporaryError{fmt.Errorf("error fetching url: %s responded %s", req.URI, res.Status)}
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		res.Body.Close()
+		return nil, fmt.Errorf("error fetching url: %s responded %s", req.URI, res.Status)
+	}
+
+	return &SourceData{
+		Body:     res.Body,
+		Filename: path.Base(r.URL.Path),
+		Size:     res.ContentLength,
+		Validators: repo.SourceValidators{
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+		},
+	}, nil
+}
+
+// fileSource reads local files, eg: file:///path/to/data.csv. Files are
+// checked for changes by modification time
+type fileSource struct{}
+
+// Local implements the LocalSource interface
+func (fileSource) Local() bool { return true }
+
+// Open implements the Source interface
+func (fileSource) Open(req *SourceRequest) (*SourceData, error) {
+	fpath, err := localPath(req)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(fpath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %s", err.Error())
+	}
+	if fi.IsDir() {
+		return nil, fmt.Errorf("error reading file: %s is a directory", fpath)
+	}
+
+	modified := fi.ModTime().UTC().Format(time.RFC3339Nano)
+	if req.Prev != nil && req.Prev.LastModified == modified {
+		return &SourceData{NotModified: true, Validators: *req.Prev}, nil
+	}
+
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %s", err.Error())
+	}
+	return &SourceData{
+		Body:       f,
+		Filename:   fi.Name(),
+		Size:       fi.Size(),
+		Validators: repo.SourceValidators{LastModified: modified},
+	}, nil
+}
+
+// localPath reads the absolute path of a local file from a request for a
+// local source. Local sources don't take credentials
+func localPath(req *SourceRequest) (string, error) {
+	u, err := url.Parse(req.URI)
+	if err != nil {
+		return "", fmt.Errorf("error parsing url: %s", err.Error())
+	}
+	if u.Host != "" && u.Host != "localhost" || !filepath.IsAbs(u.Path) {
+		return "", fmt.Errorf("%s urls need an absolute path, eg: %s:///path/to/file", u.Scheme, u.Scheme)
+	}
+	if req.Auth != nil {
+		return "", fmt.Errorf("%s urls don't take credentials", u.Scheme)
+	}
+	return u.Path, nil
+}
@@ -0,0 +1,268 @@
+package core
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qri-io/qri/config"
+	"github.com/qri-io/qri/repo"
+	"github.com/qri-io/qri/repo/profile"
+	testrepo "github.com/qri-io/qri/repo/test"
+)
+
+func TestRegisterSource(t *testing.T) {
+	schemes := strings.Join(SourceSchemes(), ",")
+	if schemes != "file,http,https,s3,sqlite" {
+		t.Errorf("registered schemes mismatch. got: %s", schemes)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected registering a scheme twice to panic")
+		}
+	}()
+	RegisterSource("HTTP", httpSource{})
+}
+
+func TestIsLocalSource(t *testing.T) {
+	cases := []struct {
+		uri   string
+		local bool
+	}{
+		{"file:///data.csv", true},
+		{"sqlite:///birds.db?table=birds", true},
+		{"https://example.com/data.csv", false},
+		{"s3://bucket/data.csv", false},
+		{"data.csv", false},
+	}
+	for i, c := range cases {
+		if got := IsLocalSource(c.uri); got != c.local {
+			t.Errorf("case %d: expected %t, got: %t", i, c.local, got)
+		}
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qri_test_file_source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "birds.csv")
+	if err := ioutil.WriteFile(path, []byte("species,count\nblue jay,4\n"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	f, _ := newFetcher("")
+	src, err := f.fetch("file://"+path, nil)
+	if err != nil {
+		t.Errorf("error fetching file: %s", err.Error())
+		return
+	}
+	data, err := ioutil.ReadAll(src.Body)
+	src.Body.Close()
+	if err != nil {
+		t.Errorf("error reading file: %s", err.Error())
+		return
+	}
+	if string(data) != "species,count\nblue jay,4\n" {
+		t.Errorf("data mismatch. got: %s", string(data))
+	}
+	if src.Filename != "birds.csv" {
+		t.Errorf("filename mismatch. expected: birds.csv, got: %s", src.Filename)
+	}
+
+	prev := src.Validators
+	if src, err = f.fetch("file://"+path, &prev); err != nil {
+		t.Errorf("error fetching file: %s", err.Error())
+		return
+	}
+	if !src.NotModified {
+		t.Errorf("expected unchanged file to be reported as not modified")
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if src, err = f.fetch("file://"+path, &prev); err != nil {
+		t.Errorf("error fetching file: %s", err.Error())
+		return
+	}
+	src.Body.Close()
+	if src.NotModified {
+		t.Errorf("expected changed file to be fetched")
+	}
+
+	bad := []string{
+		"file://birds.csv",
+		"file://" + filepath.Join(dir, "missing.csv"),
+		"file://" + dir,
+	}
+	for _, uri := range bad {
+		if _, err := f.fetch(uri, nil); err == nil {
+			t.Errorf("expected fetching %s to error", uri)
+		}
+	}
+
+	f.auth = &config.SourceAuth{Token: "secret"}
+	if _, err := f.fetch("file://"+path, nil); err == nil {
+		t.Errorf("expected fetching a file with credentials to error")
+	}
+}
+
+func TestSqliteSource(t *testing.T) {
+	if _, err := exec.LookPath(sqliteCommand); err != nil {
+		t.Skipf("%s isn't installed", sqliteCommand)
+	}
+
+	dir, err := ioutil.TempDir("", "qri_test_sqlite_source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db := filepath.Join(dir, "birds.db")
+	setup := exec.Command(sqliteCommand, db, `CREATE TABLE birds (species TEXT, count INTEGER); INSERT INTO birds VALUES ('blue jay', 4), ('robin', 2); CREATE TABLE "odd ""name""" (a INTEGER); INSERT INTO "odd ""name""" VALUES (1);`)
+	if out, err := setup.CombinedOutput(); err != nil {
+		t.Fatalf("error creating database: %s %s", err.Error(), string(out))
+	}
+
+	cases := []struct {
+		uri, filename, data string
+	}{
+		{"sqlite://" + db + "?table=birds", "birds.csv", "species,count\n\"blue jay\",4\nrobin,2\n"},
+		{"sqlite://" + db + "?table=" + url.QueryEscape(`odd "name"`), `odd "name".csv`, "a\n1\n"},
+	}
+
+	f, _ := newFetcher("")
+	for i, c := range cases {
+		src, err := f.fetch(c.uri, nil)
+		if err != nil {
+			t.Errorf("case %d unexpected error: %s", i, err.Error())
+			continue
+		}
+		data, err := ioutil.ReadAll(src.Body)
+		src.Body.Close()
+		if err != nil {
+			t.Errorf("case %d error reading results: %s", i, err.Error())
+			continue
+		}
+		if strings.Replace(string(data), "\r\n", "\n", -1) != c.data {
+			t.Errorf("case %d data mismatch. expected: %q, got: %q", i, c.data, string(data))
+		}
+		if src.Filename != c.filename {
+			t.Errorf("case %d filename mismatch. expected: %s, got: %s", i, c.filename, src.Filename)
+		}
+	}
+
+	bad := []struct {
+		uri, err string
+	}{
+		{"sqlite://" + db, "need a table"},
+		{"sqlite://" + db + "?query=SELECT 1", "queries aren't supported"},
+		{"sqlite://" + db + "?table=birds&query=DELETE FROM birds", "queries aren't supported"},
+		{"sqlite://" + db + "?table=nope", "no such table"},
+		{"sqlite://" + filepath.Join(dir, "missing.db") + "?table=birds", "error reading sqlite database"},
+	}
+	for i, c := range bad {
+		if _, err := f.fetch(c.uri, nil); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("case %d expected error containing %q. got: %v", i, c.err, err)
+		}
+	}
+}
+
+func TestUpdateFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qri_test_update_from_file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "birds.csv")
+	if err := ioutil.WriteFile(path, []byte("species,count\nblue jay,4\n"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	created := &repo.DatasetRef{}
+	if err := NewDatasetRequests(mr, nil).Init(&InitParams{Peername: "peer", Name: "birds", URL: "file://" + path}, created); err != nil {
+		t.Errorf("error creating dataset: %s", err.Error())
+		return
+	}
+	if created.Dataset.Meta.DownloadPath != "file://"+path {
+		t.Errorf("download path mismatch. expected: file://%s, got: %s", path, created.Dataset.Meta.DownloadPath)
+	}
+
+	req := NewUpdateRequests(mr, nil)
+	birds := []repo.DatasetRef{{Peername: "peer", Name: "birds"}}
+	res := []UpdateResult{}
+	if err := req.Run(&UpdateRunParams{Refs: birds}, &res); err != nil {
+		t.Errorf("error running update: %s", err.Error())
+		return
+	}
+	if len(res) != 1 || res[0].Status != UpdateUnchanged {
+		t.Errorf("expected unchanged file not to update. got: %v", res)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("species,count\nblue jay,5\n"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := req.Run(&UpdateRunParams{Refs: birds}, &res); err != nil {
+		t.Errorf("error running update: %s", err.Error())
+		return
+	}
+	if len(res) != 1 || res[0].Status != UpdateUpdated {
+		t.Errorf("expected changed file to update. got: %v", res)
+		return
+	}
+
+	// local download paths this machine didn't add data from aren't read
+	other := repo.DatasetRef{Peername: "other", ProfileID: profile.IDB58MustDecode("QmZePf5LeXow3RW5U1AgEiNbW46YnRGhZ7HPvm1UmPFPwt"), Name: "birds", Path: res[0].Ref.Path}
+	if err := mr.PutRef(other); err != nil {
+		t.Errorf("error putting ref: %s", err.Error())
+		return
+	}
+	if err := NewDatasetRequests(mr, nil).Save(&SaveParams{Peername: "other", Name: "birds", URL: "file://" + path}, &repo.DatasetRef{}); err == nil || !strings.Contains(err.Error(), "wasn't added from") {
+		t.Errorf("expected refreshing another peer's local download path to be refused. got: %v", err)
+	}
+
+	meta := strings.NewReader(`{"downloadPath": "file://` + path + `"}`)
+	if err := NewDatasetRequests(mr, nil).Init(&InitParams{Peername: "peer", Name: "counts", DataFilename: "counts.csv", Data: strings.NewReader("species,count\nrobin,2\n"), Metadata: meta}, &repo.DatasetRef{}); err != nil {
+		t.Errorf("error creating dataset: %s", err.Error())
+		return
+	}
+	counts := []repo.DatasetRef{{Peername: "peer", Name: "counts"}}
+	if err := req.Run(&UpdateRunParams{Refs: counts}, &res); err != nil {
+		t.Errorf("error running update: %s", err.Error())
+		return
+	}
+	if len(res) != 1 || res[0].Status != UpdateFailed || !strings.Contains(res[0].Error, "wasn't added from") {
+		t.Errorf("expected updating from a local download path set in metadata to fail. got: %v", res)
+	}
+
+	// requests made on behalf of other machines can't read local sources
+	remote := NewDatasetRequests(mr, nil)
+	remote.Remote = true
+	if err := remote.Init(&InitParams{Peername: "peer", Name: "remote_birds", URL: "file://" + path}, &repo.DatasetRef{}); err != ErrLocalSource {
+		t.Errorf("expected remote init from a local source to be refused. got: %v", err)
+	}
+	if err := remote.Save(&SaveParams{Peername: "peer", Name: "birds", URL: "file://" + path}, &repo.DatasetRef{}); err != ErrLocalSource {
+		t.Errorf("expected remote save from a local source to be refused. got: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// sqliteCommand is the sqlite command line shell that sqlite sources read
+// tables with
+var sqliteCommand = "sqlite3"
+
+// sqliteSource reads whole tables of local sqlite databases as csv, eg:
+// sqlite:///path/to/db.sqlite?table=birds. Tables are read-only with the
+// sqlite3 command line shell, which must be installed. Arbitrary queries
+// aren't supported, sql can do more than read a table
+type sqliteSource struct{}
+
+// Local implements the LocalSource interface
+func (sqliteSource) Local() bool { return true }
+
+// Open implements the Source interface
+func (sqliteSource) Open(req *SourceRequest) (*SourceData, error) {
+	dbpath, err := localPath(req)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(req.URI)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing url: %s", err.Error())
+	}
+
+	if u.Query().Get("query") != "" {
+		return nil, fmt.Errorf("sqlite urls read whole tables, queries aren't supported")
+	}
+	table := u.Query().Get("table")
+	if table == "" {
+		return nil, fmt.Errorf("sqlite urls need a table, eg: sqlite:///path/to/db.sqlite?table=birds")
+	}
+	query := fmt.Sprintf(`SELECT * FROM "%s"`, strings.Replace(table, `"`, `""`, -1))
+	if _, err := os.Stat(dbpath); err != nil {
+		return nil, fmt.Errorf("error reading sqlite database: %s", err.Error())
+	}
+
+	// results are written to a temp file, so query errors are reported
+	// before any data is read
+	out, err := ioutil.TempFile("", "qri_sqlite")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temp file: %s", err.Error())
+	}
+	ctx, cancel := context.Background(), func() {}
+	if req.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+	}
+	defer cancel()
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, sqliteCommand, "-readonly", "-csv", "-header", dbpath, query)
+	cmd.Stdout, cmd.Stderr = out, stderr
+	if err := cmd.Run(); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		if e, ok := err.(*exec.Error); ok && e.Err == exec.ErrNotFound {
+			return nil, fmt.Errorf("error running sqlite query: sqlite sources need the %s command line shell installed", sqliteCommand)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("error running sqlite query: %s", msg)
+		}
+		return nil, fmt.Errorf("error running sqlite query: %s", err.Error())
+	}
+
+	size, err := out.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = out.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return nil, fmt.Errorf("error reading query results: %s", err.Error())
+	}
+
+	return &SourceData{
+		Body:     tempFile{out},
+		Filename: table + ".csv",
+		Size:     size,
+	}, nil
+}
+
+// tempFile is a file that's removed once it's closed
+type tempFile struct {
+	*os.File
+}
+
+// Close closes & removes the file
+func (f tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/dataset/dsfs"
+	"github.com/qri-io/qri/repo"
+)
+
+// TagRequests encapsulates business logic for naming versions of datasets
+type TagRequests struct {
+	repo repo.Repo
+	cli  *rpc.Client
+}
+
+// CoreRequestsName implements the Requests interface
+func (TagRequests) CoreRequestsName() string { return "tags" }
+
+// NewTagRequests creates a TagRequests pointer from either a repo or an
+// rpc.Client
+func NewTagRequests(r repo.Repo, cli *rpc.Client) *TagRequests {
+	if r != nil && cli != nil {
+		panic(fmt.Errorf("both repo and client supplied to NewTagRequests"))
+	}
+	return &TagRequests{
+		repo: r,
+		cli:  cli,
+	}
+}
+
+// TagParams defines parameters for the Tag method
+type TagParams struct {
+	// Ref is the dataset version to tag. If Ref.Path is empty the latest
+	// version is tagged
+	Ref repo.DatasetRef
+	// Tag is the name to give the version
+	Tag string
+}
+
+// Tag names a version of one of this repo's datasets. Tagging an existing
+// tag moves it to the new version
+func (r *TagRequests) Tag(p *TagParams, res *repo.DatasetRef) error {
+	if r.cli != nil {
+		return r.cli.Call("TagRequests.Tag", p, res)
+	}
+
+	if err := repo.ValidateTag(p.Tag); err != nil {
+		return err
+	}
+	if p.Tag == repo.DefaultBranch {
+		return fmt.Errorf("'%s' is the default branch", repo.DefaultBranch)
+	}
+
+	ref := p.Ref
+	if err := repo.CanonicalizeDatasetRef(r.repo, &ref); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error canonicalizing reference: %s", err.Error())
+	}
+
+	pro, err := r.repo.Profile()
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error getting profile: %s", err.Error())
+	}
+	if ref.ProfileID != pro.ID {
+		return fmt.Errorf("can only tag your own datasets")
+	}
+
+	head, err := r.repo.GetRef(repo.DatasetRef{Peername: ref.Peername, ProfileID: ref.ProfileID, Name: ref.Name})
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error getting dataset '%s': %s", ref.AliasString(), err.Error())
+	}
+	if ref.Path == "" {
+		ref.Path = head.Path
+	} else if err := inHistory(r.repo.Store(), head.Path, ref.Path); err != nil {
+		return err
+	}
+	if _, err := r.repo.Branches().GetBranch(repo.DatasetRef{Peername: ref.Peername, Name: ref.Name, Branch: p.Tag}); err == nil {
+		return fmt.Errorf("'%s' is already a branch of %s", p.Tag, ref.AliasString())
+	}
+
+	ref.Tag = p.Tag
+	if err := r.repo.Tags().PutTag(ref); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error saving tag: %s", err.Error())
+	}
+
+	*res = ref
+	return nil
+}
+
+// inHistory checks path is a version in the history ending at head
+func inHistory(store cafs.Filestore, head, path string) error {
+	for p := head; p != ""; {
+		if p == path {
+			return nil
+		}
+		ds, err := dsfs.LoadDataset(store, datastore.NewKey(p))
+		if err != nil {
+			log.Debug(err.Error())
+			return fmt.Errorf("error loading dataset version '%s': %s", p, err.Error())
+		}
+		p = ds.PreviousPath
+	}
+	return fmt.Errorf("version %s isn't in this dataset's history", path)
+}
+
+// Untag removes a tag. ref must name a tag, eg: me/census@v2018
+func (r *TagRequests) Untag(ref *repo.DatasetRef, ok *bool) error {
+	if r.cli != nil {
+		return r.cli.Call("TagRequests.Untag", ref, ok)
+	}
+
+	if ref.Tag == "" {
+		return fmt.Errorf("a tag is required, eg: me/dataset_name@tag")
+	}
+	if err := repo.CanonicalizeDatasetRef(r.repo, ref); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error canonicalizing reference: %s", err.Error())
+	}
+
+	if err := r.repo.Tags().DeleteTag(*ref); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error removing tag: %s", err.Error())
+	}
+
+	*ok = true
+	return nil
+}
+
+// List lists tags for a dataset, or all known tags if ref has no name
+func (r *TagRequests) List(ref *repo.DatasetRef, res *[]repo.DatasetRef) error {
+	if r.cli != nil {
+		return r.cli.Call("TagRequests.List", ref, res)
+	}
+
+	if err := repo.CanonicalizeProfile(r.repo, ref); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error canonicalizing reference: %s", err.Error())
+	}
+
+	tags, err := r.repo.Tags().ListTags(*ref)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error listing tags: %s", err.Error())
+	}
+
+	*res = tags
+	return nil
+}
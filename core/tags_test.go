@@ -0,0 +1,90 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/qri-io/qri/repo"
+	testrepo "github.com/qri-io/qri/repo/test"
+)
+
+func TestTagRequests(t *testing.T) {
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	req := NewTagRequests(mr, nil)
+
+	movies, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "movies"})
+	if err != nil {
+		t.Errorf("error getting ref: %s", err.Error())
+		return
+	}
+	cities, err := mr.GetRef(repo.DatasetRef{Peername: "peer", Name: "cities"})
+	if err != nil {
+		t.Errorf("error getting ref: %s", err.Error())
+		return
+	}
+
+	bad := []*TagParams{
+		{Ref: repo.DatasetRef{Peername: "me", Name: "movies"}, Tag: ""},
+		{Ref: repo.DatasetRef{Peername: "me", Name: "movies"}, Tag: "no spaces"},
+		{Ref: repo.DatasetRef{Peername: "me", Name: "not_a_dataset"}, Tag: "v1"},
+		{Ref: repo.DatasetRef{Peername: "me", Name: "movies", Path: cities.Path}, Tag: "v1"},
+	}
+	for i, p := range bad {
+		if err := req.Tag(p, &repo.DatasetRef{}); err == nil {
+			t.Errorf("case %d: expected tagging to error", i)
+		}
+	}
+
+	tag := &repo.DatasetRef{}
+	if err := req.Tag(&TagParams{Ref: repo.DatasetRef{Peername: "me", Name: "movies"}, Tag: "v1"}, tag); err != nil {
+		t.Errorf("error tagging dataset: %s", err.Error())
+		return
+	}
+	if tag.Path != movies.Path || tag.Tag != "v1" {
+		t.Errorf("expected tag v1 of movies at %s. got: %s@%s", movies.Path, tag.Path, tag.Tag)
+	}
+
+	ref, err := repo.ParseDatasetRef("me/movies@v1")
+	if err != nil {
+		t.Errorf("error parsing tag reference: %s", err.Error())
+		return
+	}
+	if err := repo.CanonicalizeDatasetRef(mr, &ref); err != nil {
+		t.Errorf("error canonicalizing tag reference: %s", err.Error())
+		return
+	}
+	if ref.Path != movies.Path {
+		t.Errorf("expected tag to resolve to %s. got: %s", movies.Path, ref.Path)
+	}
+
+	dsr := NewDatasetRequests(mr, nil)
+	if err := dsr.Rename(&RenameParams{Current: repo.DatasetRef{Peername: "me", Name: "movies"}, New: repo.DatasetRef{Peername: "me", Name: "films"}}, &repo.DatasetRef{}); err != nil {
+		t.Errorf("error renaming dataset: %s", err.Error())
+		return
+	}
+
+	tags := []repo.DatasetRef{}
+	if err := req.List(&repo.DatasetRef{Peername: "me", Name: "films"}, &tags); err != nil {
+		t.Errorf("error listing tags: %s", err.Error())
+		return
+	}
+	if len(tags) != 1 || tags[0].Tag != "v1" {
+		t.Errorf("expected tags to follow renamed dataset. got: %v", tags)
+	}
+
+	ok := false
+	if err := req.Untag(&repo.DatasetRef{Peername: "me", Name: "films", Tag: "v1"}, &ok); err != nil {
+		t.Errorf("error removing tag: %s", err.Error())
+		return
+	}
+	if err := req.List(&repo.DatasetRef{}, &tags); err != nil {
+		t.Errorf("error listing tags: %s", err.Error())
+		return
+	}
+	if len(tags) != 0 {
+		t.Errorf("expected no tags after untagging. got: %v", tags)
+	}
+}
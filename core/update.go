@@ -0,0 +1,356 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/rpc"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/qri-io/qri/repo"
+)
+
+// defaultAccrualPeriodicity is the update schedule given to datasets
+// created from a url, once a week
+const defaultAccrualPeriodicity = "R/P1W"
+
+// updateCheckInterval is how often an UpdateScheduler looks for datasets
+// that are due to update
+var updateCheckInterval = 10 * time.Minute
+
+// updateLock keeps updates from running at the same time, so scheduled &
+// requested updates of a dataset can't race
+var updateLock sync.Mutex
+
+// UpdateRequests encapsulates business logic for re-fetching datasets
+// from the urls they were created from
+type UpdateRequests struct {
+	repo repo.Repo
+	cli  *rpc.Client
+}
+
+// CoreRequestsName implements the Requests interface
+func (UpdateRequests) CoreRequestsName() string { return "updates" }
+
+// NewUpdateRequests creates an UpdateRequests pointer from either a repo
+// or an rpc.Client
+func NewUpdateRequests(r repo.Repo, cli *rpc.Client) *UpdateRequests {
+	if r != nil && cli != nil {
+		panic(fmt.Errorf("both repo and client supplied to NewUpdateRequests"))
+	}
+	return &UpdateRequests{
+		repo: r,
+		cli:  cli,
+	}
+}
+
+// UpdateJob is the update schedule of a dataset, read from the
+// DownloadPath & AccrualPeriodicity of its metadata
+type UpdateJob struct {
+	Ref repo.DatasetRef `json:"ref"`
+	// URL is the source data is fetched from
+	URL string `json:"url"`
+	// Periodicity is the ISO 8601 repeating interval of updates. Jobs without
+	// a periodicity only update when asked to
+	Periodicity string `json:"periodicity,omitempty"`
+	// LastChecked is the latest of the dataset's last commit & update
+	LastChecked time.Time `json:"lastChecked"`
+	// NextCheck is zero if no more updates are scheduled
+	NextCheck time.Time `json:"nextCheck,omitempty"`
+	Due       bool      `json:"due"`
+	// Error describes a periodicity that can't be read
+	Error string `json:"error,omitempty"`
+}
+
+// UpdateStatus is the outcome of an update
+type UpdateStatus string
+
+const (
+	// UpdateUpdated means a new version was saved
+	UpdateUpdated = UpdateStatus("updated")
+	// UpdateUnchanged means source data matched the latest version
+	UpdateUnchanged = UpdateStatus("unchanged")
+	// UpdateFailed means source data couldn't be fetched or saved
+	UpdateFailed = UpdateStatus("failed")
+)
+
+// UpdateResult is the outcome of updating a single dataset
+type UpdateResult struct {
+	// Ref is the new version if the dataset updated, otherwise the version
+	// that was checked
+	Ref    repo.DatasetRef `json:"ref"`
+	Status UpdateStatus    `json:"status"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// List gives the update schedule of every dataset created from a url. If
+// dueOnly is true, only datasets that are due to update are listed
+func (r *UpdateRequests) List(dueOnly *bool, res *[]UpdateJob) error {
+	if r.cli != nil {
+		return r.cli.Call("UpdateRequests.List", dueOnly, res)
+	}
+
+	jobs, err := r.jobs(time.Now())
+	if err != nil {
+		return err
+	}
+	if dueOnly != nil && *dueOnly {
+		due := []UpdateJob{}
+		for _, job := range jobs {
+			if job.Due {
+				due = append(due, job)
+			}
+		}
+		jobs = due
+	}
+	*res = jobs
+	return nil
+}
+
+// UpdateRunParams defines parameters for the Run method
+type UpdateRunParams struct {
+	// Refs lists datasets to update now, whether they're due or not. If Refs
+	// is empty every dataset that's due is updated
+	Refs []repo.DatasetRef
+}
+
+// Run re-fetches datasets from their source urls, saving a new version of
+// each dataset whose data has changed. Outcomes are recorded in the event
+// log. Failing to update a dataset doesn't stop others from updating,
+// failures are reported in results
+func (r *UpdateRequests) Run(p *UpdateRunParams, res *[]UpdateResult) error {
+	if r.cli != nil {
+		return r.cli.Call("UpdateRequests.Run", p, res)
+	}
+
+	updateLock.Lock()
+	defer updateLock.Unlock()
+
+	pro, err := r.repo.Profile()
+	if err != nil {
+		return fmt.Errorf("error getting profile: %s", err.Error())
+	}
+	jobs, err := r.jobs(time.Now())
+	if err != nil {
+		return err
+	}
+
+	run := []UpdateJob{}
+	if len(p.Refs) == 0 {
+		for _, job := range jobs {
+			if job.Due {
+				run = append(run, job)
+			}
+		}
+	} else {
+		for _, ref := range p.Refs {
+			if err := repo.CanonicalizeDatasetRef(r.repo, &ref); err != nil {
+				return fmt.Errorf("error canonicalizing reference: %s", err.Error())
+			}
+			if ref.ProfileID != pro.ID {
+				return fmt.Errorf("%s belongs to another peer, only your own datasets can be updated", ref.AliasString())
+			}
+			found := false
+			for _, job := range jobs {
+				if job.Ref.AliasString() == ref.AliasString() {
+					run = append(run, job)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("%s wasn't created from a url, there's nothing to update it from", ref.AliasString())
+			}
+		}
+	}
+
+	results := make([]UpdateResult, len(run))
+	for i, job := range run {
+		results[i] = r.update(job)
+	}
+	*res = results
+	return nil
+}
+
+// update runs a single job, logging the outcome
+func (r *UpdateRequests) update(job UpdateJob) UpdateResult {
+	res := UpdateResult{Ref: job.Ref}
+	ref, err := r.updateDataset(job)
+	switch {
+	case err != nil:
+		log.Infof("error updating %s: %s", job.Ref.AliasString(), err.Error())
+		res.Status = UpdateFailed
+		res.Error = err.Error()
+		if err := r.repo.LogEvent(repo.ETDsUpdateFailed, job.Ref); err != nil {
+			log.Debug(err.Error())
+		}
+	case ref == nil:
+		res.Status = UpdateUnchanged
+		if err := r.repo.LogEvent(repo.ETDsUpdateUnchanged, job.Ref); err != nil {
+			log.Debug(err.Error())
+		}
+	default:
+		res.Status = UpdateUpdated
+		res.Ref = *ref
+		if err := r.repo.LogEvent(repo.ETDsUpdated, *ref); err != nil {
+			log.Debug(err.Error())
+		}
+	}
+	return res
+}
+
+// updateDataset fetches a job's url, saving a new version if data has
+// changed. Updates are saves that build on the version the job was read
+// from, so they fail if the dataset has moved on since. the returned
+// reference is nil if data hasn't changed
+func (r *UpdateRequests) updateDataset(job UpdateJob) (*repo.DatasetRef, error) {
+	ref := &repo.DatasetRef{}
+	p := &SaveParams{
+		Peername:     job.Ref.Peername,
+		Name:         job.Ref.Name,
+		URL:          job.URL,
+		PreviousPath: job.Ref.Path,
+	}
+	err := NewDatasetRequests(r.repo, nil).Save(p, ref)
+	if err == ErrSourceNotModified {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	ref.Dataset = nil
+	return ref, nil
+}
+
+// jobs reads the update schedule of every dataset this repo's profile
+// owns that has a download path, ordered by reference. Datasets added from
+// other peers are theirs to update
+func (r *UpdateRequests) jobs(now time.Time) ([]UpdateJob, error) {
+	pro, err := r.repo.Profile()
+	if err != nil {
+		return nil, fmt.Errorf("error getting profile: %s", err.Error())
+	}
+	history, err := updateHistory(r.repo)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []UpdateJob{}
+	unreadable := 0
+	it := repo.IterateDatasets(context.Background(), r.repo, repo.IterateOptions{LatestOnly: true, LoadComponents: true})
+	for v, ok := it.Next(); ok; v, ok = it.Next() {
+		if v.Err != nil {
+			unreadable++
+			continue
+		}
+		ds := v.Ref.Dataset
+		if v.Ref.ProfileID != pro.ID || ds.Meta == nil || ds.Meta.DownloadPath == "" {
+			continue
+		}
+
+		job := UpdateJob{
+			Ref:         v.Ref,
+			URL:         ds.Meta.DownloadPath,
+			Periodicity: ds.Meta.AccrualPeriodicity,
+		}
+		job.Ref.Dataset = nil
+		if ds.Commit != nil {
+			job.LastChecked = ds.Commit.Timestamp
+		}
+		h := history[job.Ref.AliasString()]
+		if h != nil && h.checked.After(job.LastChecked) {
+			job.LastChecked = h.checked
+		}
+
+		if job.Periodicity != "" {
+			ri, err := ParseRepeatingInterval(job.Periodicity)
+			if err != nil {
+				job.Error = err.Error()
+			} else if ri.Repetitions < 0 || h == nil || h.updates < ri.Repetitions {
+				job.NextCheck = ri.Next(job.LastChecked)
+				job.Due = !now.Before(job.NextCheck)
+			}
+		}
+		jobs = append(jobs, job)
+	}
+	// any errors beyond unreadable datasets mean references couldn't be listed
+	if errs := it.Errors(); len(errs) > unreadable {
+		return nil, errs[0]
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Ref.AliasString() < jobs[j].Ref.AliasString()
+	})
+	return jobs, nil
+}
+
+// updateRecord summarizes past updates of a dataset
+type updateRecord struct {
+	// checked is the time of the latest update, whatever its outcome
+	checked time.Time
+	// updates counts updates that saved a new version
+	updates int
+}
+
+// updateHistory reads past updates from the event log, by dataset alias
+func updateHistory(r repo.Repo) (map[string]*updateRecord, error) {
+	history := map[string]*updateRecord{}
+	for offset := 0; ; offset += 100 {
+		events, err := r.Events(100, offset)
+		if err != nil {
+			return nil, fmt.Errorf("error reading events: %s", err.Error())
+		}
+		for _, e := range events {
+			if e.Type != repo.ETDsUpdated && e.Type != repo.ETDsUpdateUnchanged && e.Type != repo.ETDsUpdateFailed {
+				continue
+			}
+			alias := e.Ref.AliasString()
+			h := history[alias]
+			if h == nil {
+				h = &updateRecord{}
+				history[alias] = h
+			}
+			if e.Time.After(h.checked) {
+				h.checked = e.Time
+			}
+			if e.Type == repo.ETDsUpdated {
+				h.updates++
+			}
+		}
+		if len(events) < 100 {
+			return history, nil
+		}
+	}
+}
+
+// UpdateScheduler updates datasets as they fall due
+type UpdateScheduler struct {
+	requests *UpdateRequests
+}
+
+// NewUpdateScheduler creates an UpdateScheduler for a repo
+func NewUpdateScheduler(r repo.Repo) *UpdateScheduler {
+	return &UpdateScheduler{requests: NewUpdateRequests(r, nil)}
+}
+
+// Start updates datasets that are due, checking again every
+// updateCheckInterval until ctx is cancelled
+func (s *UpdateScheduler) Start(ctx context.Context) {
+	t := time.NewTicker(updateCheckInterval)
+	defer t.Stop()
+	for {
+		results := []UpdateResult{}
+		if err := s.requests.Run(&UpdateRunParams{}, &results); err != nil {
+			log.Infof("error running scheduled updates: %s", err.Error())
+		}
+		for _, res := range results {
+			log.Infof("scheduled update of %s: %s", res.Ref.AliasString(), res.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
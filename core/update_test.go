@@ -0,0 +1,102 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/qri-io/qri/repo"
+	"github.com/qri-io/qri/repo/profile"
+	testrepo "github.com/qri-io/qri/repo/test"
+)
+
+func TestUpdateRequests(t *testing.T) {
+	data := "species,count\nblue jay,4\ncardinal,2\n"
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(data))
+	}))
+	defer s.Close()
+
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	created := &repo.DatasetRef{}
+	if err := NewDatasetRequests(mr, nil).Init(&InitParams{Peername: "peer", Name: "birds", URL: s.URL + "/birds.csv"}, created); err != nil {
+		t.Errorf("error creating dataset: %s", err.Error())
+		return
+	}
+
+	req := NewUpdateRequests(mr, nil)
+	jobs := []UpdateJob{}
+	if err := req.List(new(bool), &jobs); err != nil {
+		t.Errorf("error listing updates: %s", err.Error())
+		return
+	}
+	if len(jobs) != 1 {
+		t.Errorf("expected 1 update job. got: %d", len(jobs))
+		return
+	}
+	if jobs[0].Periodicity != defaultAccrualPeriodicity || jobs[0].Due {
+		t.Errorf("expected new dataset to update weekly & not be due. got: %s, %t", jobs[0].Periodicity, jobs[0].Due)
+	}
+
+	res := []UpdateResult{}
+	if err := req.Run(&UpdateRunParams{}, &res); err != nil {
+		t.Errorf("error running updates: %s", err.Error())
+		return
+	}
+	if len(res) != 0 {
+		t.Errorf("expected no datasets to be due. got: %d", len(res))
+	}
+
+	birds := []repo.DatasetRef{{Peername: "peer", Name: "birds"}}
+	if err := req.Run(&UpdateRunParams{Refs: birds}, &res); err != nil {
+		t.Errorf("error running update: %s", err.Error())
+		return
+	}
+	if len(res) != 1 || res[0].Status != UpdateUnchanged {
+		t.Errorf("expected unchanged data not to update. got: %v", res)
+	}
+
+	data = "species,count\nblue jay,5\ncardinal,2\n"
+	if err := req.Run(&UpdateRunParams{Refs: birds}, &res); err != nil {
+		t.Errorf("error running update: %s", err.Error())
+		return
+	}
+	if len(res) != 1 || res[0].Status != UpdateUpdated {
+		t.Errorf("expected changed data to update. got: %v", res)
+		return
+	}
+	if res[0].Ref.Path == created.Path {
+		t.Errorf("expected update to save a new version")
+	}
+	events, err := mr.Events(1, 0)
+	if err != nil || len(events) != 1 || events[0].Type != repo.ETDsUpdated {
+		t.Errorf("expected update to be logged. got: %v, %v", events, err)
+	}
+
+	movies := []repo.DatasetRef{{Peername: "peer", Name: "movies"}}
+	if err := req.Run(&UpdateRunParams{Refs: movies}, &res); err == nil {
+		t.Errorf("expected updating a dataset without a url to error")
+	}
+
+	// datasets added from other peers aren't ours to update
+	other := repo.DatasetRef{Peername: "other", ProfileID: profile.IDB58MustDecode("QmZePf5LeXow3RW5U1AgEiNbW46YnRGhZ7HPvm1UmPFPwt"), Name: "birds", Path: res[0].Ref.Path}
+	if err := mr.PutRef(other); err != nil {
+		t.Errorf("error putting ref: %s", err.Error())
+		return
+	}
+	if err := req.List(new(bool), &jobs); err != nil {
+		t.Errorf("error listing updates: %s", err.Error())
+		return
+	}
+	if len(jobs) != 1 || jobs[0].Ref.Peername != "peer" {
+		t.Errorf("expected only the peer's own dataset to be scheduled. got: %v", jobs)
+	}
+	others := []repo.DatasetRef{{Peername: "other", Name: "birds"}}
+	if err := req.Run(&UpdateRunParams{Refs: others}, &res); err == nil {
+		t.Errorf("expected updating another peer's dataset to error")
+	}
+}
@@ -0,0 +1,157 @@
+package p2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/qri-io/qri/repo"
+	"github.com/qri-io/qri/repo/profile"
+
+	peer "gx/ipfs/QmZoWKhxUmZ2seW4BzX6fJkNR8hh9PsGModr7q171yq2SS/go-libp2p-peer"
+)
+
+// MtChangeRequest delivers change requests & change request status updates
+const MtChangeRequest = MsgType("change_request")
+
+const (
+	// crActionPropose delivers a new change request to a dataset owner
+	crActionPropose = "propose"
+	// crActionStatus tells a change request author the owner's decision
+	crActionStatus = "status"
+)
+
+// SendChangeRequest delivers a change request to the owner of the
+// change request's target dataset
+func (n *QriNode) SendChangeRequest(cr *repo.ChangeRequest) error {
+	log.Debugf("%s SendChangeRequest %s", n.ID, cr.ID)
+	return n.sendChangeRequest(cr, cr.Target.ProfileID, crActionPropose)
+}
+
+// SendChangeRequestStatus tells the author of a change request that it's
+// been accepted or rejected
+func (n *QriNode) SendChangeRequestStatus(cr *repo.ChangeRequest) error {
+	log.Debugf("%s SendChangeRequestStatus %s %s", n.ID, cr.ID, cr.Status)
+	return n.sendChangeRequest(cr, cr.Proposal.ProfileID, crActionStatus)
+}
+
+// sendChangeRequest tries each known peer of a profile until one accepts
+// a change request message
+func (n *QriNode) sendChangeRequest(cr *repo.ChangeRequest, to profile.ID, action string) error {
+	if !n.Online {
+		return fmt.Errorf("cannot send change requests while offline")
+	}
+
+	pids, err := n.Repo.Profiles().PeerIDs(to)
+	if err != nil || len(pids) == 0 {
+		return fmt.Errorf("couldn't find a peer address for profile: %s", to)
+	}
+
+	req, err := NewJSONBodyMessage(n.ID, MtChangeRequest, cr)
+	if err != nil {
+		log.Debug(err.Error())
+		return err
+	}
+	req = req.WithHeaders("phase", "request", "action", action)
+
+	for _, pid := range pids {
+		replies := make(chan Message)
+		if err := n.SendMessage(req, replies, pid); err != nil {
+			log.Debug(err.Error())
+			continue
+		}
+
+		select {
+		case res := <-replies:
+			if msg := res.Header("error"); msg != "" {
+				return fmt.Errorf("peer rejected change request: %s", msg)
+			}
+			return nil
+		case <-time.After(time.Until(req.Deadline)):
+			log.Debugf("%s timed out waiting for change request response from %s", n.ID, pid)
+		}
+	}
+
+	return fmt.Errorf("couldn't reach any peers for profile: %s", to)
+}
+
+func (n *QriNode) handleChangeRequest(ws *WrappedStream, msg Message) (hangup bool) {
+	hangup = true
+
+	if msg.Header("phase") != "request" {
+		return
+	}
+
+	errmsg := ""
+	cr := &repo.ChangeRequest{}
+	if err := json.Unmarshal(msg.Body, cr); err != nil {
+		log.Debug(err.Error())
+		errmsg = err.Error()
+	} else if err := n.receiveChangeRequest(msg.provider, msg.Header("action"), cr); err != nil {
+		log.Debug(err.Error())
+		errmsg = err.Error()
+	}
+
+	res := msg.Update(nil).WithHeaders("phase", "response", "error", errmsg)
+	if err := ws.sendMessage(res); err != nil {
+		log.Debug(err.Error())
+	}
+	return
+}
+
+// receiveChangeRequest stores a change request sent by a peer. Only a
+// change request's author may propose it, and only the owner of the target
+// dataset may set its status
+func (n *QriNode) receiveChangeRequest(sender peer.ID, action string, cr *repo.ChangeRequest) error {
+	pro, err := n.Repo.Profile()
+	if err != nil {
+		return err
+	}
+	from, err := n.Repo.Profiles().PeerProfile(sender)
+	if err != nil {
+		return fmt.Errorf("unknown peer: %s", sender.Pretty())
+	}
+	store := n.Repo.ChangeRequests()
+
+	switch action {
+	case crActionPropose:
+		if !cr.Incoming(pro.ID) {
+			return fmt.Errorf("change request isn't for this profile")
+		}
+		if from.ID != cr.Proposal.ProfileID {
+			return fmt.Errorf("only a change request's author can propose it")
+		}
+		id, err := repo.NewChangeRequestID(cr.Target.Path, cr.Proposal.Path)
+		if err != nil {
+			return err
+		}
+		if id != cr.ID {
+			return fmt.Errorf("invalid change request id: %s", cr.ID)
+		}
+		if prev, err := store.GetChangeRequest(cr.ID); err == nil && prev.Status != repo.CRStatusOpen {
+			return fmt.Errorf("change request %s is already %s", cr.ID, prev.Status)
+		}
+
+		cr.Status = repo.CRStatusOpen
+		cr.Sent = true
+		cr.Updated = time.Now()
+		return store.PutChangeRequest(cr)
+	case crActionStatus:
+		local, err := store.GetChangeRequest(cr.ID)
+		if err != nil {
+			return fmt.Errorf("unknown change request: %s", cr.ID)
+		}
+		if from.ID != local.Target.ProfileID {
+			return fmt.Errorf("only the dataset owner can accept or reject a change request")
+		}
+		if cr.Status != repo.CRStatusAccepted && cr.Status != repo.CRStatusRejected {
+			return fmt.Errorf("invalid change request status: %s", cr.Status)
+		}
+
+		local.Status = cr.Status
+		local.Updated = time.Now()
+		return store.PutChangeRequest(local)
+	}
+
+	return fmt.Errorf("unknown change request action: '%s'", action)
+}
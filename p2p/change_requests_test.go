@@ -0,0 +1,94 @@
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qri-io/qri/repo"
+)
+
+func TestChangeRequests(t *testing.T) {
+	ctx := context.Background()
+	peers, err := NewTestNetwork(ctx, t, 2)
+	if err != nil {
+		t.Errorf("error creating network: %s", err.Error())
+		return
+	}
+	if err := connectNodes(ctx, peers); err != nil {
+		t.Errorf("error connecting peers: %s", err.Error())
+		return
+	}
+
+	author, owner := peers[0], peers[1]
+	// each peer needs to know the other's profile & address
+	for _, p := range [][2]*QriNode{{author, owner}, {owner, author}} {
+		pro, err := p[1].Repo.Profile()
+		if err != nil {
+			t.Errorf("error getting profile: %s", err.Error())
+			return
+		}
+		known := *pro
+		known.Addresses = map[string][]string{p[1].ID.Pretty(): []string{}}
+		if err := p[0].Repo.Profiles().PutProfile(&known); err != nil {
+			t.Errorf("error putting profile: %s", err.Error())
+			return
+		}
+	}
+
+	authorPro, _ := author.Repo.Profile()
+	ownerPro, _ := owner.Repo.Profile()
+	target := repo.DatasetRef{Peername: ownerPro.Peername, ProfileID: ownerPro.ID, Name: "movies", Path: "/map/QmTarget"}
+	proposal := repo.DatasetRef{Peername: authorPro.Peername, ProfileID: authorPro.ID, Name: "movies", Path: "/map/QmProposal"}
+	id, err := repo.NewChangeRequestID(target.Path, proposal.Path)
+	if err != nil {
+		t.Errorf("error creating id: %s", err.Error())
+		return
+	}
+	cr := &repo.ChangeRequest{
+		ID:       id,
+		Created:  time.Now(),
+		Updated:  time.Now(),
+		Status:   repo.CRStatusOpen,
+		Title:    "fix typos",
+		Target:   target,
+		Proposal: proposal,
+	}
+	if err := author.Repo.ChangeRequests().PutChangeRequest(cr); err != nil {
+		t.Errorf("error putting change request: %s", err.Error())
+		return
+	}
+
+	if err := author.SendChangeRequest(cr); err != nil {
+		t.Errorf("error sending change request: %s", err.Error())
+		return
+	}
+	got, err := owner.Repo.ChangeRequests().GetChangeRequest(id)
+	if err != nil {
+		t.Errorf("expected owner to have change request. got error: %s", err.Error())
+		return
+	}
+	if got.Title != cr.Title || got.Proposal.Path != proposal.Path {
+		t.Errorf("change request mismatch. expected: %v, got: %v", cr, got)
+	}
+
+	// status updates are addressed to Proposal.ProfileID, so this sends to
+	// the owner, who must refuse a status change from the author
+	if err := author.SendChangeRequestStatus(&repo.ChangeRequest{ID: id, Status: repo.CRStatusAccepted, Proposal: target}); err == nil {
+		t.Errorf("expected author setting change request status to fail")
+	}
+
+	accepted := *got
+	accepted.Status = repo.CRStatusAccepted
+	if err := owner.SendChangeRequestStatus(&accepted); err != nil {
+		t.Errorf("error sending change request status: %s", err.Error())
+		return
+	}
+	if got, err = author.Repo.ChangeRequests().GetChangeRequest(id); err != nil {
+		t.Errorf("error getting change request: %s", err.Error())
+		return
+	}
+	if got.Status != repo.CRStatusAccepted {
+		t.Errorf("expected author's change request to be accepted. got: %s", got.Status)
+	}
+}
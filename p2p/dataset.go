@@ -11,6 +11,9 @@ import (
 // MtDatasetInfo gets info on a dataset
 const MtDatasetInfo = MsgType("dataset_info")
 
+// MtDatasetAdded tells a dataset's owner a peer has added their dataset
+const MtDatasetAdded = MsgType("dataset_added")
+
 // RequestDataset fetches info about a dataset from qri peers
 // It's expected the local peer has attempted to canonicalize the reference
 // before sending to the network
@@ -90,6 +93,9 @@ func (n *QriNode) handleDataset(ws *WrappedStream, msg Message) (hangup bool) {
 				if err := act.ReadDataset(&ref); err != nil {
 					log.Debug(err.Error())
 				}
+				if err := repo.RecordStat(n.Repo, ref, repo.StatPeerInfoRequests); err != nil {
+					log.Debug(err.Error())
+				}
 
 				res, err = msg.UpdateJSON(ref)
 				if err != nil {
@@ -108,3 +114,57 @@ func (n *QriNode) handleDataset(ws *WrappedStream, msg Message) (hangup bool) {
 
 	return
 }
+
+// AnnounceDatasetAdded lets the owner of a dataset know it's been added to
+// this peer's repo. Announcements are best-effort, no response is expected
+func (n *QriNode) AnnounceDatasetAdded(ref repo.DatasetRef) error {
+	log.Debugf("%s AnnounceDatasetAdded %s", n.ID, ref)
+
+	if !n.Online {
+		return fmt.Errorf("cannot announce datasets while offline")
+	}
+
+	pids, err := n.Repo.Profiles().PeerIDs(ref.ProfileID)
+	if err != nil || len(pids) == 0 {
+		return fmt.Errorf("couldn't find a peer address for profile: %s", ref.ProfileID)
+	}
+
+	msg, err := NewJSONBodyMessage(n.ID, MtDatasetAdded, repo.DatasetRef{
+		Peername:  ref.Peername,
+		ProfileID: ref.ProfileID,
+		Name:      ref.Name,
+		Path:      ref.Path,
+	})
+	if err != nil {
+		log.Debug(err.Error())
+		return err
+	}
+
+	return n.SendMessage(msg, nil, pids...)
+}
+
+func (n *QriNode) handleDatasetAdded(ws *WrappedStream, msg Message) (hangup bool) {
+	hangup = true
+
+	dsr := repo.DatasetRef{}
+	if err := json.Unmarshal(msg.Body, &dsr); err != nil {
+		log.Debug(err.Error())
+		return
+	}
+
+	// only count adds of datasets this peer owns
+	pro, err := n.Repo.Profile()
+	if err != nil || pro.ID != dsr.ProfileID {
+		return
+	}
+
+	ref, err := n.Repo.GetRef(repo.DatasetRef{Peername: pro.Peername, Name: dsr.Name})
+	if err != nil {
+		log.Debug(err.Error())
+		return
+	}
+	if err := repo.RecordStat(n.Repo, ref, repo.StatPeerAdds); err != nil {
+		log.Debug(err.Error())
+	}
+	return
+}
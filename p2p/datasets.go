@@ -19,9 +19,13 @@ const listMax = 30
 type DatasetsListParams struct {
 	Limit  int
 	Offset int
+	// Tags asks for tags of listed datasets to be included in the response
+	Tags bool
 }
 
-// RequestDatasetsList gets a list of a peer's datasets
+// RequestDatasetsList gets a list of a peer's datasets. Tags the peer
+// sends for its own datasets are added to this node's repo, so references
+// to tagged versions of the peer's datasets can be resolved
 func (n *QriNode) RequestDatasetsList(pid peer.ID, p DatasetsListParams) ([]repo.DatasetRef, error) {
 	log.Debugf("%s RequestDatasetList: %s", n.ID, pid)
 
@@ -34,6 +38,7 @@ func (n *QriNode) RequestDatasetsList(pid peer.ID, p DatasetsListParams) ([]repo
 		return nil, fmt.Errorf("not connected to p2p network")
 	}
 
+	p.Tags = true
 	req, err := NewJSONBodyMessage(n.ID, MtDatasets, p)
 	if err != nil {
 		log.Debug(err.Error())
@@ -50,9 +55,32 @@ func (n *QriNode) RequestDatasetsList(pid peer.ID, p DatasetsListParams) ([]repo
 	}
 
 	res := <-replies
-	ref := []repo.DatasetRef{}
-	err = json.Unmarshal(res.Body, &ref)
-	return ref, err
+	refs := []repo.DatasetRef{}
+	if err = json.Unmarshal(res.Body, &refs); err != nil {
+		return nil, err
+	}
+
+	// peers can only tag their own datasets
+	from, err := n.Repo.Profiles().PeerProfile(pid)
+	if err != nil {
+		log.Debugf("error getting profile for peer %s, ignoring tags: %s", pid.Pretty(), err.Error())
+	}
+
+	datasets := make([]repo.DatasetRef, 0, len(refs))
+	for _, ref := range refs {
+		if ref.Tag == "" {
+			datasets = append(datasets, ref)
+			continue
+		}
+		if from == nil || ref.ProfileID != from.ID || ref.Peername != from.Peername {
+			log.Debugf("ignoring tag %s@%s, peer %s doesn't own it", ref.AliasString(), ref.Tag, pid.Pretty())
+			continue
+		}
+		if err := n.Repo.Tags().PutTag(ref); err != nil {
+			log.Debugf("error saving tag %s@%s: %s", ref.AliasString(), ref.Tag, err.Error())
+		}
+	}
+	return datasets, nil
 }
 
 func (n *QriNode) handleDatasetsList(ws *WrappedStream, msg Message) (hangup bool) {
@@ -75,6 +103,20 @@ func (n *QriNode) handleDatasetsList(ws *WrappedStream, msg Message) (hangup boo
 			return
 		}
 
+		// tags follow the datasets they belong to. peers that don't ask for
+		// tags would mistake them for datasets
+		if dlp.Tags {
+			datasets := refs
+			for _, ref := range datasets {
+				tags, err := n.Repo.Tags().ListTags(ref)
+				if err != nil {
+					log.Debug(err.Error())
+					continue
+				}
+				refs = append(refs, tags...)
+			}
+		}
+
 		// replies := make([]*repo.DatasetRef, p.Limit)
 		// i := 0
 		// for i, ref := range refs {
@@ -4,6 +4,9 @@ import (
 	"context"
 	"sync"
 	"testing"
+
+	"github.com/qri-io/qri/repo"
+	"github.com/qri-io/qri/repo/profile"
 )
 
 func TestRequestDatasetsList(t *testing.T) {
@@ -41,3 +44,63 @@ func TestRequestDatasetsList(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestRequestDatasetsListTags(t *testing.T) {
+	ctx := context.Background()
+	peers, err := NewTestDirNetwork(ctx, t)
+	if err != nil {
+		t.Errorf("error creating network: %s", err.Error())
+		return
+	}
+	if err := connectNodes(ctx, peers); err != nil {
+		t.Errorf("error connecting peers: %s", err.Error())
+	}
+	p1, p2 := peers[0], peers[1]
+
+	refs, err := p2.Repo.References(1, 0)
+	if err != nil || len(refs) == 0 {
+		t.Errorf("expected peer to have datasets")
+		return
+	}
+	tag := refs[0]
+	tag.Tag = "v1"
+	if err := p2.Repo.Tags().PutTag(tag); err != nil {
+		t.Errorf("error putting tag: %s", err.Error())
+		return
+	}
+
+	// peers can't tag datasets they don't own
+	foreign := repo.DatasetRef{Peername: "other", ProfileID: profile.IDB58MustDecode("QmZePf5LeXow3RW5U1AgEiNbW46YnRGhZ7HPvm1UmPFPwt"), Name: tag.Name, Path: tag.Path}
+	if err := p2.Repo.PutRef(foreign); err != nil {
+		t.Errorf("error putting ref: %s", err.Error())
+		return
+	}
+	foreign.Tag = "v1"
+	if err := p2.Repo.Tags().PutTag(foreign); err != nil {
+		t.Errorf("error putting tag: %s", err.Error())
+		return
+	}
+
+	list, err := p1.RequestDatasetsList(p2.ID, DatasetsListParams{Limit: 10})
+	if err != nil {
+		t.Errorf("error requesting datasets list: %s", err.Error())
+		return
+	}
+	for _, ref := range list {
+		if ref.Tag != "" {
+			t.Errorf("expected tags to be separated from datasets. got: %s", ref)
+		}
+	}
+
+	got, err := p1.Repo.Tags().GetTag(tag)
+	if err != nil {
+		t.Errorf("expected tag to be shared with requesting peer: %s", err.Error())
+		return
+	}
+	if got.Path != tag.Path {
+		t.Errorf("tag path mismatch. expected: %s, got: %s", tag.Path, got.Path)
+	}
+	if _, err := p1.Repo.Tags().GetTag(foreign); err != repo.ErrNotFound {
+		t.Errorf("expected tag for a dataset the peer doesn't own to be dropped. got: %v", err)
+	}
+}
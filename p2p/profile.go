@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/qri-io/qri/repo"
 	"github.com/qri-io/qri/repo/profile"
 
 	peer "gx/ipfs/QmZoWKhxUmZ2seW4BzX6fJkNR8hh9PsGModr7q171yq2SS/go-libp2p-peer"
@@ -45,7 +46,8 @@ func (n *QriNode) RequestProfile(pid peer.ID) (*profile.Profile, error) {
 		return nil, err
 	}
 
-	if err := n.Repo.Profiles().PutProfile(pro); err != nil {
+	// read-only repos can't keep the profile, but can still report it
+	if err := n.Repo.Profiles().PutProfile(pro); err != nil && err != repo.ErrReadOnly {
 		log.Debug(err.Error())
 		return nil, err
 	}
@@ -1,10 +1,14 @@
 package actions
 
 import (
+	"encoding/base64"
+	"fmt"
+
 	"github.com/ipfs/go-datastore"
 	"github.com/qri-io/cafs"
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/dataset/dsfs"
+	"github.com/qri-io/dsdiff"
 	"github.com/qri-io/qri/repo"
 	"github.com/qri-io/qri/repo/profile"
 )
@@ -30,7 +34,81 @@ func (act Dataset) CreateDataset(name string, ds *dataset.Dataset, data cafs.Fil
 	if err != nil {
 		return
 	}
+	return act.putVersion(pro, name, ds, path, pin)
+}
+
+// SaveDataset adds a version of a dataset whose data is already in the store.
+// Unlike CreateDataset, data isn't read to check it, so the dataset's
+// structure must already record the checksum, length & entry count of data
+func (act Dataset) SaveDataset(name string, ds *dataset.Dataset, data cafs.File, pin bool) (ref repo.DatasetRef, err error) {
+	var (
+		path datastore.Key
+		pro  *profile.Profile
+	)
+	pro, err = act.Profile()
+	if err != nil {
+		return
+	}
+
+	path, err = act.WriteDataset(ds, data, pin)
+	if err != nil {
+		return
+	}
+	return act.putVersion(pro, name, ds, path, pin)
+}
+
+// WriteDataset writes a version of a dataset whose data is already in the
+// store without changing any references, returning the version's path.
+// Commits without a title are titled with changes from the previous version.
+// Every commit is timestamped & signed with the repo's private key
+func (act Dataset) WriteDataset(ds *dataset.Dataset, data cafs.File, pin bool) (datastore.Key, error) {
+	pk := act.PrivateKey()
+	if pk == nil {
+		return datastore.NewKey(""), fmt.Errorf("private key is required to create a dataset")
+	}
+	if ds.Structure == nil {
+		return datastore.NewKey(""), fmt.Errorf("structure is required to create a dataset")
+	}
+	if ds.Commit == nil {
+		ds.Commit = &dataset.Commit{}
+	}
+
+	if ds.Commit.Title == "" {
+		prev := &dataset.Dataset{}
+		if ds.PreviousPath != "" && ds.PreviousPath != "/" {
+			loaded, err := dsfs.LoadDataset(act.Store(), datastore.NewKey(ds.PreviousPath))
+			if err != nil {
+				return datastore.NewKey(""), fmt.Errorf("error loading previous dataset: %s", err.Error())
+			}
+			prev = loaded
+		}
+		diffs, err := dsdiff.DiffDatasets(prev, ds, nil)
+		if err != nil {
+			return datastore.NewKey(""), fmt.Errorf("error diffing datasets: %s", err.Error())
+		}
+		title, err := dsdiff.MapDiffsToString(diffs, "listKeys")
+		if err != nil {
+			return datastore.NewKey(""), fmt.Errorf("error describing changes: %s", err.Error())
+		}
+		if title == "" {
+			return datastore.NewKey(""), fmt.Errorf("no changes detected")
+		}
+		ds.Commit.Title = title
+	}
+
+	ds.Commit.Timestamp = dsfs.Timestamp()
+	sig, err := pk.Sign(ds.Commit.SignableBytes())
+	if err != nil {
+		return datastore.NewKey(""), fmt.Errorf("error signing commit: %s", err.Error())
+	}
+	ds.Commit.Signature = base64.StdEncoding.EncodeToString(sig)
+
+	return dsfs.WriteDataset(act.Store(), ds, data, pin)
+}
 
+// putVersion points the reference for a dataset name at a newly written
+// version, logging the change
+func (act Dataset) putVersion(pro *profile.Profile, name string, ds *dataset.Dataset, path datastore.Key, pin bool) (ref repo.DatasetRef, err error) {
 	if ds.PreviousPath != "" && ds.PreviousPath != "/" {
 		prev := repo.DatasetRef{
 			ProfileID: pro.ID,
@@ -7,8 +7,10 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/ipfs/go-datastore"
 	"github.com/libp2p/go-libp2p-crypto"
 	"github.com/qri-io/cafs"
+	"github.com/qri-io/dataset"
 	"github.com/qri-io/dataset/dstest"
 	"github.com/qri-io/qri/repo"
 	"github.com/qri-io/qri/repo/profile"
@@ -62,6 +64,7 @@ type RepoTestFunc func(t *testing.T, rmf RepoMakerFunc)
 func DatasetTests(t *testing.T, rmf RepoMakerFunc) {
 	for _, test := range []RepoTestFunc{
 		testCreateDataset,
+		testSaveDataset,
 		testReadDataset,
 		testRenameDataset,
 		testDatasetPinning,
@@ -96,6 +99,61 @@ func createDataset(t *testing.T, rmf RepoMakerFunc) (repo.Repo, repo.DatasetRef)
 	return r, ref
 }
 
+func testSaveDataset(t *testing.T, rmf RepoMakerFunc) {
+	r, ref := createDataset(t, rmf)
+	act := Dataset{r}
+	if err := act.ReadDataset(&ref); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	next := func(prev repo.DatasetRef) *dataset.Dataset {
+		ds := &dataset.Dataset{}
+		ds.Assign(prev.Dataset)
+		ds.PreviousPath = prev.Path
+		ds.Commit = &dataset.Commit{}
+		ds.Meta = &dataset.Meta{Title: "cities of the world"}
+		ds.Structure.SetPath("")
+		return ds
+	}
+
+	data, err := r.Store().Get(datastore.NewKey(ref.Dataset.DataPath))
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	ds := next(ref)
+	saved, err := act.SaveDataset(ref.Name, ds, data, true)
+	data.Close()
+	if err != nil {
+		t.Errorf("error saving dataset: %s", err.Error())
+		return
+	}
+	if err := act.ReadDataset(&saved); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if saved.Dataset.Commit.Title == "" || saved.Dataset.Commit.Signature == "" {
+		t.Errorf("expected saved commit to be titled & signed. got: %v", saved.Dataset.Commit)
+	}
+	if saved.Dataset.DataPath != ref.Dataset.DataPath {
+		t.Errorf("data path mismatch. expected: %s, got: %s", ref.Dataset.DataPath, saved.Dataset.DataPath)
+	}
+	if got, err := r.GetRef(repo.DatasetRef{Peername: ref.Peername, Name: ref.Name}); err != nil || got.Path != saved.Path {
+		t.Errorf("expected reference to move to the saved version. got: %s, %v", got.Path, err)
+	}
+
+	data, err = r.Store().Get(datastore.NewKey(ref.Dataset.DataPath))
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer data.Close()
+	if _, err := act.WriteDataset(next(saved), data, true); err == nil {
+		t.Errorf("expected writing a version with no changes to error")
+	}
+}
+
 func testReadDataset(t *testing.T, rmf RepoMakerFunc) {
 	r, ref := createDataset(t, rmf)
 	act := Dataset{r}
@@ -0,0 +1,163 @@
+package repo
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/qri-io/qri/repo/profile"
+)
+
+// StatType names a counter kept for each dataset
+type StatType string
+
+const (
+	// StatAPIReads counts dataset requests made through the JSON api
+	StatAPIReads = StatType("api_reads")
+	// StatDataReads counts requests for dataset data, either from qri data
+	// or the /data api endpoint
+	StatDataReads = StatType("data_reads")
+	// StatPeerInfoRequests counts dataset info requests served to peers
+	StatPeerInfoRequests = StatType("peer_info_requests")
+	// StatPeerAdds counts peers that have added a dataset to their repo
+	StatPeerAdds = StatType("peer_adds")
+)
+
+// DatasetStats is a set of usage counters for a dataset. Counters are
+// kept for a dataset's name, and accumulate across versions
+type DatasetStats struct {
+	Peername  string     `json:"peername"`
+	ProfileID profile.ID `json:"profileID,omitempty"`
+	Name      string     `json:"name"`
+	// Counts maps StatTypes to the number of times they've occurred
+	Counts map[StatType]int `json:"counts"`
+	// Updated is the last time any counter changed
+	Updated time.Time `json:"updated"`
+}
+
+// Total sums all counters
+func (s *DatasetStats) Total() (total int) {
+	for _, c := range s.Counts {
+		total += c
+	}
+	return
+}
+
+// copy duplicates stats, so counters can be read while they're updated
+func (s *DatasetStats) copy() *DatasetStats {
+	cp := *s
+	cp.Counts = make(map[StatType]int, len(s.Counts))
+	for t, c := range s.Counts {
+		cp.Counts[t] = c
+	}
+	return &cp
+}
+
+// Analytics keeps usage counters for datasets in a repo
+type Analytics interface {
+	// IncrementStat adds one to a dataset's counter. ref must have a
+	// Peername & Name
+	IncrementStat(ref DatasetRef, t StatType) error
+	// DatasetStats gets counters for a single dataset, returning ErrNotFound
+	// if nothing has been recorded for the dataset
+	DatasetStats(ref DatasetRef) (*DatasetStats, error)
+	// Stats lists counters for all datasets, most used first
+	Stats(limit, offset int) ([]*DatasetStats, error)
+}
+
+// RecordStat increments a counter for a dataset in a repo, completing
+// the reference from the repo's refstore if it's missing a name
+func RecordStat(r Repo, ref DatasetRef, t StatType) error {
+	if ref.Name == "" {
+		got, err := r.GetRef(ref)
+		if err != nil {
+			return err
+		}
+		ref = got
+	}
+	return r.Analytics().IncrementStat(ref, t)
+}
+
+// MemAnalytics is an in-memory implementation of the Analytics interface
+type MemAnalytics struct {
+	sync.Mutex
+	stats map[string]*DatasetStats
+}
+
+// NewMemAnalytics allocates a MemAnalytics
+func NewMemAnalytics() *MemAnalytics {
+	return &MemAnalytics{stats: map[string]*DatasetStats{}}
+}
+
+// IncrementStat adds one to a dataset's counter
+func (a *MemAnalytics) IncrementStat(ref DatasetRef, t StatType) error {
+	a.Lock()
+	defer a.Unlock()
+	return IncrementStat(a.stats, ref, t)
+}
+
+// DatasetStats gets counters for a single dataset
+func (a *MemAnalytics) DatasetStats(ref DatasetRef) (*DatasetStats, error) {
+	a.Lock()
+	defer a.Unlock()
+	if s, ok := a.stats[ref.AliasString()]; ok {
+		return s.copy(), nil
+	}
+	return nil, ErrNotFound
+}
+
+// Stats lists counters for all datasets, most used first
+func (a *MemAnalytics) Stats(limit, offset int) ([]*DatasetStats, error) {
+	a.Lock()
+	defer a.Unlock()
+	return PageDatasetStats(a.stats, limit, offset), nil
+}
+
+// IncrementStat adds one to a dataset's counter within a map of stats
+// keyed by dataset alias, for use by Analytics implementations
+func IncrementStat(stats map[string]*DatasetStats, ref DatasetRef, t StatType) error {
+	if ref.Peername == "" {
+		return ErrPeernameRequired
+	} else if ref.Name == "" {
+		return ErrNameRequired
+	}
+
+	key := ref.AliasString()
+	s, ok := stats[key]
+	if !ok {
+		s = &DatasetStats{
+			Peername:  ref.Peername,
+			ProfileID: ref.ProfileID,
+			Name:      ref.Name,
+			Counts:    map[StatType]int{},
+		}
+		stats[key] = s
+	}
+	s.Counts[t]++
+	s.Updated = time.Now()
+	return nil
+}
+
+// PageDatasetStats sorts stats by total use, most used first, and returns
+// copies of the requested page
+func PageDatasetStats(stats map[string]*DatasetStats, limit, offset int) []*DatasetStats {
+	list := make([]*DatasetStats, 0, len(stats))
+	for _, s := range stats {
+		list = append(list, s.copy())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if ti, tj := list[i].Total(), list[j].Total(); ti != tj {
+			return ti > tj
+		}
+		return list[i].Peername+list[i].Name < list[j].Peername+list[j].Name
+	})
+
+	if offset > len(list) {
+		offset = len(list)
+	}
+	stop := limit + offset
+	if limit < 0 || stop > len(list) {
+		stop = len(list)
+	}
+	return list[offset:stop]
+}
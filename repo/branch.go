@@ -0,0 +1,171 @@
+package repo
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultBranch is the name of the line of history a dataset's reference
+// points to. The default branch isn't kept in a BranchStore, its head is
+// always the dataset's reference in the repo's Refstore
+const DefaultBranch = "main"
+
+// ErrFork is returned when a new version would fork a dataset's history,
+// building on a version other than the head of the branch it's saved to
+var ErrFork = fmt.Errorf("repo: save would fork dataset history")
+
+// BranchStore keeps named lines of history for datasets alongside the
+// default branch. Branches are DatasetRefs with Branch set, where Path is
+// the branch's latest version
+type BranchStore interface {
+	// PutBranch creates or moves a branch. ref must have Peername, Name,
+	// Branch & Path
+	PutBranch(ref DatasetRef) error
+	// GetBranch completes a branch reference with the branch's head,
+	// returning ErrNotFound for unknown branches
+	GetBranch(ref DatasetRef) (DatasetRef, error)
+	// DeleteBranch removes a branch
+	DeleteBranch(ref DatasetRef) error
+	// ListBranches lists branches for the dataset ref names, or all branches
+	// if ref has no Name. Branches are ordered by alias, then branch name
+	ListBranches(ref DatasetRef) ([]DatasetRef, error)
+}
+
+// BranchKey gives the key a branch is stored under, for use by
+// BranchStore implementations
+func BranchKey(ref DatasetRef) string {
+	return ref.AliasString() + "#" + ref.Branch
+}
+
+// MemBranchStore is an in-memory implementation of the BranchStore
+// interface
+type MemBranchStore struct {
+	sync.Mutex
+	branches map[string]DatasetRef
+}
+
+// NewMemBranchStore allocates a MemBranchStore
+func NewMemBranchStore() *MemBranchStore {
+	return &MemBranchStore{branches: map[string]DatasetRef{}}
+}
+
+// PutBranch creates or moves a branch
+func (s *MemBranchStore) PutBranch(ref DatasetRef) error {
+	s.Lock()
+	defer s.Unlock()
+	return PutBranch(s.branches, ref)
+}
+
+// GetBranch completes a branch reference
+func (s *MemBranchStore) GetBranch(ref DatasetRef) (DatasetRef, error) {
+	s.Lock()
+	defer s.Unlock()
+	if b, ok := s.branches[BranchKey(ref)]; ok {
+		return b, nil
+	}
+	return DatasetRef{}, ErrNotFound
+}
+
+// DeleteBranch removes a branch
+func (s *MemBranchStore) DeleteBranch(ref DatasetRef) error {
+	s.Lock()
+	defer s.Unlock()
+	key := BranchKey(ref)
+	if _, ok := s.branches[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.branches, key)
+	return nil
+}
+
+// ListBranches lists branches for a dataset, or all branches
+func (s *MemBranchStore) ListBranches(ref DatasetRef) ([]DatasetRef, error) {
+	s.Lock()
+	defer s.Unlock()
+	return FilterBranches(s.branches, ref), nil
+}
+
+// PutBranch adds a branch to a map of branches keyed by BranchKey, for use
+// by BranchStore implementations
+func PutBranch(branches map[string]DatasetRef, ref DatasetRef) error {
+	if ref.Peername == "" {
+		return ErrPeernameRequired
+	} else if ref.Name == "" {
+		return ErrNameRequired
+	} else if ref.Path == "" {
+		return ErrPathRequired
+	}
+	if err := ValidateBranch(ref.Branch); err != nil {
+		return err
+	}
+
+	branches[BranchKey(ref)] = DatasetRef{
+		Peername:  ref.Peername,
+		ProfileID: ref.ProfileID,
+		Name:      ref.Name,
+		Branch:    ref.Branch,
+		Path:      ref.Path,
+	}
+	return nil
+}
+
+// FilterBranches lists the branches in a map of branches that belong to
+// the dataset ref names, or all branches if ref has no Name
+func FilterBranches(branches map[string]DatasetRef, ref DatasetRef) []DatasetRef {
+	list := []DatasetRef{}
+	for _, b := range branches {
+		if ref.Name == "" || (b.Peername == ref.Peername && b.Name == ref.Name) {
+			list = append(list, b)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if ai, aj := list[i].AliasString(), list[j].AliasString(); ai != aj {
+			return ai < aj
+		}
+		return list[i].Branch < list[j].Branch
+	})
+	return list
+}
+
+// ValidateBranch checks a branch name is usable. Branches share a
+// namespace with tags, and can't be named after the default branch
+func ValidateBranch(branch string) error {
+	if branch == DefaultBranch {
+		return fmt.Errorf("'%s' is the default branch", DefaultBranch)
+	}
+	return validateRefName("branch", branch)
+}
+
+// RenameBranches moves all branches of one dataset to another, for use when
+// a dataset is renamed
+func RenameBranches(bs BranchStore, from, to DatasetRef) error {
+	branches, err := bs.ListBranches(from)
+	if err != nil {
+		return err
+	}
+	for _, b := range branches {
+		if err := bs.DeleteBranch(b); err != nil {
+			return err
+		}
+		b.Peername, b.ProfileID, b.Name = to.Peername, to.ProfileID, to.Name
+		if err := bs.PutBranch(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteBranches removes all branches of a dataset
+func DeleteBranches(bs BranchStore, ref DatasetRef) error {
+	branches, err := bs.ListBranches(ref)
+	if err != nil {
+		return err
+	}
+	for _, b := range branches {
+		if err := bs.DeleteBranch(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+package repo
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/qri/repo/profile"
+)
+
+// BundleVersion is the version of the bundle format written by ExportBundle
+const BundleVersion = 1
+
+const (
+	bundleManifest = "manifest.json"
+	bundleBlocks   = "blocks"
+)
+
+// EventImporter is an opt-in interface for event logs that can restore
+// events with their original timestamps. Event logs that don't support
+// importing have events replayed with LogEvent, stamping them with the
+// time they're imported
+type EventImporter interface {
+	// ImportEvents appends events to the log. events are ordered oldest
+	// first, and must be newer than any event already in the log
+	ImportEvents(events []*Event) error
+}
+
+// BundleBlock is a single piece of content in a bundle, stored in the
+// bundle's blocks directory under its store path
+type BundleBlock struct {
+	Path string `json:"path"`
+	Dir  bool   `json:"dir,omitempty"`
+}
+
+// BundleManifest describes the contents of a repo bundle
+type BundleManifest struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	// StorePrefix is the path prefix of the store blocks were exported from.
+	// bundles can only be imported into repos with the same kind of store
+	StorePrefix string             `json:"storePrefix"`
+	Profile     *profile.Profile   `json:"profile"`
+	Profiles    []*profile.Profile `json:"profiles"`
+	Refs        []DatasetRef       `json:"refs"`
+	Tags        []DatasetRef       `json:"tags"`
+	Branches    []DatasetRef       `json:"branches"`
+	// Events is the repo's event log, oldest first
+	Events []*Event      `json:"events"`
+	Blocks []BundleBlock `json:"blocks"`
+}
+
+// ExportBundle writes a zip archive holding everything needed to restore a
+// repo elsewhere: references, tags, branches, profiles, the event log and
+// every block reachable from them
+func ExportBundle(r Repo, w io.Writer) (*BundleManifest, error) {
+	m := &BundleManifest{
+		Version:     BundleVersion,
+		Created:     time.Now(),
+		StorePrefix: r.Store().PathPrefix(),
+		Blocks:      []BundleBlock{},
+	}
+
+	var err error
+	if m.Profile, err = r.Profile(); err != nil {
+		return nil, fmt.Errorf("error getting profile: %s", err.Error())
+	}
+	profiles, err := r.Profiles().List()
+	if err != nil {
+		return nil, fmt.Errorf("error listing profiles: %s", err.Error())
+	}
+	for _, pro := range profiles {
+		m.Profiles = append(m.Profiles, pro)
+	}
+	sort.Slice(m.Profiles, func(i, j int) bool { return m.Profiles[i].ID < m.Profiles[j].ID })
+
+	count, err := r.RefCount()
+	if err != nil {
+		return nil, fmt.Errorf("error counting references: %s", err.Error())
+	}
+	if m.Refs, err = r.References(count, 0); err != nil {
+		return nil, fmt.Errorf("error listing references: %s", err.Error())
+	}
+	if m.Tags, err = r.Tags().ListTags(DatasetRef{}); err != nil {
+		return nil, fmt.Errorf("error listing tags: %s", err.Error())
+	}
+	if m.Branches, err = r.Branches().ListBranches(DatasetRef{}); err != nil {
+		return nil, fmt.Errorf("error listing branches: %s", err.Error())
+	}
+
+	if m.Events, err = allEvents(r); err != nil {
+		return nil, err
+	}
+
+	live, err := LivePaths(r)
+	if err != nil {
+		return nil, fmt.Errorf("error finding blocks: %s", err.Error())
+	}
+	paths := make([]string, 0, len(live))
+	for p := range live {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	zw := zip.NewWriter(w)
+	for _, p := range paths {
+		f, err := r.Store().Get(datastore.NewKey(p))
+		if err != nil {
+			return nil, fmt.Errorf("error getting block %s: %s", p, err.Error())
+		}
+		err = writeBundleFile(zw, path.Join(bundleBlocks, p), f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error writing block %s: %s", p, err.Error())
+		}
+		m.Blocks = append(m.Blocks, BundleBlock{Path: p, Dir: f.IsDirectory()})
+	}
+
+	mw, err := zw.Create(bundleManifest)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding manifest: %s", err.Error())
+	}
+	if _, err := mw.Write(data); err != nil {
+		return nil, err
+	}
+	return m, zw.Close()
+}
+
+// writeBundleFile adds a file to a zip archive at name. Directories are
+// written as a directory entry followed by their contents
+func writeBundleFile(zw *zip.Writer, name string, f cafs.File) error {
+	if !f.IsDirectory() {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, f)
+		return err
+	}
+
+	if _, err := zw.Create(name + "/"); err != nil {
+		return err
+	}
+	for {
+		child, err := f.NextFile()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		err = writeBundleFile(zw, path.Join(name, child.FileName()), child)
+		child.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ImportBundle restores a bundle written by ExportBundle into an empty
+// repo. Each block is added to the repo's store & pinned, and must hash to
+// the path it was exported from. References are only written once every
+// block is verified
+func ImportBundle(r Repo, ra io.ReaderAt, size int64) (*BundleManifest, error) {
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, fmt.Errorf("error opening bundle: %s", err.Error())
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	mf, ok := files[bundleManifest]
+	if !ok {
+		return nil, fmt.Errorf("bundle is missing %s", bundleManifest)
+	}
+	m := &BundleManifest{}
+	if err := readBundleJSON(mf, m); err != nil {
+		return nil, fmt.Errorf("error reading manifest: %s", err.Error())
+	}
+	if m.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version: %d", m.Version)
+	}
+	if prefix := r.Store().PathPrefix(); m.StorePrefix != prefix {
+		return nil, fmt.Errorf("bundle holds %s content, which can't be imported into a %s store", m.StorePrefix, prefix)
+	}
+
+	if count, err := r.RefCount(); err != nil {
+		return nil, err
+	} else if count > 0 {
+		return nil, fmt.Errorf("can only import bundles into an empty repo, this repo has %d datasets", count)
+	}
+
+	for _, b := range m.Blocks {
+		f, err := readBundleBlock(zr, files, b)
+		if err != nil {
+			return nil, fmt.Errorf("error reading block %s: %s", b.Path, err.Error())
+		}
+		key, err := r.Store().Put(f, true)
+		if err != nil {
+			return nil, fmt.Errorf("error adding block %s: %s", b.Path, err.Error())
+		}
+		if key.String() != b.Path {
+			// don't leave content that failed verification in the store
+			r.Store().Delete(key)
+			return nil, fmt.Errorf("block %s failed verification, content hashes to %s", b.Path, key.String())
+		}
+	}
+
+	for _, pro := range m.Profiles {
+		if err := r.Profiles().PutProfile(pro); err != nil {
+			return nil, fmt.Errorf("error adding profile: %s", err.Error())
+		}
+	}
+	for _, ref := range m.Refs {
+		if err := r.PutRef(ref); err != nil {
+			return nil, fmt.Errorf("error adding reference %s: %s", ref, err.Error())
+		}
+	}
+	for _, ref := range m.Tags {
+		if err := r.Tags().PutTag(ref); err != nil {
+			return nil, fmt.Errorf("error adding tag: %s", err.Error())
+		}
+	}
+	for _, ref := range m.Branches {
+		if err := r.Branches().PutBranch(ref); err != nil {
+			return nil, fmt.Errorf("error adding branch: %s", err.Error())
+		}
+	}
+
+	if importer, ok := AsEventImporter(r); ok {
+		if err := importer.ImportEvents(m.Events); err != nil {
+			return nil, fmt.Errorf("error importing events: %s", err.Error())
+		}
+	} else {
+		for _, e := range m.Events {
+			if err := r.LogEvent(e.Type, e.Ref); err != nil {
+				return nil, fmt.Errorf("error importing events: %s", err.Error())
+			}
+		}
+	}
+
+	return m, nil
+}
+
+func readBundleJSON(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(rc).Decode(v)
+}
+
+// readBundleBlock rebuilds a block as a cafs.File from archive entries
+func readBundleBlock(zr *zip.Reader, files map[string]*zip.File, b BundleBlock) (cafs.File, error) {
+	name := path.Join(bundleBlocks, b.Path)
+	if !b.Dir {
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("bundle is missing %s", name)
+		}
+		return readBundleMemfile(f, path.Base(name))
+	}
+
+	if _, ok := files[name+"/"]; !ok {
+		return nil, fmt.Errorf("bundle is missing %s", name+"/")
+	}
+	dirs := map[string]*cafs.Memdir{name: cafs.NewMemdir("/" + path.Base(name))}
+	entries := []*zip.File{}
+	for _, f := range zr.File {
+		if strings.HasPrefix(f.Name, name+"/") && f.Name != name+"/" {
+			entries = append(entries, f)
+		}
+	}
+	// parent directories sort before their contents
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	for _, f := range entries {
+		entry := strings.TrimSuffix(f.Name, "/")
+		parent, ok := dirs[path.Dir(entry)]
+		if !ok {
+			return nil, fmt.Errorf("bundle is missing %s", path.Dir(entry)+"/")
+		}
+		if strings.HasSuffix(f.Name, "/") {
+			d := cafs.NewMemdir(strings.TrimPrefix(entry, name))
+			dirs[entry] = d
+			parent.AddChildren(d)
+			continue
+		}
+		mf, err := readBundleMemfile(f, strings.TrimPrefix(entry, name))
+		if err != nil {
+			return nil, err
+		}
+		parent.AddChildren(mf)
+	}
+	return dirs[name], nil
+}
+
+// readBundleMemfile creates a file that streams the content of an archive
+// entry, so blocks aren't held in memory while they're added to a store
+func readBundleMemfile(f *zip.File, name string) (cafs.File, error) {
+	return cafs.NewMemfileReader(name, &bundleEntry{f: f}), nil
+}
+
+// bundleEntry reads an archive entry, opening it on the first read & closing
+// it when the entry is read to the end. Entries are checked against the CRC
+// recorded in the archive as they're read
+type bundleEntry struct {
+	f   *zip.File
+	rc  io.ReadCloser
+	err error
+}
+
+// Read implements the io.Reader interface
+func (e *bundleEntry) Read(p []byte) (n int, err error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	if e.rc == nil {
+		if e.rc, e.err = e.f.Open(); e.err != nil {
+			return 0, e.err
+		}
+	}
+	n, err = e.rc.Read(p)
+	if err != nil {
+		e.err = err
+		e.rc.Close()
+	}
+	return n, err
+}
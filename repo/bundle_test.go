@@ -0,0 +1,126 @@
+package repo
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/dataset/dsfs"
+	"github.com/qri-io/qri/repo/profile"
+)
+
+func TestBundle(t *testing.T) {
+	src, err := makeTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	ref, err := src.GetRef(DatasetRef{Peername: "peer", Name: "ds1"})
+	if err != nil {
+		t.Errorf("error getting ref: %s", err.Error())
+		return
+	}
+	if err := src.LogEvent(ETDsCreated, ref); err != nil {
+		t.Errorf("error logging event: %s", err.Error())
+		return
+	}
+	if err := src.Tags().PutTag(DatasetRef{Peername: "peer", Name: "ds1", Tag: "v1", Path: ref.Path}); err != nil {
+		t.Errorf("error putting tag: %s", err.Error())
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	exported, err := ExportBundle(src, buf)
+	if err != nil {
+		t.Errorf("error exporting bundle: %s", err.Error())
+		return
+	}
+	if len(exported.Refs) != 2 || len(exported.Events) != 1 || len(exported.Blocks) == 0 {
+		t.Errorf("expected 2 refs, 1 event & some blocks. got: %d, %d, %d", len(exported.Refs), len(exported.Events), len(exported.Blocks))
+	}
+
+	dst, err := NewMemRepo(&profile.Profile{Peername: "peer"}, cafs.NewMapstore(), profile.NewMemStore())
+	if err != nil {
+		t.Errorf("error allocating mem repo: %s", err.Error())
+		return
+	}
+	data := bytes.NewReader(buf.Bytes())
+	if _, err := ImportBundle(dst, data, data.Size()); err != nil {
+		t.Errorf("error importing bundle: %s", err.Error())
+		return
+	}
+
+	got, err := dst.GetRef(DatasetRef{Peername: "peer", Name: "ds1"})
+	if err != nil {
+		t.Errorf("error getting imported ref: %s", err.Error())
+		return
+	}
+	if _, err := dsfs.LoadDataset(dst.Store(), datastore.NewKey(got.Path)); err != nil {
+		t.Errorf("error loading imported dataset: %s", err.Error())
+	}
+	if tag, err := dst.Tags().GetTag(DatasetRef{Peername: "peer", Name: "ds1", Tag: "v1"}); err != nil || tag.Path != ref.Path {
+		t.Errorf("expected imported tag v1 at %s. got: %s, %v", ref.Path, tag.Path, err)
+	}
+	events, err := dst.Events(10, 0)
+	if err != nil {
+		t.Errorf("error reading events: %s", err.Error())
+		return
+	}
+	if len(events) != 1 || !events[0].Time.Equal(exported.Events[0].Time) {
+		t.Errorf("expected imported event to keep its timestamp. got: %v", events)
+	}
+
+	if _, err := ImportBundle(dst, data, data.Size()); err == nil {
+		t.Errorf("expected importing into a repo with datasets to error")
+	}
+
+	// alter the content of a single file block
+	tampered := &bytes.Buffer{}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Errorf("error reading bundle: %s", err.Error())
+		return
+	}
+	zw := zip.NewWriter(tampered)
+	altered := false
+	for _, f := range zr.File {
+		w, err := zw.Create(f.Name)
+		if err != nil {
+			t.Errorf("error writing bundle: %s", err.Error())
+			return
+		}
+		if !altered && strings.HasPrefix(f.Name, bundleBlocks+"/") && !strings.HasSuffix(f.Name, "/") {
+			w.Write([]byte("not the original content"))
+			altered = true
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Errorf("error reading bundle entry: %s", err.Error())
+			return
+		}
+		io.Copy(w, rc)
+		rc.Close()
+	}
+	if err := zw.Close(); err != nil {
+		t.Errorf("error writing bundle: %s", err.Error())
+		return
+	}
+
+	dst, err = NewMemRepo(&profile.Profile{Peername: "peer"}, cafs.NewMapstore(), profile.NewMemStore())
+	if err != nil {
+		t.Errorf("error allocating mem repo: %s", err.Error())
+		return
+	}
+	data = bytes.NewReader(tampered.Bytes())
+	if _, err := ImportBundle(dst, data, data.Size()); err == nil {
+		t.Errorf("expected importing a tampered bundle to error")
+	}
+	if count, err := dst.RefCount(); err != nil || count != 0 {
+		t.Errorf("expected no references after a failed import. got: %d, %v", count, err)
+	}
+}
@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/multiformats/go-multihash"
+	"github.com/qri-io/qri/repo/profile"
+)
+
+// ChangeRequestStatus is the state of a change request
+type ChangeRequestStatus string
+
+const (
+	// CRStatusOpen is a change request awaiting a decision from the dataset owner
+	CRStatusOpen = ChangeRequestStatus("open")
+	// CRStatusAccepted is a change request the dataset owner has merged
+	CRStatusAccepted = ChangeRequestStatus("accepted")
+	// CRStatusRejected is a change request the dataset owner has declined
+	CRStatusRejected = ChangeRequestStatus("rejected")
+)
+
+// ChangeRequest is a proposal to add a version to another peer's dataset.
+// The proposed version must build on the dataset's head at the time
+// the request is made, so accepting it fast-forwards the dataset
+type ChangeRequest struct {
+	// ID uniquely identifies a change request, see NewChangeRequestID
+	ID      string              `json:"id"`
+	Created time.Time           `json:"created"`
+	Updated time.Time           `json:"updated"`
+	Status  ChangeRequestStatus `json:"status"`
+	// Title & Message describe the proposed changes
+	Title   string `json:"title"`
+	Message string `json:"message,omitempty"`
+	// Target is the dataset changes are proposed to. Target.Path is the
+	// version the proposal builds on
+	Target DatasetRef `json:"target"`
+	// Proposal is the proposed version. Proposal.Peername & ProfileID
+	// identify the author of the change request
+	Proposal DatasetRef `json:"proposal"`
+	// Sent is true once the target dataset's owner has received the request
+	Sent bool `json:"sent"`
+}
+
+// NewChangeRequestID derives a change request identifier from the paths of
+// the target & proposed dataset versions
+func NewChangeRequestID(target, proposal string) (string, error) {
+	sum, err := multihash.Sum([]byte(target+proposal), multihash.SHA2_256, -1)
+	if err != nil {
+		return "", err
+	}
+	return sum.B58String(), nil
+}
+
+// Incoming returns true if this change request proposes changes to
+// a dataset owned by id
+func (cr *ChangeRequest) Incoming(id profile.ID) bool {
+	return cr.Target.ProfileID == id
+}
+
+// ChangeRequestStore keeps a collection of change requests, both those
+// made by this repo's profile and those made to this repo's datasets
+type ChangeRequestStore interface {
+	// PutChangeRequest adds or updates a change request
+	PutChangeRequest(cr *ChangeRequest) error
+	// GetChangeRequest fetches a change request by ID, returning ErrNotFound
+	// if it doesn't exist
+	GetChangeRequest(id string) (*ChangeRequest, error)
+	// DeleteChangeRequest removes a change request
+	DeleteChangeRequest(id string) error
+	// ListChangeRequests lists change requests, most recently updated first
+	ListChangeRequests(limit, offset int) ([]*ChangeRequest, error)
+}
+
+// MemChangeRequestStore is an in-memory implementation of the
+// ChangeRequestStore interface
+type MemChangeRequestStore struct {
+	sync.Mutex
+	requests map[string]*ChangeRequest
+}
+
+// NewMemChangeRequestStore allocates a MemChangeRequestStore
+func NewMemChangeRequestStore() *MemChangeRequestStore {
+	return &MemChangeRequestStore{requests: map[string]*ChangeRequest{}}
+}
+
+// PutChangeRequest adds or updates a change request
+func (s *MemChangeRequestStore) PutChangeRequest(cr *ChangeRequest) error {
+	if cr.ID == "" {
+		return fmt.Errorf("change request ID is required")
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.requests[cr.ID] = cr
+	return nil
+}
+
+// GetChangeRequest fetches a change request by ID
+func (s *MemChangeRequestStore) GetChangeRequest(id string) (*ChangeRequest, error) {
+	s.Lock()
+	defer s.Unlock()
+	if cr, ok := s.requests[id]; ok {
+		return cr, nil
+	}
+	return nil, ErrNotFound
+}
+
+// DeleteChangeRequest removes a change request
+func (s *MemChangeRequestStore) DeleteChangeRequest(id string) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.requests[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.requests, id)
+	return nil
+}
+
+// ListChangeRequests lists change requests, most recently updated first
+func (s *MemChangeRequestStore) ListChangeRequests(limit, offset int) ([]*ChangeRequest, error) {
+	s.Lock()
+	defer s.Unlock()
+	crs := make([]*ChangeRequest, 0, len(s.requests))
+	for _, cr := range s.requests {
+		crs = append(crs, cr)
+	}
+	return PageChangeRequests(crs, limit, offset), nil
+}
+
+// PageChangeRequests sorts change requests by last update, most recent
+// first, and returns the requested page. A limit below zero returns all
+// requests from offset onward
+func PageChangeRequests(crs []*ChangeRequest, limit, offset int) []*ChangeRequest {
+	sort.Slice(crs, func(i, j int) bool {
+		if crs[i].Updated.Equal(crs[j].Updated) {
+			return crs[i].ID < crs[j].ID
+		}
+		return crs[i].Updated.After(crs[j].Updated)
+	})
+
+	if offset > len(crs) {
+		offset = len(crs)
+	}
+	stop := limit + offset
+	if limit < 0 || stop > len(crs) {
+		stop = len(crs)
+	}
+	return crs[offset:stop]
+}
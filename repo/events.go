@@ -35,6 +35,12 @@ const (
 	ETDsUnpinned = EventType("ds_unpinned")
 	// ETDsAdded represents adding a reference to another peer's dataset to their node
 	ETDsAdded = EventType("ds_added")
+	// ETDsUpdated represents a scheduled update saving a new version of a dataset
+	ETDsUpdated = EventType("ds_updated")
+	// ETDsUpdateUnchanged represents a scheduled update finding a dataset's source hasn't changed
+	ETDsUpdateUnchanged = EventType("ds_update_unchanged")
+	// ETDsUpdateFailed represents a scheduled update failing to fetch or save a dataset
+	ETDsUpdateFailed = EventType("ds_update_failed")
 )
 
 // MemEventLog is an in-memory implementation of the
@@ -54,6 +60,15 @@ func (log *MemEventLog) LogEvent(t EventType, ref DatasetRef) error {
 	return nil
 }
 
+// ImportEvents adds events with their original timestamps, implementing
+// the EventImporter interface
+func (log *MemEventLog) ImportEvents(events []*Event) error {
+	logs := append(append([]*Event{}, events...), *log...)
+	sort.Slice(logs, func(i, j int) bool { return logs[i].Time.After(logs[j].Time) })
+	*log = logs
+	return nil
+}
+
 // Events grabs a set of Events from the store
 func (log MemEventLog) Events(limit, offset int) ([]*Event, error) {
 	if offset > len(log) {
@@ -0,0 +1,88 @@
+package fsrepo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/qri-io/qri/repo"
+)
+
+// Analytics is an on-disk json file implementation of the repo.Analytics
+// interface. Counters are rewritten on every change, so only a repo that's
+// held exclusively can record them
+type Analytics struct {
+	sync.Mutex
+	basepath
+	readOnly bool
+}
+
+// NewAnalytics allocates an Analytics. readOnly analytics refuse to
+// change counters, returning repo.ErrReadOnly
+func NewAnalytics(bp basepath, readOnly bool) *Analytics {
+	return &Analytics{basepath: bp, readOnly: readOnly}
+}
+
+// IncrementStat adds one to a dataset's counter
+func (a *Analytics) IncrementStat(ref repo.DatasetRef, t repo.StatType) error {
+	if a.readOnly {
+		return repo.ErrReadOnly
+	}
+	a.Lock()
+	defer a.Unlock()
+
+	stats, err := a.stats()
+	if err != nil {
+		return err
+	}
+	if err := repo.IncrementStat(stats, ref, t); err != nil {
+		return err
+	}
+	return a.saveFile(stats, FileAnalytics)
+}
+
+// DatasetStats gets counters for a single dataset
+func (a *Analytics) DatasetStats(ref repo.DatasetRef) (*repo.DatasetStats, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	stats, err := a.stats()
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := stats[ref.AliasString()]; ok {
+		return s, nil
+	}
+	return nil, repo.ErrNotFound
+}
+
+// Stats lists counters for all datasets, most used first
+func (a *Analytics) Stats(limit, offset int) ([]*repo.DatasetStats, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	stats, err := a.stats()
+	if err != nil {
+		return nil, err
+	}
+	return repo.PageDatasetStats(stats, limit, offset), nil
+}
+
+func (a *Analytics) stats() (map[string]*repo.DatasetStats, error) {
+	stats := map[string]*repo.DatasetStats{}
+	data, err := a.readBytes(FileAnalytics)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error loading analytics: %s", err.Error())
+	}
+
+	if err := json.Unmarshal(data, &stats); err != nil {
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error decoding analytics: %s", err.Error())
+	}
+	return stats, nil
+}
@@ -0,0 +1,99 @@
+package fsrepo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/qri-io/qri/repo"
+)
+
+// BranchStore is an on-disk json file implementation of the repo.BranchStore
+// interface
+type BranchStore struct {
+	sync.Mutex
+	basepath
+}
+
+// NewBranchStore allocates a BranchStore
+func NewBranchStore(bp basepath) *BranchStore {
+	return &BranchStore{basepath: bp}
+}
+
+// PutBranch creates or moves a branch
+func (s *BranchStore) PutBranch(ref repo.DatasetRef) error {
+	s.Lock()
+	defer s.Unlock()
+
+	branches, err := s.branches()
+	if err != nil {
+		return err
+	}
+	if err := repo.PutBranch(branches, ref); err != nil {
+		return err
+	}
+	return s.saveFile(branches, FileBranches)
+}
+
+// GetBranch completes a branch reference
+func (s *BranchStore) GetBranch(ref repo.DatasetRef) (repo.DatasetRef, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	branches, err := s.branches()
+	if err != nil {
+		return repo.DatasetRef{}, err
+	}
+	if t, ok := branches[repo.BranchKey(ref)]; ok {
+		return t, nil
+	}
+	return repo.DatasetRef{}, repo.ErrNotFound
+}
+
+// DeleteBranch removes a branch
+func (s *BranchStore) DeleteBranch(ref repo.DatasetRef) error {
+	s.Lock()
+	defer s.Unlock()
+
+	branches, err := s.branches()
+	if err != nil {
+		return err
+	}
+	key := repo.BranchKey(ref)
+	if _, ok := branches[key]; !ok {
+		return repo.ErrNotFound
+	}
+	delete(branches, key)
+	return s.saveFile(branches, FileBranches)
+}
+
+// ListBranches lists branches for a dataset, or all branches
+func (s *BranchStore) ListBranches(ref repo.DatasetRef) ([]repo.DatasetRef, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	branches, err := s.branches()
+	if err != nil {
+		return nil, err
+	}
+	return repo.FilterBranches(branches, ref), nil
+}
+
+func (s *BranchStore) branches() (map[string]repo.DatasetRef, error) {
+	branches := map[string]repo.DatasetRef{}
+	data, err := s.readBytes(FileBranches)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return branches, nil
+		}
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error loading branches: %s", err.Error())
+	}
+
+	if err := json.Unmarshal(data, &branches); err != nil {
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error decoding branches: %s", err.Error())
+	}
+	return branches, nil
+}
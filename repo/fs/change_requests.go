@@ -0,0 +1,104 @@
+package fsrepo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/qri-io/qri/repo"
+)
+
+// ChangeRequestStore is an on-disk json file implementation of the
+// repo.ChangeRequestStore interface
+type ChangeRequestStore struct {
+	sync.Mutex
+	basepath
+}
+
+// NewChangeRequestStore allocates a ChangeRequestStore
+func NewChangeRequestStore(bp basepath) *ChangeRequestStore {
+	return &ChangeRequestStore{basepath: bp}
+}
+
+// PutChangeRequest adds or updates a change request
+func (s *ChangeRequestStore) PutChangeRequest(cr *repo.ChangeRequest) error {
+	if cr.ID == "" {
+		return fmt.Errorf("change request ID is required")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	crs, err := s.requests()
+	if err != nil {
+		return err
+	}
+	crs[cr.ID] = cr
+	return s.saveFile(crs, FileChangeRequests)
+}
+
+// GetChangeRequest fetches a change request by ID
+func (s *ChangeRequestStore) GetChangeRequest(id string) (*repo.ChangeRequest, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	crs, err := s.requests()
+	if err != nil {
+		return nil, err
+	}
+	if cr, ok := crs[id]; ok {
+		return cr, nil
+	}
+	return nil, repo.ErrNotFound
+}
+
+// DeleteChangeRequest removes a change request
+func (s *ChangeRequestStore) DeleteChangeRequest(id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	crs, err := s.requests()
+	if err != nil {
+		return err
+	}
+	if _, ok := crs[id]; !ok {
+		return repo.ErrNotFound
+	}
+	delete(crs, id)
+	return s.saveFile(crs, FileChangeRequests)
+}
+
+// ListChangeRequests lists change requests, most recently updated first
+func (s *ChangeRequestStore) ListChangeRequests(limit, offset int) ([]*repo.ChangeRequest, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	crs, err := s.requests()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*repo.ChangeRequest, 0, len(crs))
+	for _, cr := range crs {
+		list = append(list, cr)
+	}
+	return repo.PageChangeRequests(list, limit, offset), nil
+}
+
+func (s *ChangeRequestStore) requests() (map[string]*repo.ChangeRequest, error) {
+	crs := map[string]*repo.ChangeRequest{}
+	data, err := s.readBytes(FileChangeRequests)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return crs, nil
+		}
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error loading change requests: %s", err.Error())
+	}
+
+	if err := json.Unmarshal(data, &crs); err != nil {
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error decoding change requests: %s", err.Error())
+	}
+	return crs, nil
+}
@@ -1,95 +1,519 @@
 package fsrepo
 
 import (
+	"bufio"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/qri-io/cafs"
 	"github.com/qri-io/qri/repo"
 )
 
-// EventLog is a file-based implementation of the repo.EventLog interface
+// idxRecordSize is the byte length of a single event index record:
+// an int64 unix nanosecond timestamp followed by an int64 byte offset
+// into the event log file
+const idxRecordSize = 16
+
+// EventLog is a file-based implementation of the repo.EventLog interface.
+// Events are appended as newline-delimited json records to a log file,
+// with a fixed-width index of timestamps & offsets kept alongside, so
+// writing is constant-time and reads only touch the records they return.
+// The index is written after the record it points to, so a crash mid-write
+// can never corrupt previously logged events
 type EventLog struct {
 	basepath
 	file  File
+	index File
 	store cafs.Filestore
+	lock  *sync.Mutex
+	// readOnly logs are held under a shared repo lock, and never write
+	readOnly bool
 }
 
-// NewEventLog allocates a new file-based EventLog instance
-func NewEventLog(base string, file File, store cafs.Filestore) EventLog {
-	return EventLog{basepath: basepath(base), file: file, store: store}
-}
+// NewEventLog allocates a new file-based EventLog instance, recovering from
+// any partially-written events. Read-only logs skip recovery, reads only
+// ever see completely written events
+func NewEventLog(base string, file, index File, store cafs.Filestore, readOnly bool) (*EventLog, error) {
+	el := &EventLog{
+		basepath: basepath(base),
+		file:     file,
+		index:    index,
+		store:    store,
+		lock:     &sync.Mutex{},
+		readOnly: readOnly,
+	}
+	if readOnly {
+		return el, nil
+	}
 
-// LogEvent adds a Event to the store
-func (ql EventLog) LogEvent(t repo.EventType, ref repo.DatasetRef) error {
-	log, err := ql.logs()
-	if err != nil {
-		return err
+	if err := el.recover(); err != nil {
+		return nil, err
 	}
+	return el, nil
+}
 
-	e := &repo.Event{
+// LogEvent appends an Event to the store
+func (ql *EventLog) LogEvent(t repo.EventType, ref repo.DatasetRef) error {
+	return ql.appendEvents(&repo.Event{
 		Time: time.Now(),
 		Type: t,
 		Ref:  ref,
+	})
+}
+
+// ImportEvents appends events with their original timestamps, implementing
+// the repo.EventImporter interface. events must be ordered oldest-first, and
+// be newer than any event already in the log
+func (ql *EventLog) ImportEvents(events []*repo.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if ql.readOnly {
+		return repo.ErrReadOnly
 	}
-	log = append([]*repo.Event{e}, log...)
-	sort.Slice(log, func(i, j int) bool { return log[i].Time.After(log[j].Time) })
-	return ql.saveFile(log, ql.file)
+	for i := 1; i < len(events); i++ {
+		if events[i].Time.Before(events[i-1].Time) {
+			return fmt.Errorf("imported events must be ordered oldest-first")
+		}
+	}
+
+	ql.lock.Lock()
+	defer ql.lock.Unlock()
+
+	latest, err := ql.latest()
+	if err != nil {
+		return err
+	}
+	if events[0].Time.Before(latest) {
+		return fmt.Errorf("imported events must be newer than the latest event in the log, %s", latest.Format(time.RFC3339))
+	}
+	return ql.writeEvents(events...)
 }
 
-// Events fetches a set of Events from the store
-func (ql EventLog) Events(limit, offset int) ([]*repo.Event, error) {
-	logs, err := ql.logs()
+// Events fetches a set of Events from the store, ordered newest-first
+func (ql *EventLog) Events(limit, offset int) ([]*repo.Event, error) {
+	ql.lock.Lock()
+	defer ql.lock.Unlock()
+
+	idx, err := ql.openIndex()
+	if err != nil {
+		return nil, err
+	}
+	defer idx.Close()
+
+	count, err := indexLen(idx)
 	if err != nil {
 		return nil, err
 	}
 
-	if offset > len(logs) {
-		offset = len(logs)
+	if offset > count {
+		offset = count
 	}
 	stop := limit + offset
-	if stop > len(logs) {
-		stop = len(logs)
+	if stop > count {
+		stop = count
+	}
+
+	// index is ordered oldest-first, read the window in reverse
+	offsets, _, err := readIndexRange(idx, count-stop, count-offset)
+	if err != nil {
+		return nil, err
 	}
 
-	return logs[offset:stop], nil
+	events, err := ql.readEvents(offsets)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events, nil
 }
 
-// EventsSince fetches a set of Events from the store that occur after a given timestamp
-func (ql EventLog) EventsSince(t time.Time) ([]*repo.Event, error) {
-	logs, err := ql.logs()
+// EventsSince fetches a set of Events from the store that occur after a given timestamp,
+// ordered oldest-first
+func (ql *EventLog) EventsSince(t time.Time) ([]*repo.Event, error) {
+	ql.lock.Lock()
+	defer ql.lock.Unlock()
+
+	idx, err := ql.openIndex()
+	if err != nil {
+		return nil, err
+	}
+	defer idx.Close()
+
+	count, err := indexLen(idx)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := t.UnixNano()
+	var searchErr error
+	start := sort.Search(count, func(i int) bool {
+		_, times, err := readIndexRange(idx, i, i+1)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return times[0] > ts
+	})
+	if searchErr != nil {
+		return nil, searchErr
+	}
+
+	offsets, _, err := readIndexRange(idx, start, count)
 	if err != nil {
 		return nil, err
 	}
+	return ql.readEvents(offsets)
+}
+
+// appendEvents writes events to the end of the log
+func (ql *EventLog) appendEvents(events ...*repo.Event) error {
+	if ql.readOnly {
+		return repo.ErrReadOnly
+	}
+	ql.lock.Lock()
+	defer ql.lock.Unlock()
+	return ql.writeEvents(events...)
+}
+
+// latest gives the time of the most recent event in the log, or the zero
+// time if the log is empty. callers must hold the log's lock
+func (ql *EventLog) latest() (time.Time, error) {
+	idx, err := ql.openIndex()
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer idx.Close()
+
+	count, err := indexLen(idx)
+	if err != nil || count == 0 {
+		return time.Time{}, err
+	}
+	_, times, err := readIndexRange(idx, count-1, count)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, times[0]), nil
+}
+
+// writeEvents writes events to the end of the log, syncing event data to
+// disk before the index that points to it. The index must be ordered by
+// time, so events older than the latest in the log, like ones logged after
+// the system clock steps back, are given the latest time. callers must
+// hold the log's lock
+func (ql *EventLog) writeEvents(events ...*repo.Event) error {
+	latest, err := ql.latest()
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if e.Time.Before(latest) {
+			e.Time = latest
+		}
+		latest = e.Time
+	}
+
+	f, err := os.OpenFile(ql.filepath(ql.file), os.O_WRONLY|os.O_CREATE|os.O_APPEND, os.ModePerm)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error opening event log: %s", err.Error())
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
 
-	events := make([]*repo.Event, 0, len(logs))
-	for _, e := range logs {
-		if e.Time.After(t) {
-			events = append([]*repo.Event{e}, events...)
+	data := []byte{}
+	idxdata := make([]byte, 0, len(events)*idxRecordSize)
+	for _, e := range events {
+		line, err := json.Marshal(e)
+		if err != nil {
+			log.Debug(err.Error())
+			return fmt.Errorf("error encoding event: %s", err.Error())
 		}
+
+		rec := make([]byte, idxRecordSize)
+		binary.BigEndian.PutUint64(rec[:8], uint64(e.Time.UnixNano()))
+		binary.BigEndian.PutUint64(rec[8:], uint64(offset+int64(len(data))))
+		idxdata = append(idxdata, rec...)
+
+		data = append(data, append(line, '\n')...)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error writing event: %s", err.Error())
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	idx, err := os.OpenFile(ql.filepath(ql.index), os.O_WRONLY|os.O_CREATE|os.O_APPEND, os.ModePerm)
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error opening event log index: %s", err.Error())
+	}
+	defer idx.Close()
+
+	if _, err := idx.Write(idxdata); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error writing event index: %s", err.Error())
+	}
+	return idx.Sync()
+}
+
+// readEvents reads the event records that begin at each of a list of offsets
+func (ql *EventLog) readEvents(offsets []int64) ([]*repo.Event, error) {
+	events := make([]*repo.Event, 0, len(offsets))
+	if len(offsets) == 0 {
+		return events, nil
 	}
 
+	f, err := os.Open(ql.filepath(ql.file))
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error loading logs: %s", err.Error())
+	}
+	defer f.Close()
+
+	for _, off := range offsets {
+		line, err := readLineAt(f, off)
+		if err != nil {
+			log.Debug(err.Error())
+			return nil, fmt.Errorf("error reading event: %s", err.Error())
+		}
+		e := &repo.Event{}
+		if err := json.Unmarshal(line, e); err != nil {
+			log.Debug(err.Error())
+			return nil, fmt.Errorf("error unmarshaling event: %s", err.Error())
+		}
+		events = append(events, e)
+	}
 	return events, nil
 }
 
-func (ql *EventLog) logs() ([]*repo.Event, error) {
-	ds := []*repo.Event{}
-	data, err := ioutil.ReadFile(ql.filepath(ql.file))
+// recover drops any incomplete trailing index record, and truncates any
+// event data written after the last indexed event. A missing or empty index
+// is rebuilt from the log
+func (ql *EventLog) recover() error {
+	ql.lock.Lock()
+	defer ql.lock.Unlock()
+
+	idxpath := ql.filepath(ql.index)
+	fi, err := os.Stat(idxpath)
+	if os.IsNotExist(err) {
+		return ql.rebuildIndex()
+	} else if err != nil {
+		return err
+	}
+
+	if rem := fi.Size() % idxRecordSize; rem != 0 {
+		log.Infof("dropping incomplete event index record")
+		if err := os.Truncate(idxpath, fi.Size()-rem); err != nil {
+			return err
+		}
+	}
+
+	idx, err := ql.openIndex()
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+
+	count, err := indexLen(idx)
+	if err != nil {
+		return err
+	} else if count == 0 {
+		return ql.rebuildIndex()
+	}
+
+	offsets, _, err := readIndexRange(idx, count-1, count)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(ql.filepath(ql.file))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	line, err := readLineAt(f, offsets[0])
+	if err != nil {
+		return fmt.Errorf("event log is corrupt: %s", err.Error())
+	}
+	end := offsets[0] + int64(len(line)) + 1
+
+	if fi, err := f.Stat(); err == nil && fi.Size() > end {
+		log.Infof("dropping incomplete event log record")
+		return os.Truncate(ql.filepath(ql.file), end)
+	}
+	return nil
+}
+
+// rebuildIndex writes a new index of every complete event in the log,
+// truncating the log after the last one. callers must hold the log's lock
+func (ql *EventLog) rebuildIndex() error {
+	f, err := os.OpenFile(ql.filepath(ql.file), os.O_RDONLY|os.O_CREATE, os.ModePerm)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return ds, nil
+		log.Debug(err.Error())
+		return fmt.Errorf("error opening event log: %s", err.Error())
+	}
+	defer f.Close()
+
+	var (
+		rdr     = bufio.NewReader(f)
+		end     int64
+		latest  int64
+		idxdata = []byte{}
+	)
+	for {
+		line, err := rdr.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("error reading event log: %s", err.Error())
 		}
+		e := &repo.Event{}
+		if err := json.Unmarshal(line[:len(line)-1], e); err != nil {
+			log.Debug(err.Error())
+			break
+		}
+		// keep the index ordered, the same way writes do
+		ts := e.Time.UnixNano()
+		if ts < latest {
+			ts = latest
+		}
+		latest = ts
+
+		rec := make([]byte, idxRecordSize)
+		binary.BigEndian.PutUint64(rec[:8], uint64(ts))
+		binary.BigEndian.PutUint64(rec[8:], uint64(end))
+		idxdata = append(idxdata, rec...)
+		end += int64(len(line))
+	}
+
+	if fi, err := f.Stat(); err == nil && fi.Size() > end {
+		log.Infof("dropping incomplete event log record")
+		if err := os.Truncate(ql.filepath(ql.file), end); err != nil {
+			return err
+		}
+	}
+	if len(idxdata) > 0 {
+		log.Infof("rebuilt event log index of %d events", len(idxdata)/idxRecordSize)
+	}
+	if err := ioutil.WriteFile(ql.filepath(ql.index), idxdata, os.ModePerm); err != nil {
 		log.Debug(err.Error())
-		return ds, fmt.Errorf("error loading logs: %s", err.Error())
+		return fmt.Errorf("error writing event log index: %s", err.Error())
 	}
+	return nil
+}
 
-	if err := json.Unmarshal(data, &ds); err != nil {
+// migrateJSONEvents performs a one-time import of events stored in a legacy
+// json array file. The file is renamed once import completes, leaving a copy
+// of the original data in place
+func (ql *EventLog) migrateJSONEvents(legacy File) error {
+	path := ql.filepath(legacy)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
 		log.Debug(err.Error())
-		return ds, fmt.Errorf("error unmarshaling logs: %s", err.Error())
+		return fmt.Errorf("error loading logs: %s", err.Error())
+	}
+
+	events := []*repo.Event{}
+	if err := json.Unmarshal(data, &events); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error unmarshaling logs: %s", err.Error())
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+
+	// a non-empty index means a previous migration was interrupted after
+	// events were written, but before the legacy file was moved
+	idx, err := ql.openIndex()
+	if err != nil {
+		return err
+	}
+	count, err := indexLen(idx)
+	idx.Close()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 && len(events) > 0 {
+		if err := ql.appendEvents(events...); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("migrated %d events to event log", len(events))
+	return os.Rename(path, path+".migrated")
+}
+
+func (ql *EventLog) openIndex() (*os.File, error) {
+	f, err := os.OpenFile(ql.filepath(ql.index), os.O_RDONLY|os.O_CREATE, os.ModePerm)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error opening event log index: %s", err.Error())
+	}
+	return f, nil
+}
+
+// indexLen gives the number of complete records in an index file
+func indexLen(idx *os.File) (int, error) {
+	fi, err := idx.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return int(fi.Size() / idxRecordSize), nil
+}
+
+// readIndexRange reads index records [start, stop), returning event offsets
+// & unix nanosecond timestamps
+func readIndexRange(idx *os.File, start, stop int) (offsets, times []int64, err error) {
+	if stop <= start {
+		return []int64{}, []int64{}, nil
+	}
+
+	buf := make([]byte, (stop-start)*idxRecordSize)
+	if _, err = idx.ReadAt(buf, int64(start*idxRecordSize)); err != nil {
+		log.Debug(err.Error())
+		return nil, nil, fmt.Errorf("error reading event log index: %s", err.Error())
+	}
+
+	offsets = make([]int64, stop-start)
+	times = make([]int64, stop-start)
+	for i := range offsets {
+		rec := buf[i*idxRecordSize : (i+1)*idxRecordSize]
+		times[i] = int64(binary.BigEndian.Uint64(rec[:8]))
+		offsets[i] = int64(binary.BigEndian.Uint64(rec[8:]))
+	}
+	return offsets, times, nil
+}
+
+// readLineAt reads a single newline-terminated record starting at offset,
+// returning the record without the trailing newline
+func readLineAt(f *os.File, offset int64) ([]byte, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	rdr := bufio.NewReader(io.NewSectionReader(f, offset, fi.Size()-offset))
+	line, err := rdr.ReadBytes('\n')
+	if err != nil {
+		return nil, err
 	}
-	return ds, nil
+	return line[:len(line)-1], nil
 }
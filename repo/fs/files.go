@@ -42,7 +42,8 @@ const (
 	FileConfig
 	// FileDatasets holds the list of datasets
 	FileDatasets
-	// FileEventLogs is a log of all queries in order they're run
+	// FileEventLogs is a legacy json array of all events, superseded
+	// by FileEvents
 	FileEventLogs
 	// FileRefstore is a file for the user's local namespace
 	FileRefstore
@@ -55,6 +56,20 @@ const (
 	FileSearchIndex
 	// FileChangeRequests is a file of change requests
 	FileChangeRequests
+	// FileRefstoreDB is a key-value database of the user's local namespace
+	FileRefstoreDB
+	// FileEvents is an append-only log of all events in order they occur
+	FileEvents
+	// FileEventsIndex is an index of timestamps & offsets into FileEvents
+	FileEventsIndex
+	// FileBackups is a directory of repo copies made before migrating
+	FileBackups
+	// FileTags holds named references to dataset versions
+	FileTags
+	// FileBranches holds the heads of named lines of dataset history
+	FileBranches
+	// FileSources holds cache validators of dataset source urls
+	FileSources
 )
 
 var paths = map[File]string{
@@ -69,6 +84,13 @@ var paths = map[File]string{
 	FileAnalytics:      "/analytics.json",
 	FileSearchIndex:    "/index.bleve",
 	FileChangeRequests: "/change_requests.json",
+	FileRefstoreDB:     "/ds_refs.leveldb",
+	FileEvents:         "/events.ndjson",
+	FileEventsIndex:    "/events.idx",
+	FileBackups:        "/backups",
+	FileTags:           "/tags.json",
+	FileBranches:       "/branches.json",
+	FileSources:        "/sources.json",
 }
 
 // Filepath gives the relative filepath to a repofile
@@ -0,0 +1,596 @@
+package fsrepo
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/multiformats/go-multihash"
+	"github.com/qri-io/cafs"
+)
+
+// FilestorePrefix is the path prefix for all keys in a Filestore
+const FilestorePrefix = "fs"
+
+// Filestore is a content-addressed file store kept in a directory on the
+// local filesystem, for running qri without IPFS. Files are stored by the
+// base58-encoded sha256 multihash of their contents. Directories are stored
+// as a json list of links to their children, and resolve paths below them,
+// so "/fs/QmDir/dataset.json" works as expected.
+//
+// Content is either a root (anything added directly, not as part of a
+// directory) or reachable from a root. Deleting a root removes any content
+// no other root depends on. Pinned content can't be deleted until unpinned.
+//
+// Filestore implements the cafs.Filestore, cafs.Pinner & cafs.Fetcher
+// interfaces. It's safe for concurrent use within a single process
+type Filestore struct {
+	path string
+	lock sync.Mutex
+	// hashes held by open adders, which sweep treats as roots
+	pending map[string]int
+}
+
+// fsLink is a directory entry
+type fsLink struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+	Dir  bool   `json:"dir,omitempty"`
+}
+
+// NewFilestore creates a Filestore at path, creating directories
+// as needed
+func NewFilestore(path string) (*Filestore, error) {
+	for _, dir := range []string{"blocks", "dirs", "roots", "pins"} {
+		if err := os.MkdirAll(filepath.Join(path, dir), os.ModePerm); err != nil {
+			log.Debug(err.Error())
+			return nil, fmt.Errorf("error creating filestore: %s", err.Error())
+		}
+	}
+	return &Filestore{path: path, pending: map[string]int{}}, nil
+}
+
+// PathPrefix returns the prefix on paths in the store
+func (fs *Filestore) PathPrefix() string {
+	return FilestorePrefix
+}
+
+// Put adds a file to the store. Directories are added along with all
+// of their contents
+func (fs *Filestore) Put(file cafs.File, pin bool) (datastore.Key, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	hash, _, err := fs.write(file, nil)
+	if err != nil {
+		return datastore.NewKey(""), err
+	}
+	if err := fs.mark("roots", hash); err != nil {
+		return datastore.NewKey(""), err
+	}
+	if pin {
+		if err := fs.mark("pins", hash); err != nil {
+			return datastore.NewKey(""), err
+		}
+	}
+	return fs.key(hash), nil
+}
+
+// Get fetches a file from the store
+func (fs *Filestore) Get(key datastore.Key) (cafs.File, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	hash, dir, err := fs.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	return fs.open(key.String(), hash, dir)
+}
+
+// Fetch gets a file from the store. Filestores have no network, so only
+// local content can be fetched
+func (fs *Filestore) Fetch(source cafs.Source, key datastore.Key) (cafs.File, error) {
+	return fs.Get(key)
+}
+
+// Has checks for the presence of a key in the store
+func (fs *Filestore) Has(key datastore.Key) (bool, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	if _, _, err := fs.resolve(key); err == datastore.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete removes a root from the store, along with any content no other
+// root depends on. Keys below a root can't be deleted on their own
+func (fs *Filestore) Delete(key datastore.Key) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	hash, err := fs.hash(key)
+	if err != nil {
+		return err
+	}
+	if fs.marked("pins", hash) {
+		return fmt.Errorf("cannot delete pinned content: %s", key.String())
+	}
+	if !fs.marked("roots", hash) {
+		if _, _, err := fs.resolve(key); err != nil {
+			return err
+		}
+		return fmt.Errorf("can only delete content added directly to the store: %s", key.String())
+	}
+
+	if err := os.Remove(fs.markPath("roots", hash)); err != nil {
+		return err
+	}
+	return fs.sweep()
+}
+
+// Pin marks content for retention. Only roots can be pinned, pinning
+// is always recursive
+func (fs *Filestore) Pin(key datastore.Key, recursive bool) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	hash, _, err := fs.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := fs.mark("roots", hash); err != nil {
+		return err
+	}
+	return fs.mark("pins", hash)
+}
+
+// Unpin removes a pin, allowing content to be deleted
+func (fs *Filestore) Unpin(key datastore.Key, recursive bool) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	hash, err := fs.hash(key)
+	if err != nil {
+		return err
+	}
+	if !fs.marked("pins", hash) {
+		return fmt.Errorf("not pinned: %s", key.String())
+	}
+	return os.Remove(fs.markPath("pins", hash))
+}
+
+// IsPinned reports whether content is pinned, implementing repo.PinChecker
+func (fs *Filestore) IsPinned(key datastore.Key) (bool, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	hash, err := fs.hash(key)
+	if err != nil {
+		return false, err
+	}
+	return fs.marked("pins", hash), nil
+}
+
+// Keys lists the roots held by the store, implementing repo.KeyLister
+func (fs *Filestore) Keys() ([]datastore.Key, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	hashes, err := fs.list("roots")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]datastore.Key, len(hashes))
+	for i, h := range hashes {
+		keys[i] = fs.key(h)
+	}
+	return keys, nil
+}
+
+// NewAdder creates an adder for adding many files to the store at once.
+// Setting wrap places all files added in a single directory, which is the
+// final file reported on the Added channel
+func (fs *Filestore) NewAdder(pin, wrap bool) (cafs.Adder, error) {
+	return &fsAdder{
+		fs:    fs,
+		pin:   pin,
+		wrap:  wrap,
+		added: make(chan cafs.AddedFile, 16),
+		links: []fsLink{},
+	}, nil
+}
+
+// key gives the store key for a hash
+func (fs *Filestore) key(hash string) datastore.Key {
+	return datastore.NewKey("/" + FilestorePrefix + "/" + hash)
+}
+
+// hash extracts the hash from a key that points to the top of a file,
+// rejecting paths to files within directories
+func (fs *Filestore) hash(key datastore.Key) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(key.String(), "/"), "/")
+	if len(parts) != 2 || parts[0] != FilestorePrefix || parts[1] == "" {
+		return "", fmt.Errorf("invalid filestore key: %s", key.String())
+	}
+	return parts[1], nil
+}
+
+// resolve walks a key to the hash of the file it points to
+func (fs *Filestore) resolve(key datastore.Key) (hash string, dir bool, err error) {
+	parts := strings.Split(strings.TrimPrefix(key.String(), "/"), "/")
+	if len(parts) < 2 || parts[0] != FilestorePrefix || parts[1] == "" {
+		return "", false, datastore.ErrNotFound
+	}
+
+	hash = parts[1]
+	if _, err := os.Stat(fs.blockPath(hash)); err == nil {
+		dir = false
+	} else if _, err := os.Stat(fs.dirPath(hash)); err == nil {
+		dir = true
+	} else {
+		return "", false, datastore.ErrNotFound
+	}
+
+	for _, name := range parts[2:] {
+		if name == "" {
+			continue
+		}
+		if !dir {
+			return "", false, datastore.ErrNotFound
+		}
+		links, err := fs.readDir(hash)
+		if err != nil {
+			return "", false, err
+		}
+		found := false
+		for _, l := range links {
+			if l.Name == name {
+				hash, dir, found = l.Hash, l.Dir, true
+				break
+			}
+		}
+		if !found {
+			return "", false, datastore.ErrNotFound
+		}
+	}
+	return hash, dir, nil
+}
+
+// open loads a file or directory. Directory children are opened as well
+func (fs *Filestore) open(path, hash string, dir bool) (cafs.File, error) {
+	if !dir {
+		f, err := os.Open(fs.blockPath(hash))
+		if err != nil {
+			return nil, err
+		}
+		return cafs.NewMemfileReader(path, f), nil
+	}
+
+	links, err := fs.readDir(hash)
+	if err != nil {
+		return nil, err
+	}
+	d := cafs.NewMemdir(path)
+	for _, l := range links {
+		child, err := fs.open(path+"/"+l.Name, l.Hash, l.Dir)
+		if err != nil {
+			return nil, err
+		}
+		d.AddChildren(child)
+	}
+	return d, nil
+}
+
+// write stores a file, returning its hash. Directories are written
+// recursively. Each file written is reported to added if it isn't nil
+func (fs *Filestore) write(file cafs.File, added func(name, hash string, size int64)) (hash string, dir bool, err error) {
+	if !file.IsDirectory() {
+		hash, size, err := fs.writeBlockFrom(file)
+		if err != nil {
+			return "", false, err
+		}
+		if added != nil {
+			added(file.FullPath(), hash, size)
+		}
+		return hash, false, nil
+	}
+
+	links := []fsLink{}
+	for {
+		child, err := file.NextFile()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", false, err
+		}
+		h, d, err := fs.write(child, added)
+		if err != nil {
+			return "", false, err
+		}
+		links = append(links, fsLink{Name: child.FileName(), Hash: h, Dir: d})
+	}
+
+	if hash, err = fs.writeDir(links); err != nil {
+		return "", false, err
+	}
+	if added != nil {
+		added(file.FullPath(), hash, 0)
+	}
+	return hash, true, nil
+}
+
+// writeDir stores a directory listing, returning its hash
+func (fs *Filestore) writeDir(links []fsLink) (string, error) {
+	sort.Slice(links, func(i, j int) bool { return links[i].Name < links[j].Name })
+	data, err := json.Marshal(links)
+	if err != nil {
+		return "", err
+	}
+	return fs.writeBlock(fs.dirPath, data)
+}
+
+// writeBlock hashes & stores data, skipping the write if the content
+// already exists. Data is written to a temp file & moved into place so
+// readers never see partial blocks
+func (fs *Filestore) writeBlock(pathFor func(string) string, data []byte) (string, error) {
+	sum, err := multihash.Sum(data, multihash.SHA2_256, -1)
+	if err != nil {
+		return "", err
+	}
+	hash := sum.B58String()
+	path := pathFor(hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return hash, os.Rename(tmp.Name(), path)
+}
+
+// writeBlockFrom streams a file into a block, returning its hash & size.
+// Content is hashed as it's written to a temp file, which is then moved
+// into place, so files are never held in memory
+func (fs *Filestore) writeBlockFrom(r io.Reader) (string, int64, error) {
+	tmp, err := ioutil.TempFile(filepath.Join(fs.path, "blocks"), ".tmp-")
+	if err != nil {
+		return "", 0, err
+	}
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", 0, fmt.Errorf("error reading file: %s", err.Error())
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, err
+	}
+
+	sum, err := multihash.Encode(h.Sum(nil), multihash.SHA2_256)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, err
+	}
+	hash := multihash.Multihash(sum).B58String()
+	if _, err := os.Stat(fs.blockPath(hash)); err == nil {
+		os.Remove(tmp.Name())
+		return hash, size, nil
+	}
+	return hash, size, os.Rename(tmp.Name(), fs.blockPath(hash))
+}
+
+func (fs *Filestore) readDir(hash string) ([]fsLink, error) {
+	data, err := ioutil.ReadFile(fs.dirPath(hash))
+	if err != nil {
+		return nil, err
+	}
+	links := []fsLink{}
+	if err := json.Unmarshal(data, &links); err != nil {
+		return nil, fmt.Errorf("error decoding directory %s: %s", hash, err.Error())
+	}
+	return links, nil
+}
+
+// sweep removes all blocks & directories that aren't reachable from a root
+func (fs *Filestore) sweep() error {
+	roots, err := fs.list("roots")
+	if err != nil {
+		return err
+	}
+
+	live := map[string]bool{}
+	var mark func(hash string) error
+	mark = func(hash string) error {
+		if live[hash] {
+			return nil
+		}
+		live[hash] = true
+		if _, err := os.Stat(fs.dirPath(hash)); err != nil {
+			return nil
+		}
+		links, err := fs.readDir(hash)
+		if err != nil {
+			return err
+		}
+		for _, l := range links {
+			if err := mark(l.Hash); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for h := range fs.pending {
+		roots = append(roots, h)
+	}
+	for _, h := range roots {
+		if err := mark(h); err != nil {
+			return err
+		}
+	}
+
+	for _, kind := range []string{"blocks", "dirs"} {
+		hashes, err := fs.list(kind)
+		if err != nil {
+			return err
+		}
+		for _, h := range hashes {
+			if !live[h] {
+				if err := os.Remove(filepath.Join(fs.path, kind, h)); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// list gives the names of all entries in a store directory, skipping
+// any temp files left by interrupted writes
+func (fs *Filestore) list(kind string) ([]string, error) {
+	f, err := os.Open(filepath.Join(fs.path, kind))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(names))
+	for _, n := range names {
+		if !strings.HasPrefix(n, ".") {
+			hashes = append(hashes, n)
+		}
+	}
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+func (fs *Filestore) blockPath(hash string) string {
+	return filepath.Join(fs.path, "blocks", hash)
+}
+
+func (fs *Filestore) dirPath(hash string) string {
+	return filepath.Join(fs.path, "dirs", hash)
+}
+
+func (fs *Filestore) markPath(kind, hash string) string {
+	return filepath.Join(fs.path, kind, hash)
+}
+
+func (fs *Filestore) mark(kind, hash string) error {
+	return ioutil.WriteFile(fs.markPath(kind, hash), []byte{}, os.ModePerm)
+}
+
+func (fs *Filestore) marked(kind, hash string) bool {
+	_, err := os.Stat(fs.markPath(kind, hash))
+	return err == nil
+}
+
+// fsAdder implements the cafs.Adder interface for Filestore
+type fsAdder struct {
+	fs    *Filestore
+	pin   bool
+	wrap  bool
+	added chan cafs.AddedFile
+	links []fsLink
+}
+
+// AddFile adds a file or directory to the store
+func (a *fsAdder) AddFile(f cafs.File) error {
+	a.fs.lock.Lock()
+	defer a.fs.lock.Unlock()
+
+	hash, dir, err := a.fs.write(f, func(name, hash string, size int64) {
+		a.added <- cafs.AddedFile{
+			Path:  a.fs.key(hash),
+			Name:  name,
+			Hash:  hash,
+			Bytes: size,
+		}
+	})
+	if err != nil {
+		return err
+	}
+	a.links = append(a.links, fsLink{Name: f.FileName(), Hash: hash, Dir: dir})
+
+	if !a.wrap || dir {
+		return a.root(hash)
+	}
+	// hold on to wrapped files until they're linked to a root on Close
+	a.fs.pending[hash]++
+	return nil
+}
+
+// Added gives a channel of files as they're added
+func (a *fsAdder) Added() chan cafs.AddedFile {
+	return a.added
+}
+
+// Close finishes adding, wrapping added files in a directory if
+// requested. Close must be called to close the Added channel
+func (a *fsAdder) Close() error {
+	defer close(a.added)
+
+	a.fs.lock.Lock()
+	defer a.fs.lock.Unlock()
+
+	for _, l := range a.links {
+		if a.fs.pending[l.Hash]--; a.fs.pending[l.Hash] <= 0 {
+			delete(a.fs.pending, l.Hash)
+		}
+	}
+
+	// a single directory doesn't need a wrapper
+	if !a.wrap || len(a.links) == 0 || (len(a.links) == 1 && a.links[0].Dir) {
+		return nil
+	}
+
+	hash, err := a.fs.writeDir(a.links)
+	if err != nil {
+		return err
+	}
+	if err := a.root(hash); err != nil {
+		return err
+	}
+	a.added <- cafs.AddedFile{Path: a.fs.key(hash), Name: "", Hash: hash}
+	return nil
+}
+
+// root marks a hash as a root, pinning it if the adder pins
+func (a *fsAdder) root(hash string) error {
+	if err := a.fs.mark("roots", hash); err != nil {
+		return err
+	}
+	if a.pin {
+		return a.fs.mark("pins", hash)
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package fsrepo
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 
 	golog "github.com/ipfs/go-log"
@@ -31,20 +33,61 @@ type Repo struct {
 	store cafs.Filestore
 	graph map[string]*dsgraph.Node
 
-	Refstore
-	EventLog
+	repo.Refstore
+	*EventLog
 
-	profiles ProfileStore
-	index    search.Index
+	profiles       ProfileStore
+	changeRequests *ChangeRequestStore
+	analytics      *Analytics
+	tags           *TagStore
+	branches       *BranchStore
+	sources        *SourceCache
+	index          search.Index
+
+	lock *Lockfile
 }
 
-// NewRepo creates a new file-based repository
-func NewRepo(store cafs.Filestore, cfg *config.Profile, base string) (repo.Repo, error) {
+// NewRepo creates a new file-based repository. Options configure the repo,
+// with the default of json-file backed references. NewRepo takes an advisory
+// lock on the repo directory that's held until Close is called, returning a
+// LockedError if another process holds a conflicting lock. Repos written by
+// older versions are migrated to CurrentVersion when opened for writing
+func NewRepo(store cafs.Filestore, cfg *config.Profile, base string, opts ...func(o *config.Repo)) (_ repo.Repo, err error) {
+	rcfg := config.DefaultRepo()
+	for _, opt := range opts {
+		opt(rcfg)
+	}
+
 	if err := os.MkdirAll(base, os.ModePerm); err != nil {
 		return nil, err
 	}
 	bp := basepath(base)
 
+	mode := LockExclusive
+	if rcfg.ReadOnly {
+		mode = LockShared
+	}
+	lock, err := AcquireLock(bp, mode)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			lock.Release()
+		}
+	}()
+
+	if rcfg.ReadOnly {
+		pending, err := PendingMigrations(base)
+		if err != nil {
+			return nil, err
+		} else if len(pending) > 0 {
+			return nil, fmt.Errorf("repo needs to be migrated to version %d, please run `qri repo migrate`", CurrentVersion)
+		}
+	} else if _, err = migrate(bp); err != nil {
+		return nil, err
+	}
+
 	p, err := cfg.DecodeProfile()
 	if err != nil {
 		return nil, err
@@ -61,15 +104,32 @@ func NewRepo(store cafs.Filestore, cfg *config.Profile, base string) (repo.Repo,
 		store:    store,
 		basepath: bp,
 
-		Refstore: Refstore{basepath: bp, store: store, file: FileRefstore},
-		EventLog: NewEventLog(base, FileEventLogs, store),
+		profiles:       NewProfileStore(bp),
+		changeRequests: NewChangeRequestStore(bp),
+		analytics:      NewAnalytics(bp, rcfg.ReadOnly),
+		tags:           NewTagStore(bp),
+		branches:       NewBranchStore(bp),
+		sources:        NewSourceCache(bp),
+		lock:           lock,
+	}
 
-		profiles: NewProfileStore(bp),
+	if r.EventLog, err = NewEventLog(base, FileEvents, FileEventsIndex, store, rcfg.ReadOnly); err != nil {
+		return nil, err
 	}
 
 	if index, err := search.LoadIndex(bp.filepath(FileSearchIndex)); err == nil {
 		r.index = index
-		r.Refstore.index = index
+	}
+
+	switch rcfg.Type {
+	case "fs":
+		r.Refstore = Refstore{basepath: bp, store: store, file: FileRefstore, index: r.index}
+	case "fs_leveldb":
+		if r.Refstore, err = NewLevelDBRefstore(bp, store, r.index, rcfg.ReadOnly); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown repo type: %s", rcfg.Type)
 	}
 
 	// TODO - this is racey.
@@ -77,8 +137,11 @@ func NewRepo(store cafs.Filestore, cfg *config.Profile, base string) (repo.Repo,
 	// 	r.graph, _ = repo.Graph(r)
 	// }()
 
-	if err = r.Profiles().PutProfile(p); err != nil {
-		return nil, err
+	// shared lock holders can't write
+	if !rcfg.ReadOnly {
+		if err = r.Profiles().PutProfile(p); err != nil {
+			return nil, err
+		}
 	}
 
 	return r, nil
@@ -152,7 +215,7 @@ func (r *Repo) Search(p repo.SearchParams) ([]repo.DatasetRef, error) {
 
 // UpdateSearchIndex refreshes this repos search index
 func (r *Repo) UpdateSearchIndex(store cafs.Filestore) error {
-	return search.IndexRepo(r, r.index)
+	return search.IndexRepo(context.Background(), r, r.index)
 }
 
 // Profiles returns this repo's Peers implementation
@@ -160,6 +223,52 @@ func (r *Repo) Profiles() profile.Store {
 	return r.profiles
 }
 
+// ChangeRequests returns this repo's ChangeRequestStore implementation
+func (r *Repo) ChangeRequests() repo.ChangeRequestStore {
+	return r.changeRequests
+}
+
+// Analytics returns this repo's Analytics implementation
+func (r *Repo) Analytics() repo.Analytics {
+	return r.analytics
+}
+
+// Tags returns this repo's TagStore implementation
+func (r *Repo) Tags() repo.TagStore {
+	return r.tags
+}
+
+// Branches returns this repo's BranchStore implementation
+func (r *Repo) Branches() repo.BranchStore {
+	return r.branches
+}
+
+// SourceValidators gets the validators of a dataset's source, implementing
+// the repo.SourceCache interface
+func (r *Repo) SourceValidators(ref repo.DatasetRef) (repo.SourceValidators, error) {
+	return r.sources.SourceValidators(ref)
+}
+
+// PutSourceValidators sets the validators of a dataset's source,
+// implementing the repo.SourceCache interface
+func (r *Repo) PutSourceValidators(ref repo.DatasetRef, v repo.SourceValidators) error {
+	return r.sources.PutSourceValidators(ref, v)
+}
+
+// Close releases resources held by this repo, including the repo lock.
+// The repo must not be used after calling Close
+func (r *Repo) Close() (err error) {
+	if c, ok := r.Refstore.(io.Closer); ok {
+		err = c.Close()
+	}
+	if r.lock != nil {
+		if lerr := r.lock.Release(); err == nil {
+			err = lerr
+		}
+	}
+	return err
+}
+
 // Destroy destroys this repository
 func (r *Repo) Destroy() error {
 	return os.RemoveAll(string(r.basepath))
@@ -1,13 +1,20 @@
 package fsrepo
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ipfs/go-datastore"
 	"github.com/qri-io/cafs"
 	"github.com/qri-io/qri/config"
 	"github.com/qri-io/qri/repo"
+	"github.com/qri-io/qri/repo/profile"
 	"github.com/qri-io/qri/repo/test"
 )
 
@@ -33,3 +40,865 @@ func TestRepo(t *testing.T) {
 		t.Errorf("error cleaning up after test: %s", err.Error())
 	}
 }
+
+func TestLevelDBRepo(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "qri_repo_leveldb_test")
+	t.Log(path)
+
+	rmf := func(t *testing.T) repo.Repo {
+		if err := os.RemoveAll(path); err != nil {
+			t.Errorf("error removing files: %s", err.Error())
+		}
+
+		r, err := NewRepo(cafs.NewMapstore(), config.DefaultProfile(), path, func(c *config.Repo) {
+			c.Type = "fs_leveldb"
+		})
+		if err != nil {
+			t.Errorf("error creating repo: %s", err.Error())
+		}
+		return r
+	}
+
+	test.RunRepoTests(t, rmf)
+
+	if err := os.RemoveAll(path); err != nil {
+		t.Errorf("error cleaning up after test: %s", err.Error())
+	}
+}
+
+func TestLevelDBRefstore(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "qri_leveldb_refstore_test")
+	if err := os.RemoveAll(path); err != nil {
+		t.Errorf("error removing files: %s", err.Error())
+		return
+	}
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		t.Errorf("error creating test dir: %s", err.Error())
+		return
+	}
+	defer os.RemoveAll(path)
+	bp := basepath(path)
+
+	pid := profile.IDB58MustDecode("QmZePf5LeXow3RW5U1AgEiNbW46YnRGhZ7HPvm1UmPFPwt")
+	a := repo.DatasetRef{Peername: "peer", ProfileID: pid, Name: "a", Path: "/map/QmTjzPnEv6PpibPyR5GjvvG7MPSmZUBbsQ6zmCnfNydHgu"}
+	b := repo.DatasetRef{Peername: "peer", ProfileID: pid, Name: "b", Path: "/map/QmXTF2ai5o2yvECdeEHx2z8z8j2kt7dAFGHTuqwjCvuEsK"}
+
+	// write a json refstore to confirm migration
+	legacy := Refstore{basepath: bp, file: FileRefstore}
+	if err := legacy.save([]repo.DatasetRef{a}); err != nil {
+		t.Errorf("error writing json refstore: %s", err.Error())
+		return
+	}
+
+	rs, err := NewLevelDBRefstore(bp, nil, nil, false)
+	if err != nil {
+		t.Errorf("error creating refstore: %s", err.Error())
+		return
+	}
+	defer rs.Close()
+
+	if _, err := os.Stat(bp.filepath(FileRefstore)); !os.IsNotExist(err) {
+		t.Errorf("expected json refstore file to be moved after migration")
+	}
+
+	if err := rs.PutRef(b); err != nil {
+		t.Errorf("error putting ref: %s", err.Error())
+		return
+	}
+	if err := rs.PutRef(repo.DatasetRef{Peername: "peer", ProfileID: pid, Name: "b", Path: a.Path}); err != repo.ErrNameTaken {
+		t.Errorf("expected putting a taken name to error with ErrNameTaken, got: %s", err)
+	}
+
+	cases := []struct {
+		get, expect repo.DatasetRef
+		err         error
+	}{
+		{repo.DatasetRef{Peername: "peer", Name: "a"}, a, nil},
+		{repo.DatasetRef{ProfileID: pid, Name: "b"}, b, nil},
+		{repo.DatasetRef{Path: b.Path}, b, nil},
+		{repo.DatasetRef{Peername: "peer", Name: "c"}, repo.DatasetRef{}, repo.ErrNotFound},
+	}
+
+	for i, c := range cases {
+		got, err := rs.GetRef(c.get)
+		if err != c.err {
+			t.Errorf("case %d error mismatch. expected: %s, got: %s", i, c.err, err)
+			continue
+		}
+		if !c.expect.Equal(got) {
+			t.Errorf("case %d result mismatch. expected: %s, got: %s", i, c.expect, got)
+		}
+	}
+
+	refs, err := rs.References(10, 0)
+	if err != nil {
+		t.Errorf("error listing references: %s", err.Error())
+		return
+	}
+	if len(refs) != 2 || refs[0].Name != "a" || refs[1].Name != "b" {
+		t.Errorf("expected references to be [a, b], got: %v", refs)
+	}
+
+	if err := rs.DeleteRef(repo.DatasetRef{Path: a.Path}); err != nil {
+		t.Errorf("error deleting ref: %s", err.Error())
+		return
+	}
+	if _, err := rs.GetRef(repo.DatasetRef{ProfileID: pid, Name: "a"}); err != repo.ErrNotFound {
+		t.Errorf("expected deleted ref to be removed from all indexes, got: %s", err)
+	}
+
+	count, err := rs.RefCount()
+	if err != nil {
+		t.Errorf("error counting refs: %s", err.Error())
+		return
+	}
+	if count != 1 {
+		t.Errorf("expected refcount of 1, got: %d", count)
+	}
+
+	if err := rs.DeleteRef(repo.DatasetRef{Peername: "peer", Name: "a"}); err != nil {
+		t.Errorf("expected deleting a missing ref to do nothing, got: %s", err)
+	}
+
+	// any number of names can refer to the same path
+	c := repo.DatasetRef{Peername: "peer", ProfileID: pid, Name: "c", Path: b.Path}
+	if err := rs.PutRef(c); err != nil {
+		t.Errorf("error putting ref: %s", err.Error())
+		return
+	}
+	if got, err := rs.GetRef(repo.DatasetRef{Path: b.Path}); err != nil || !got.Equal(b) {
+		t.Errorf("expected getting a shared path to give the first name. got: %s, %v", got, err)
+	}
+	if got, err := rs.GetRef(repo.DatasetRef{Peername: "peer", Name: "c", Path: b.Path}); err != nil || !got.Equal(c) {
+		t.Errorf("expected getting a shared path by name to give that name. got: %s, %v", got, err)
+	}
+	if err := rs.DeleteRef(b); err != nil {
+		t.Errorf("error deleting ref: %s", err.Error())
+		return
+	}
+	if got, err := rs.GetRef(repo.DatasetRef{Path: b.Path}); err != nil || !got.Equal(c) {
+		t.Errorf("expected deleting one name of a path to keep the other. got: %s, %v", got, err)
+	}
+	if err := rs.DeleteRef(c); err != nil {
+		t.Errorf("error deleting ref: %s", err.Error())
+		return
+	}
+	if _, err := rs.GetRef(repo.DatasetRef{Path: b.Path}); err != repo.ErrNotFound {
+		t.Errorf("expected deleting every name of a path to remove it, got: %v", err)
+	}
+	if err := rs.PutRef(b); err != nil {
+		t.Errorf("error putting ref: %s", err.Error())
+		return
+	}
+
+	// any number of read-only refstores can share the database
+	if err := rs.Close(); err != nil {
+		t.Errorf("error closing refstore: %s", err.Error())
+		return
+	}
+	for i := 0; i < 2; i++ {
+		ro, err := NewLevelDBRefstore(bp, nil, nil, true)
+		if err != nil {
+			t.Errorf("error opening read-only refstore: %s", err.Error())
+			return
+		}
+		defer ro.Close()
+		if _, err := ro.GetRef(repo.DatasetRef{Peername: "peer", Name: "b"}); err != nil {
+			t.Errorf("error getting ref from read-only refstore: %s", err.Error())
+		}
+		if err := ro.PutRef(a); err == nil {
+			t.Errorf("expected putting a ref in a read-only refstore to error")
+		}
+	}
+}
+
+func TestEventLog(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "qri_event_log_test")
+	if err := os.RemoveAll(path); err != nil {
+		t.Errorf("error removing files: %s", err.Error())
+		return
+	}
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		t.Errorf("error creating test dir: %s", err.Error())
+		return
+	}
+	defer os.RemoveAll(path)
+	bp := basepath(path)
+
+	// write a legacy json events file to confirm migration
+	start := time.Now()
+	legacy := []*repo.Event{
+		{Time: start.Add(-time.Minute), Type: repo.ETDsRenamed, Ref: repo.DatasetRef{Name: "b"}},
+		{Time: start.Add(-time.Hour), Type: repo.ETDsCreated, Ref: repo.DatasetRef{Name: "a"}},
+	}
+	if err := bp.saveFile(legacy, FileEventLogs); err != nil {
+		t.Errorf("error writing legacy events: %s", err.Error())
+		return
+	}
+
+	el, err := NewEventLog(path, FileEvents, FileEventsIndex, nil, false)
+	if err != nil {
+		t.Errorf("error creating event log: %s", err.Error())
+		return
+	}
+	if err := el.migrateJSONEvents(FileEventLogs); err != nil {
+		t.Errorf("error migrating legacy events: %s", err.Error())
+		return
+	}
+
+	if err := el.LogEvent(repo.ETDsPinned, repo.DatasetRef{Name: "c"}); err != nil {
+		t.Errorf("error logging event: %s", err.Error())
+		return
+	}
+
+	events, err := el.Events(10, 0)
+	if err != nil {
+		t.Errorf("error reading events: %s", err.Error())
+		return
+	}
+	expect := []repo.EventType{repo.ETDsPinned, repo.ETDsRenamed, repo.ETDsCreated}
+	if len(events) != len(expect) {
+		t.Errorf("event count mismatch. expected: %d, got: %d", len(expect), len(events))
+		return
+	}
+	for i, et := range expect {
+		if events[i].Type != et {
+			t.Errorf("case %d eventType mismatch. expected: %s, got: %s", i, et, events[i].Type)
+		}
+	}
+
+	events, err = el.Events(1, 1)
+	if err != nil {
+		t.Errorf("error reading events: %s", err.Error())
+		return
+	}
+	if len(events) != 1 || events[0].Type != repo.ETDsRenamed {
+		t.Errorf("expected offset read to return renamed event, got: %v", events)
+	}
+
+	events, err = el.EventsSince(start.Add(-time.Minute * 30))
+	if err != nil {
+		t.Errorf("error reading events since: %s", err.Error())
+		return
+	}
+	if len(events) != 2 || events[0].Type != repo.ETDsRenamed || events[1].Type != repo.ETDsPinned {
+		t.Errorf("expected events since to return [renamed, pinned], got: %v", events)
+	}
+
+	// simulate a crash mid-write, leaving partial data & index records
+	f, err := os.OpenFile(bp.filepath(FileEvents), os.O_WRONLY|os.O_APPEND, os.ModePerm)
+	if err != nil {
+		t.Errorf("error opening event log file: %s", err.Error())
+		return
+	}
+	f.Write([]byte(`{"Time":"20`))
+	f.Close()
+	idx, err := os.OpenFile(bp.filepath(FileEventsIndex), os.O_WRONLY|os.O_APPEND, os.ModePerm)
+	if err != nil {
+		t.Errorf("error opening event index file: %s", err.Error())
+		return
+	}
+	idx.Write([]byte{0, 1, 2})
+	idx.Close()
+
+	el, err = NewEventLog(path, FileEvents, FileEventsIndex, nil, false)
+	if err != nil {
+		t.Errorf("error re-opening event log: %s", err.Error())
+		return
+	}
+	if err := el.LogEvent(repo.ETDsUnpinned, repo.DatasetRef{Name: "c"}); err != nil {
+		t.Errorf("error logging event: %s", err.Error())
+		return
+	}
+	events, err = el.Events(10, 0)
+	if err != nil {
+		t.Errorf("error reading events after recovery: %s", err.Error())
+		return
+	}
+	if len(events) != 4 || events[0].Type != repo.ETDsUnpinned {
+		t.Errorf("expected 4 events after recovery, newest unpinned. got: %v", events)
+	}
+
+	now := time.Now()
+	badImports := [][]*repo.Event{
+		{
+			{Time: now.Add(time.Minute * 2), Type: repo.ETDsPinned, Ref: repo.DatasetRef{Name: "d"}},
+			{Time: now.Add(time.Minute), Type: repo.ETDsPinned, Ref: repo.DatasetRef{Name: "e"}},
+		},
+		{
+			{Time: start.Add(-time.Hour * 2), Type: repo.ETDsPinned, Ref: repo.DatasetRef{Name: "d"}},
+		},
+	}
+	for i, imp := range badImports {
+		if err := el.ImportEvents(imp); err == nil {
+			t.Errorf("case %d: expected import to error", i)
+		}
+	}
+	imported := []*repo.Event{
+		{Time: now.Add(time.Minute), Type: repo.ETDsPinned, Ref: repo.DatasetRef{Name: "d"}},
+		{Time: now.Add(time.Minute * 2), Type: repo.ETDsPinned, Ref: repo.DatasetRef{Name: "e"}},
+	}
+	if err := el.ImportEvents(imported); err != nil {
+		t.Errorf("error importing events: %s", err.Error())
+		return
+	}
+	events, err = el.Events(10, 0)
+	if err != nil {
+		t.Errorf("error reading events: %s", err.Error())
+		return
+	}
+	if len(events) != 6 || events[0].Ref.Name != "e" || !events[0].Time.Equal(imported[1].Time) {
+		t.Errorf("expected imported events to keep their timestamps, newest first. got: %v", events)
+	}
+
+	// read-only logs leave partial writes for the lock holder to recover
+	f, err = os.OpenFile(bp.filepath(FileEvents), os.O_WRONLY|os.O_APPEND, os.ModePerm)
+	if err != nil {
+		t.Errorf("error opening event log file: %s", err.Error())
+		return
+	}
+	f.Write([]byte(`{"Time":"20`))
+	f.Close()
+	before, err := os.Stat(bp.filepath(FileEvents))
+	if err != nil {
+		t.Errorf("error reading event log file: %s", err.Error())
+		return
+	}
+	if el, err = NewEventLog(path, FileEvents, FileEventsIndex, nil, true); err != nil {
+		t.Errorf("error opening read-only event log: %s", err.Error())
+		return
+	}
+	if after, err := os.Stat(bp.filepath(FileEvents)); err != nil || after.Size() != before.Size() {
+		t.Errorf("expected read-only event log not to truncate the log file")
+	}
+	if events, err = el.Events(10, 0); err != nil || len(events) != 6 {
+		t.Errorf("expected 6 events from read-only event log. got: %d, %v", len(events), err)
+	}
+	if err := el.LogEvent(repo.ETDsPinned, repo.DatasetRef{Name: "d"}); err != repo.ErrReadOnly {
+		t.Errorf("expected read-only event log to refuse writes. got: %v", err)
+	}
+
+	// events logged after the clock steps back stay readable by time
+	if el, err = NewEventLog(path, FileEvents, FileEventsIndex, nil, false); err != nil {
+		t.Errorf("error re-opening event log: %s", err.Error())
+		return
+	}
+	if err := el.appendEvents(&repo.Event{Time: start.Add(-time.Hour * 3), Type: repo.ETDsUnpinned, Ref: repo.DatasetRef{Name: "e"}}); err != nil {
+		t.Errorf("error logging event: %s", err.Error())
+		return
+	}
+	events, err = el.EventsSince(now.Add(time.Minute + time.Second))
+	if err != nil {
+		t.Errorf("error reading events since: %s", err.Error())
+		return
+	}
+	if len(events) != 2 || events[0].Ref.Name != "e" || events[1].Type != repo.ETDsUnpinned {
+		t.Errorf("expected events since to include an event logged with an earlier time. got: %v", events)
+	}
+
+	// a lost index is rebuilt from the log
+	if err := os.Remove(bp.filepath(FileEventsIndex)); err != nil {
+		t.Errorf("error removing event index: %s", err.Error())
+		return
+	}
+	if el, err = NewEventLog(path, FileEvents, FileEventsIndex, nil, false); err != nil {
+		t.Errorf("error re-opening event log: %s", err.Error())
+		return
+	}
+	if events, err = el.Events(10, 0); err != nil || len(events) != 7 || events[0].Type != repo.ETDsUnpinned {
+		t.Errorf("expected 7 events after rebuilding the index, newest unpinned. got: %v, %v", events, err)
+	}
+	if events, err = el.EventsSince(now.Add(time.Minute + time.Second)); err != nil || len(events) != 2 {
+		t.Errorf("expected a rebuilt index to be ordered by time. got: %v, %v", events, err)
+	}
+}
+
+func TestLockfile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("repo locks aren't supported on windows")
+	}
+
+	path := filepath.Join(os.TempDir(), "qri_lockfile_test")
+	if err := os.RemoveAll(path); err != nil {
+		t.Errorf("error removing files: %s", err.Error())
+		return
+	}
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		t.Errorf("error creating test dir: %s", err.Error())
+		return
+	}
+	defer os.RemoveAll(path)
+	bp := basepath(path)
+
+	// acquiring within a process shares a single lock
+	a, err := AcquireLock(bp, LockShared)
+	if err != nil {
+		t.Errorf("error acquiring shared lock: %s", err.Error())
+		return
+	}
+	b, err := AcquireLock(bp, LockExclusive)
+	if err != nil {
+		t.Errorf("error upgrading lock: %s", err.Error())
+		return
+	}
+	if a != b {
+		t.Errorf("expected in-process locks to be shared")
+	}
+	if a.Mode() != LockExclusive {
+		t.Errorf("expected lock to be upgraded to exclusive. got: %s", a.Mode())
+	}
+	if pid := readLockPID(bp.filepath(FileLockfile)); pid != os.Getpid() {
+		t.Errorf("lockfile pid mismatch. expected: %d, got: %d", os.Getpid(), pid)
+	}
+	if err := a.Release(); err != nil {
+		t.Errorf("error releasing lock: %s", err.Error())
+	}
+	if err := b.Release(); err != nil {
+		t.Errorf("error releasing lock: %s", err.Error())
+	}
+
+	// simulate another process holding the lock by locking a separate file handle
+	holdLock := func(pid int) *os.File {
+		f, err := os.OpenFile(bp.filepath(FileLockfile), os.O_RDWR|os.O_CREATE, os.ModePerm)
+		if err != nil {
+			t.Fatalf("error opening lockfile: %s", err.Error())
+		}
+		if err := lockFile(f, LockExclusive); err != nil {
+			t.Fatalf("error locking file: %s", err.Error())
+		}
+		f.Truncate(0)
+		f.WriteAt([]byte(strconv.Itoa(pid)), 0)
+		return f
+	}
+
+	held := holdLock(os.Getppid())
+	_, err = AcquireLock(bp, LockShared)
+	if !IsLocked(err) {
+		t.Errorf("expected LockedError, got: %v", err)
+	} else if e := err.(LockedError); e.PID != os.Getppid() {
+		t.Errorf("LockedError pid mismatch. expected: %d, got: %d", os.Getppid(), e.PID)
+	}
+	held.Close()
+}
+
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "qri_migrate_test")
+	if err := os.RemoveAll(path); err != nil {
+		t.Errorf("error removing files: %s", err.Error())
+		return
+	}
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		t.Errorf("error creating test dir: %s", err.Error())
+		return
+	}
+	defer os.RemoveAll(path)
+	bp := basepath(path)
+
+	// a version 0 repo has a json array of events & no info file
+	legacy := []*repo.Event{
+		{Time: time.Now().Add(-time.Hour), Type: repo.ETDsCreated, Ref: repo.DatasetRef{Name: "a"}},
+	}
+	if err := bp.saveFile(legacy, FileEventLogs); err != nil {
+		t.Errorf("error writing legacy events: %s", err.Error())
+		return
+	}
+
+	pending, err := PendingMigrations(path)
+	if err != nil {
+		t.Errorf("error listing pending migrations: %s", err.Error())
+		return
+	}
+	if len(pending) != CurrentVersion {
+		t.Errorf("pending migration count mismatch. expected: %d, got: %d", CurrentVersion, len(pending))
+	}
+
+	applied, err := Migrate(path)
+	if err != nil {
+		t.Errorf("error migrating: %s", err.Error())
+		return
+	}
+	if len(applied) != len(pending) {
+		t.Errorf("applied migration count mismatch. expected: %d, got: %d", len(pending), len(applied))
+	}
+
+	info, err := ReadInfo(path)
+	if err != nil {
+		t.Errorf("error reading info: %s", err.Error())
+		return
+	}
+	if info.Version != CurrentVersion {
+		t.Errorf("version mismatch. expected: %d, got: %d", CurrentVersion, info.Version)
+	}
+
+	backups, err := filepath.Glob(filepath.Join(bp.filepath(FileBackups), "v0-*", Filepath(FileEventLogs)))
+	if err != nil {
+		t.Errorf("error listing backups: %s", err.Error())
+		return
+	}
+	if len(backups) != 1 {
+		t.Errorf("expected legacy events file to be backed up. got: %v", backups)
+	}
+
+	el, err := NewEventLog(path, FileEvents, FileEventsIndex, nil, false)
+	if err != nil {
+		t.Errorf("error opening event log: %s", err.Error())
+		return
+	}
+	events, err := el.Events(10, 0)
+	if err != nil {
+		t.Errorf("error reading events: %s", err.Error())
+		return
+	}
+	if len(events) != 1 {
+		t.Errorf("expected 1 migrated event, got: %d", len(events))
+	}
+
+	applied, err = Migrate(path)
+	if err != nil {
+		t.Errorf("error re-running migrate: %s", err.Error())
+		return
+	}
+	if len(applied) != 0 {
+		t.Errorf("expected migrating an up-to-date repo to be a no-op. applied: %d", len(applied))
+	}
+
+	// new repos start at the current version
+	fresh := filepath.Join(path, "fresh")
+	if _, err := NewRepo(cafs.NewMapstore(), config.DefaultProfile(), fresh); err != nil {
+		t.Errorf("error creating repo: %s", err.Error())
+		return
+	}
+	if info, err = ReadInfo(fresh); err != nil {
+		t.Errorf("error reading info: %s", err.Error())
+		return
+	}
+	if info.Version != CurrentVersion {
+		t.Errorf("new repo version mismatch. expected: %d, got: %d", CurrentVersion, info.Version)
+	}
+	if _, err := os.Stat(filepath.Join(fresh, Filepath(FileBackups))); !os.IsNotExist(err) {
+		t.Errorf("expected new repo not to be backed up")
+	}
+}
+
+func TestFilestore(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "qri_filestore_test")
+	if err := os.RemoveAll(path); err != nil {
+		t.Errorf("error removing files: %s", err.Error())
+		return
+	}
+	defer os.RemoveAll(path)
+
+	fs, err := NewFilestore(path)
+	if err != nil {
+		t.Errorf("error creating filestore: %s", err.Error())
+		return
+	}
+
+	key, err := fs.Put(cafs.NewMemfileBytes("a.txt", []byte("apples")), false)
+	if err != nil {
+		t.Errorf("error putting file: %s", err.Error())
+		return
+	}
+	if !strings.HasPrefix(key.String(), "/"+FilestorePrefix+"/") {
+		t.Errorf("expected key to have store prefix. got: %s", key.String())
+	}
+	f, err := fs.Get(key)
+	if err != nil {
+		t.Errorf("error getting file: %s", err.Error())
+		return
+	}
+	data, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Errorf("error reading file: %s", err.Error())
+		return
+	}
+	if string(data) != "apples" {
+		t.Errorf("file data mismatch. expected: apples, got: %s", string(data))
+	}
+
+	adder, err := fs.NewAdder(true, true)
+	if err != nil {
+		t.Errorf("error creating adder: %s", err.Error())
+		return
+	}
+	var root datastore.Key
+	done := make(chan bool)
+	go func() {
+		for added := range adder.Added() {
+			root = added.Path
+		}
+		done <- true
+	}()
+
+	pkg := cafs.NewMemdir("/package",
+		cafs.NewMemfileBytes("a.txt", []byte("apples")),
+		cafs.NewMemfileBytes("b.txt", []byte("bananas")),
+	)
+	if err := adder.AddFile(pkg); err != nil {
+		t.Errorf("error adding directory: %s", err.Error())
+		return
+	}
+	if err := adder.Close(); err != nil {
+		t.Errorf("error closing adder: %s", err.Error())
+		return
+	}
+	<-done
+
+	child := datastore.NewKey(root.String() + "/b.txt")
+	if f, err = fs.Get(child); err != nil {
+		t.Errorf("error getting file in directory: %s", err.Error())
+		return
+	}
+	data, _ = ioutil.ReadAll(f)
+	f.Close()
+	if string(data) != "bananas" {
+		t.Errorf("file data mismatch. expected: bananas, got: %s", string(data))
+	}
+
+	keys, err := fs.Keys()
+	if err != nil {
+		t.Errorf("error listing keys: %s", err.Error())
+		return
+	}
+	if len(keys) != 2 {
+		t.Errorf("expected 2 roots. got: %v", keys)
+	}
+
+	if err := fs.Delete(root); err == nil {
+		t.Errorf("expected deleting pinned content to error")
+	}
+	if err := fs.Unpin(root, true); err != nil {
+		t.Errorf("error unpinning: %s", err.Error())
+		return
+	}
+	if err := fs.Delete(root); err != nil {
+		t.Errorf("error deleting: %s", err.Error())
+		return
+	}
+	if has, _ := fs.Has(child); has {
+		t.Errorf("expected directory contents to be removed")
+	}
+	// a.txt is shared with the first root, and must be kept
+	if has, _ := fs.Has(key); !has {
+		t.Errorf("expected content shared with another root to be kept")
+	}
+}
+
+func TestChangeRequestStore(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "qri_change_requests_test")
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		t.Errorf("error creating directory: %s", err.Error())
+		return
+	}
+	defer os.RemoveAll(path)
+
+	s := NewChangeRequestStore(basepath(path))
+	if err := s.PutChangeRequest(&repo.ChangeRequest{}); err == nil {
+		t.Errorf("expected putting a change request without an id to error")
+	}
+
+	now := time.Now()
+	for i, id := range []string{"a", "b", "c"} {
+		cr := &repo.ChangeRequest{ID: id, Status: repo.CRStatusOpen, Updated: now.Add(time.Duration(i) * time.Second)}
+		if err := s.PutChangeRequest(cr); err != nil {
+			t.Errorf("error putting change request: %s", err.Error())
+			return
+		}
+	}
+
+	// re-open to make sure requests are persisted
+	s = NewChangeRequestStore(basepath(path))
+	cr, err := s.GetChangeRequest("b")
+	if err != nil {
+		t.Errorf("error getting change request: %s", err.Error())
+		return
+	}
+	cr.Status = repo.CRStatusRejected
+	if err := s.PutChangeRequest(cr); err != nil {
+		t.Errorf("error updating change request: %s", err.Error())
+		return
+	}
+	if cr, err = s.GetChangeRequest("b"); err != nil || cr.Status != repo.CRStatusRejected {
+		t.Errorf("expected change request status to update")
+	}
+
+	crs, err := s.ListChangeRequests(2, 0)
+	if err != nil {
+		t.Errorf("error listing change requests: %s", err.Error())
+		return
+	}
+	if len(crs) != 2 || crs[0].ID != "c" || crs[1].ID != "b" {
+		t.Errorf("expected most recently updated change requests first")
+	}
+
+	if err := s.DeleteChangeRequest("a"); err != nil {
+		t.Errorf("error deleting change request: %s", err.Error())
+	}
+	if _, err := s.GetChangeRequest("a"); err != repo.ErrNotFound {
+		t.Errorf("expected deleted change request to be missing. got: %v", err)
+	}
+}
+
+func TestAnalytics(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "qri_analytics_test")
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		t.Errorf("error creating directory: %s", err.Error())
+		return
+	}
+	defer os.RemoveAll(path)
+
+	a := NewAnalytics(basepath(path), false)
+	if err := a.IncrementStat(repo.DatasetRef{Name: "movies"}, repo.StatAPIReads); err == nil {
+		t.Errorf("expected incrementing a stat without a peername to error")
+	}
+
+	movies := repo.DatasetRef{Peername: "peer", Name: "movies"}
+	cities := repo.DatasetRef{Peername: "peer", Name: "cities"}
+	for _, inc := range []struct {
+		ref repo.DatasetRef
+		t   repo.StatType
+	}{
+		{movies, repo.StatAPIReads},
+		{cities, repo.StatDataReads},
+		{cities, repo.StatDataReads},
+		{cities, repo.StatPeerAdds},
+	} {
+		if err := a.IncrementStat(inc.ref, inc.t); err != nil {
+			t.Errorf("error incrementing stat: %s", err.Error())
+			return
+		}
+	}
+
+	// re-open read-only to make sure counters are persisted
+	a = NewAnalytics(basepath(path), true)
+	if err := a.IncrementStat(cities, repo.StatAPIReads); err != repo.ErrReadOnly {
+		t.Errorf("expected read-only analytics to refuse writes. got: %v", err)
+	}
+	s, err := a.DatasetStats(cities)
+	if err != nil {
+		t.Errorf("error getting dataset stats: %s", err.Error())
+		return
+	}
+	if s.Counts[repo.StatDataReads] != 2 || s.Counts[repo.StatPeerAdds] != 1 {
+		t.Errorf("unexpected counts: %v", s.Counts)
+	}
+
+	if _, err := a.DatasetStats(repo.DatasetRef{Peername: "peer", Name: "counter"}); err != repo.ErrNotFound {
+		t.Errorf("expected stats for an unused dataset to be ErrNotFound. got: %v", err)
+	}
+
+	list, err := a.Stats(-1, 0)
+	if err != nil {
+		t.Errorf("error listing stats: %s", err.Error())
+		return
+	}
+	if len(list) != 2 || list[0].Name != "cities" || list[1].Name != "movies" {
+		t.Errorf("expected stats ordered by use, cities first. got: %v", list)
+	}
+	if list, _ = a.Stats(1, 1); len(list) != 1 || list[0].Name != "movies" {
+		t.Errorf("expected a page with only movies. got: %v", list)
+	}
+}
+
+func TestTagStore(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "qri_tags_test")
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		t.Errorf("error creating directory: %s", err.Error())
+		return
+	}
+	defer os.RemoveAll(path)
+
+	s := NewTagStore(basepath(path))
+	if err := s.PutTag(repo.DatasetRef{Peername: "peer", Name: "movies", Tag: "v1"}); err != repo.ErrPathRequired {
+		t.Errorf("expected putting a tag without a path to return ErrPathRequired. got: %v", err)
+	}
+
+	for _, ref := range []repo.DatasetRef{
+		{Peername: "peer", Name: "movies", Tag: "v2", Path: "/map/b"},
+		{Peername: "peer", Name: "movies", Tag: "v1", Path: "/map/a"},
+		{Peername: "peer", Name: "cities", Tag: "v1", Path: "/map/c"},
+	} {
+		if err := s.PutTag(ref); err != nil {
+			t.Errorf("error putting tag: %s", err.Error())
+			return
+		}
+	}
+
+	// re-open to make sure tags are persisted
+	s = NewTagStore(basepath(path))
+	got, err := s.GetTag(repo.DatasetRef{Peername: "peer", Name: "movies", Tag: "v1"})
+	if err != nil {
+		t.Errorf("error getting tag: %s", err.Error())
+		return
+	}
+	if got.Path != "/map/a" {
+		t.Errorf("expected tag path /map/a. got: %s", got.Path)
+	}
+
+	tags, err := s.ListTags(repo.DatasetRef{Peername: "peer", Name: "movies"})
+	if err != nil {
+		t.Errorf("error listing tags: %s", err.Error())
+		return
+	}
+	if len(tags) != 2 || tags[0].Tag != "v1" || tags[1].Tag != "v2" {
+		t.Errorf("expected movies tags v1, v2. got: %v", tags)
+	}
+
+	if err := s.DeleteTag(got); err != nil {
+		t.Errorf("error deleting tag: %s", err.Error())
+		return
+	}
+	if tags, _ = s.ListTags(repo.DatasetRef{}); len(tags) != 2 {
+		t.Errorf("expected 2 tags after delete. got: %d", len(tags))
+	}
+}
+
+func TestBranchStore(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "qri_branches_test")
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		t.Errorf("error creating directory: %s", err.Error())
+		return
+	}
+	defer os.RemoveAll(path)
+
+	s := NewBranchStore(basepath(path))
+	if err := s.PutBranch(repo.DatasetRef{Peername: "peer", Name: "movies", Branch: repo.DefaultBranch, Path: "/map/a"}); err == nil {
+		t.Errorf("expected putting the default branch to error")
+	}
+
+	for _, ref := range []repo.DatasetRef{
+		{Peername: "peer", Name: "movies", Branch: "fix", Path: "/map/a"},
+		{Peername: "peer", Name: "movies", Branch: "fix", Path: "/map/b"},
+		{Peername: "peer", Name: "cities", Branch: "draft", Path: "/map/c"},
+	} {
+		if err := s.PutBranch(ref); err != nil {
+			t.Errorf("error putting branch: %s", err.Error())
+			return
+		}
+	}
+
+	// re-open to make sure branches are persisted
+	s = NewBranchStore(basepath(path))
+	got, err := s.GetBranch(repo.DatasetRef{Peername: "peer", Name: "movies", Branch: "fix"})
+	if err != nil {
+		t.Errorf("error getting branch: %s", err.Error())
+		return
+	}
+	if got.Path != "/map/b" {
+		t.Errorf("expected putting a branch to move its head to /map/b. got: %s", got.Path)
+	}
+
+	if err := repo.RenameBranches(s, got, repo.DatasetRef{Peername: "peer", Name: "films"}); err != nil {
+		t.Errorf("error renaming branches: %s", err.Error())
+		return
+	}
+	branches, err := s.ListBranches(repo.DatasetRef{})
+	if err != nil {
+		t.Errorf("error listing branches: %s", err.Error())
+		return
+	}
+	if len(branches) != 2 || branches[1].Name != "films" || branches[1].Branch != "fix" {
+		t.Errorf("expected branch fix to move to peer/films. got: %v", branches)
+	}
+}
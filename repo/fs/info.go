@@ -0,0 +1,86 @@
+package fsrepo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CurrentVersion is the version of the on-disk repo layout this package
+// reads & writes. Repos with a lower version are upgraded by running
+// migrations when opened
+const CurrentVersion = 1
+
+// Info stores information about a repository. It's written to FileInfo
+type Info struct {
+	// Version of the on-disk repo layout
+	Version int `json:"version"`
+	// Created is when the repo was first written with version information
+	Created time.Time `json:"created"`
+	// Updated is when Version last changed
+	Updated time.Time `json:"updated"`
+}
+
+// ReadInfo loads repo information for the repo at base. Repos written
+// before versioning was introduced have no info file, and are reported
+// as version 0
+func ReadInfo(base string) (*Info, error) {
+	data, err := basepath(base).readBytes(FileInfo)
+	if os.IsNotExist(err) {
+		return &Info{Version: 0}, nil
+	} else if err != nil {
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error reading repo info: %s", err.Error())
+	}
+
+	info := &Info{}
+	if err := json.Unmarshal(data, info); err != nil {
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error decoding repo info: %s", err.Error())
+	}
+	return info, nil
+}
+
+// writeInfo records a repo version, preserving the creation time of any
+// existing info
+func writeInfo(bp basepath, version int) error {
+	info, err := ReadInfo(string(bp))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if info.Created.IsZero() {
+		info.Created = now
+	}
+	info.Version = version
+	info.Updated = now
+	return bp.saveFile(info, FileInfo)
+}
+
+// Size gives the number of bytes the repo at base occupies on disk, not
+// including the underlying cafs store
+func Size(base string) (int64, error) {
+	bp := basepath(base)
+	var size int64
+	for f := range paths {
+		if f == FileUnknown || f == FileBackups {
+			continue
+		}
+		err := filepath.Walk(bp.filepath(f), func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.IsDir() {
+				size += fi.Size()
+			}
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+	}
+	return size, nil
+}
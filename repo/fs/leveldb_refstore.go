@@ -0,0 +1,306 @@
+package fsrepo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/dsfs"
+	"github.com/qri-io/qri/repo"
+	"github.com/qri-io/qri/repo/search"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// key prefixes for each index the LevelDBRefstore maintains. every reference
+// is written once under each prefix
+const (
+	refPrefixName      = "/name/"
+	refPrefixProfileID = "/pid/"
+	refPrefixPath      = "/path/"
+)
+
+// pathKeyPrefix is the start of the path index keys of every reference to
+// path. Any number of names can refer to the same path, so path keys end
+// with the name they're for
+func pathKeyPrefix(path string) string {
+	return refPrefixPath + path + "\x00"
+}
+
+// pathKey is the path index key of a reference
+func pathKey(ref repo.DatasetRef) string {
+	return pathKeyPrefix(ref.Path) + ref.Peername + "/" + ref.Name
+}
+
+// LevelDBRefstore is an implementation of the repo.Refstore interface
+// backed by an embedded on-disk leveldb key-value store. References are
+// indexed by peername/name, profileID/name and path/peername/name so reads
+// & writes don't depend on the number of references in the store
+type LevelDBRefstore struct {
+	db *leveldb.DB
+	// optional search index to add/remove from
+	index search.Index
+	// filestore for checking dataset integrity
+	store cafs.Filestore
+}
+
+// NewLevelDBRefstore opens (creating if necessary) a leveldb refstore within
+// a repo's base path. Any references stored in a json refstore file are
+// migrated into the database the first time it's opened. A readOnly
+// refstore shares the database with other readers, and never writes to it
+func NewLevelDBRefstore(bp basepath, store cafs.Filestore, index search.Index, readOnly bool) (*LevelDBRefstore, error) {
+	db, err := leveldb.OpenFile(bp.filepath(FileRefstoreDB), &opt.Options{ReadOnly: readOnly})
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error opening refstore database: %s", err.Error())
+	}
+
+	rs := &LevelDBRefstore{db: db, index: index, store: store}
+	if readOnly {
+		return rs, nil
+	}
+	if err := rs.migrateJSONRefs(bp); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return rs, nil
+}
+
+// PutRef adds a reference to the store
+func (rs *LevelDBRefstore) PutRef(put repo.DatasetRef) (err error) {
+	var ds *dataset.Dataset
+
+	if put.ProfileID == "" {
+		return repo.ErrPeerIDRequired
+	} else if put.Name == "" {
+		return repo.ErrNameRequired
+	} else if put.Path == "" {
+		return repo.ErrPathRequired
+	} else if put.Peername == "" {
+		return repo.ErrPeernameRequired
+	}
+
+	p := repo.DatasetRef{Peername: put.Peername, ProfileID: put.ProfileID, Name: put.Name, Path: put.Path}
+
+	if ref, err := rs.GetRef(p); err == nil {
+		if ref.Equal(p) {
+			return nil
+		}
+		return repo.ErrNameTaken
+	} else if err != repo.ErrNotFound {
+		return err
+	}
+
+	if rs.store != nil {
+		ds, err = dsfs.LoadDataset(rs.store, datastore.NewKey(p.Path))
+		if err != nil {
+			return err
+		}
+	}
+
+	if rs.index != nil {
+		batch := rs.index.NewBatch()
+		err = batch.Index(p.Path, ds)
+		if err != nil {
+			log.Debug(err.Error())
+			return err
+		}
+		err = rs.index.Batch(batch)
+		if err != nil {
+			log.Debug(err.Error())
+			return err
+		}
+	}
+
+	batch := new(leveldb.Batch)
+	if err = putRefBatch(batch, p); err != nil {
+		return err
+	}
+	return rs.db.Write(batch, nil)
+}
+
+// GetRef completes a partially-known reference, checking path, peername/name
+// and profileID/name indexes in that order. If more than one name refers to
+// a path, the first by peername/name is given
+func (rs *LevelDBRefstore) GetRef(get repo.DatasetRef) (repo.DatasetRef, error) {
+	keys := make([]string, 0, 3)
+	if get.Path != "" {
+		if get.Peername != "" && get.Name != "" {
+			keys = append(keys, pathKey(get))
+		} else {
+			ref, err := rs.firstWithPath(get.Path)
+			if err != repo.ErrNotFound {
+				return ref, err
+			}
+		}
+	}
+	if get.Name != "" {
+		if get.Peername != "" {
+			keys = append(keys, refPrefixName+get.Peername+"/"+get.Name)
+		}
+		if get.ProfileID != "" {
+			keys = append(keys, refPrefixProfileID+get.ProfileID.String()+"/"+get.Name)
+		}
+	}
+
+	for _, key := range keys {
+		data, err := rs.db.Get([]byte(key), nil)
+		if err == leveldb.ErrNotFound {
+			continue
+		} else if err != nil {
+			log.Debug(err.Error())
+			return repo.DatasetRef{}, fmt.Errorf("error reading reference: %s", err.Error())
+		}
+		return decodeRef(data)
+	}
+
+	return repo.DatasetRef{}, repo.ErrNotFound
+}
+
+// firstWithPath gets the first reference to path by peername/name
+func (rs *LevelDBRefstore) firstWithPath(path string) (repo.DatasetRef, error) {
+	iter := rs.db.NewIterator(util.BytesPrefix([]byte(pathKeyPrefix(path))), nil)
+	defer iter.Release()
+
+	if iter.Next() {
+		return decodeRef(iter.Value())
+	}
+	if err := iter.Error(); err != nil {
+		log.Debug(err.Error())
+		return repo.DatasetRef{}, fmt.Errorf("error reading reference: %s", err.Error())
+	}
+	return repo.DatasetRef{}, repo.ErrNotFound
+}
+
+// DeleteRef removes a reference from the store. Deleting a reference that
+// isn't in the store does nothing
+func (rs *LevelDBRefstore) DeleteRef(del repo.DatasetRef) error {
+	ref, err := rs.GetRef(del)
+	if err == repo.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Delete([]byte(refPrefixName + ref.Peername + "/" + ref.Name))
+	batch.Delete([]byte(pathKey(ref)))
+	if ref.ProfileID != "" {
+		batch.Delete([]byte(refPrefixProfileID + ref.ProfileID.String() + "/" + ref.Name))
+	}
+	if err := rs.db.Write(batch, nil); err != nil {
+		return err
+	}
+
+	// the path stays searchable while other names refer to it
+	if ref.Path != "" && rs.index != nil {
+		if _, err := rs.firstWithPath(ref.Path); err == repo.ErrNotFound {
+			if err := rs.index.Delete(ref.Path); err != nil {
+				log.Debug(err.Error())
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// References gives a set of dataset references from the store, ordered
+// lexographically by peername/name
+func (rs *LevelDBRefstore) References(limit, offset int) ([]repo.DatasetRef, error) {
+	refs := make([]repo.DatasetRef, 0, limit)
+	iter := rs.db.NewIterator(util.BytesPrefix([]byte(refPrefixName)), nil)
+	defer iter.Release()
+
+	for i := 0; iter.Next(); i++ {
+		if i < offset {
+			continue
+		}
+		if len(refs) == limit {
+			break
+		}
+		ref, err := decodeRef(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+
+	return refs, iter.Error()
+}
+
+// RefCount returns the size of the Refstore
+func (rs *LevelDBRefstore) RefCount() (int, error) {
+	count := 0
+	iter := rs.db.NewIterator(util.BytesPrefix([]byte(refPrefixName)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		count++
+	}
+	return count, iter.Error()
+}
+
+// Close releases the underlying database
+func (rs *LevelDBRefstore) Close() error {
+	return rs.db.Close()
+}
+
+// migrateJSONRefs performs a one-time import of references from a json
+// refstore file. The file is renamed once import completes, leaving a copy
+// of the original data in place
+func (rs *LevelDBRefstore) migrateJSONRefs(bp basepath) error {
+	path := bp.filepath(FileRefstore)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	legacy := Refstore{basepath: bp, file: FileRefstore}
+	refs, err := legacy.names()
+	if err != nil {
+		return fmt.Errorf("error reading references to migrate: %s", err.Error())
+	}
+
+	batch := new(leveldb.Batch)
+	for _, ref := range refs {
+		if err := putRefBatch(batch, ref); err != nil {
+			return err
+		}
+	}
+	if err := rs.db.Write(batch, nil); err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("error writing migrated references: %s", err.Error())
+	}
+
+	log.Infof("migrated %d references to refstore database", len(refs))
+	return os.Rename(path, path+".migrated")
+}
+
+// putRefBatch adds write operations for all index entries of a reference
+// to a batch
+func putRefBatch(batch *leveldb.Batch, ref repo.DatasetRef) error {
+	data, err := json.Marshal(repo.DatasetRef{Peername: ref.Peername, ProfileID: ref.ProfileID, Name: ref.Name, Path: ref.Path})
+	if err != nil {
+		log.Debug(err.Error())
+		return err
+	}
+
+	batch.Put([]byte(refPrefixName+ref.Peername+"/"+ref.Name), data)
+	batch.Put([]byte(pathKey(ref)), data)
+	if ref.ProfileID != "" {
+		batch.Put([]byte(refPrefixProfileID+ref.ProfileID.String()+"/"+ref.Name), data)
+	}
+	return nil
+}
+
+func decodeRef(data []byte) (repo.DatasetRef, error) {
+	ref := repo.DatasetRef{}
+	if err := json.Unmarshal(data, &ref); err != nil {
+		log.Debug(err.Error())
+		return ref, fmt.Errorf("error decoding reference: %s", err.Error())
+	}
+	return ref, nil
+}
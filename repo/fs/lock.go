@@ -0,0 +1,193 @@
+package fsrepo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LockMode specifies how a repo lockfile is held
+type LockMode int
+
+const (
+	// LockExclusive is held by processes that write to the repo.
+	// Only one process may hold an exclusive lock at a time
+	LockExclusive LockMode = iota
+	// LockShared is held by processes that only read from the repo.
+	// Any number of processes may hold a shared lock at once
+	LockShared
+)
+
+// String implements the Stringer interface for LockMode
+func (m LockMode) String() string {
+	if m == LockShared {
+		return "shared"
+	}
+	return "exclusive"
+}
+
+// errWouldBlock is returned by platform lock implementations when a lock
+// is held by another process
+var errWouldBlock = fmt.Errorf("lock is held by another process")
+
+// LockedError is returned when another process holds a lock that conflicts
+// with the one being acquired. PID is the process id recorded by the holder
+// of an exclusive lock, or 0 if unknown
+type LockedError struct {
+	Path string
+	PID  int
+}
+
+// Error implements the error interface
+func (e LockedError) Error() string {
+	if e.PID != 0 {
+		return fmt.Sprintf("repo at %s is locked by qri process %d, usually `qri connect`. stop that process, or restart it with rpc enabled so other commands can reach it", e.Path, e.PID)
+	}
+	return fmt.Sprintf("repo at %s is locked by another qri process, usually `qri connect`. stop that process, or restart it with rpc enabled so other commands can reach it", e.Path)
+}
+
+// IsLocked checks if an error is the result of a repo lock held by another
+// process
+func IsLocked(err error) bool {
+	_, ok := err.(LockedError)
+	return ok
+}
+
+// Lockfile is an advisory lock on a repo, backed by FileLockfile.
+// Exclusive lock holders record their process id in the lockfile, so
+// processes that fail to acquire the lock can report who holds it
+type Lockfile struct {
+	base string
+	path string
+	mode LockMode
+	f    *os.File
+	refs int
+}
+
+var (
+	// locks held by this process, keyed by lockfile path. the OS treats
+	// each open file separately, so opening the same repo more than once
+	// within a process must share a lock to avoid blocking on itself
+	locks     = map[string]*Lockfile{}
+	locksLock sync.Mutex
+)
+
+// AcquireLock takes an advisory lock on the repo at basepath, failing with
+// LockedError if another process holds a conflicting lock. Every successful
+// call must be paired with a call to Release
+func AcquireLock(bp basepath, mode LockMode) (*Lockfile, error) {
+	path := bp.filepath(FileLockfile)
+
+	locksLock.Lock()
+	defer locksLock.Unlock()
+
+	if l, ok := locks[path]; ok {
+		if mode == LockExclusive && l.mode == LockShared {
+			if err := l.upgrade(); err != nil {
+				return nil, err
+			}
+		}
+		l.refs++
+		return l, nil
+	}
+
+	l, err := openLock(bp, mode)
+	if err == errWouldBlock {
+		return nil, LockedError{Path: string(bp), PID: readLockPID(path)}
+	} else if err != nil {
+		return nil, err
+	}
+
+	locks[path] = l
+	return l, nil
+}
+
+// Mode gives the mode this lock is currently held in
+func (l *Lockfile) Mode() LockMode {
+	return l.mode
+}
+
+// Release gives up a lock. The lock is only returned to the OS once
+// all acquisitions within this process have been released
+func (l *Lockfile) Release() error {
+	locksLock.Lock()
+	defer locksLock.Unlock()
+
+	l.refs--
+	if l.refs > 0 {
+		return nil
+	}
+
+	delete(locks, l.path)
+	if l.mode == LockExclusive {
+		l.f.Truncate(0)
+	}
+	if err := unlockFile(l.f); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// upgrade converts a shared lock to an exclusive lock
+func (l *Lockfile) upgrade() error {
+	if err := lockFile(l.f, LockExclusive); err == errWouldBlock {
+		return LockedError{Path: l.base, PID: readLockPID(l.path)}
+	} else if err != nil {
+		return err
+	}
+	l.mode = LockExclusive
+	return l.writePID()
+}
+
+// writePID records the current process id in the lockfile
+func (l *Lockfile) writePID() error {
+	if err := l.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := l.f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0); err != nil {
+		return err
+	}
+	return l.f.Sync()
+}
+
+func openLock(bp basepath, mode LockMode) (*Lockfile, error) {
+	path := bp.filepath(FileLockfile)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, os.ModePerm)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error opening repo lockfile: %s", err.Error())
+	}
+
+	if err := lockFile(f, mode); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	l := &Lockfile{base: string(bp), path: path, mode: mode, f: f, refs: 1}
+	if mode == LockExclusive {
+		if err := l.writePID(); err != nil {
+			unlockFile(f)
+			f.Close()
+			return nil, fmt.Errorf("error writing repo lockfile: %s", err.Error())
+		}
+	}
+	return l, nil
+}
+
+// readLockPID reads the process id recorded in a lockfile, returning 0
+// if no valid id is present
+func readLockPID(path string) int {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
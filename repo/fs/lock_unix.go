@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package fsrepo
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes a non-blocking flock on a file, returning errWouldBlock
+// if another process holds a conflicting lock
+func lockFile(f *os.File, mode LockMode) error {
+	how := syscall.LOCK_EX
+	if mode == LockShared {
+		how = syscall.LOCK_SH
+	}
+	err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return errWouldBlock
+	}
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
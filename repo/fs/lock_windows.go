@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package fsrepo
+
+import (
+	"os"
+)
+
+// lockFile is a no-op on windows, where locks aren't yet supported
+// TODO - use LockFileEx
+func lockFile(f *os.File, mode LockMode) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,185 @@
+package fsrepo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Migration upgrades the on-disk layout of a repo by one version
+type Migration struct {
+	// Version the migration upgrades a repo to
+	Version int
+	// Description of changes the migration makes
+	Description string
+	// Up performs the migration on the repo at a base path
+	Up func(base string) error
+}
+
+// Migrations lists all repo migrations in order. A migration's Version must
+// be one greater than the migration before it, and the last migration's
+// Version must equal CurrentVersion
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "move events from a json array to an append-only event log",
+		Up: func(base string) error {
+			el, err := NewEventLog(base, FileEvents, FileEventsIndex, nil, false)
+			if err != nil {
+				return err
+			}
+			return el.migrateJSONEvents(FileEventLogs)
+		},
+	},
+}
+
+// PendingMigrations lists migrations that haven't been applied to the repo
+// at base, in the order they'll run
+func PendingMigrations(base string) ([]Migration, error) {
+	bp := basepath(base)
+	info, err := ReadInfo(base)
+	if err != nil {
+		return nil, err
+	}
+	if info.Version > CurrentVersion {
+		return nil, fmt.Errorf("repo version %d is newer than the latest version this qri supports (%d), please upgrade qri", info.Version, CurrentVersion)
+	}
+	if info.Version == 0 && !hasRepoFiles(bp) {
+		// nothing to migrate in a brand new repo
+		return []Migration{}, nil
+	}
+
+	pending := []Migration{}
+	for _, m := range Migrations {
+		if m.Version > info.Version {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate upgrades the repo at base to CurrentVersion, returning the
+// migrations that were applied. Migrate takes an exclusive lock on the repo
+// while it runs
+func Migrate(base string) ([]Migration, error) {
+	bp := basepath(base)
+	lock, err := AcquireLock(bp, LockExclusive)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	return migrate(bp)
+}
+
+// migrate applies any pending migrations in order, backing up the repo
+// before changing anything. Callers must hold an exclusive lock
+func migrate(bp basepath) ([]Migration, error) {
+	pending, err := PendingMigrations(string(bp))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pending) > 0 {
+		from := pending[0].Version - 1
+		dir, err := backup(bp, from)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("backed up version %d repo to %s", from, dir)
+	}
+
+	for _, m := range pending {
+		log.Infof("migrating repo to version %d: %s", m.Version, m.Description)
+		if err := m.Up(string(bp)); err != nil {
+			log.Debug(err.Error())
+			return nil, fmt.Errorf("error migrating repo to version %d: %s", m.Version, err.Error())
+		}
+		// write version after each step so interrupted migrations resume
+		// where they left off
+		if err := writeInfo(bp, m.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	if info, err := ReadInfo(string(bp)); err != nil {
+		return nil, err
+	} else if info.Version != CurrentVersion {
+		if err := writeInfo(bp, CurrentVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	return pending, nil
+}
+
+// hasRepoFiles checks for the presence of any repo data, ignoring the
+// lockfile, which is written before migrations run
+func hasRepoFiles(bp basepath) bool {
+	for f := range paths {
+		if f == FileUnknown || f == FileLockfile || f == FileInfo || f == FileBackups {
+			continue
+		}
+		if _, err := os.Stat(bp.filepath(f)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// backup copies all repo files into a timestamped directory within
+// FileBackups, returning the path to the new backup
+func backup(bp basepath, version int) (string, error) {
+	dir := filepath.Join(bp.filepath(FileBackups), fmt.Sprintf("v%d-%d", version, time.Now().Unix()))
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		log.Debug(err.Error())
+		return "", fmt.Errorf("error creating backup directory: %s", err.Error())
+	}
+
+	for f, path := range paths {
+		if f == FileUnknown || f == FileLockfile || f == FileBackups {
+			continue
+		}
+		if err := copyPath(bp.filepath(f), filepath.Join(dir, path)); err != nil && !os.IsNotExist(err) {
+			log.Debug(err.Error())
+			return "", fmt.Errorf("error backing up repo: %s", err.Error())
+		}
+	}
+	return dir, nil
+}
+
+// copyPath recursively copies a file or directory from src to dst
+func copyPath(src, dst string) error {
+	return filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if fi.IsDir() {
+			return os.MkdirAll(target, fi.Mode())
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
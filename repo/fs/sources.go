@@ -0,0 +1,68 @@
+package fsrepo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/qri-io/qri/repo"
+)
+
+// SourceCache is an on-disk json file implementation of the
+// repo.SourceCache interface
+type SourceCache struct {
+	sync.Mutex
+	basepath
+}
+
+// NewSourceCache allocates a SourceCache
+func NewSourceCache(bp basepath) *SourceCache {
+	return &SourceCache{basepath: bp}
+}
+
+// SourceValidators gets the validators of a dataset's source
+func (c *SourceCache) SourceValidators(ref repo.DatasetRef) (repo.SourceValidators, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	sources, err := c.sources()
+	if err != nil {
+		return repo.SourceValidators{}, err
+	}
+	if v, ok := sources[ref.AliasString()]; ok {
+		return v, nil
+	}
+	return repo.SourceValidators{}, repo.ErrNotFound
+}
+
+// PutSourceValidators sets the validators of a dataset's source
+func (c *SourceCache) PutSourceValidators(ref repo.DatasetRef, v repo.SourceValidators) error {
+	c.Lock()
+	defer c.Unlock()
+
+	sources, err := c.sources()
+	if err != nil {
+		return err
+	}
+	sources[ref.AliasString()] = v
+	return c.saveFile(sources, FileSources)
+}
+
+func (c *SourceCache) sources() (map[string]repo.SourceValidators, error) {
+	sources := map[string]repo.SourceValidators{}
+	data, err := c.readBytes(FileSources)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sources, nil
+		}
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error loading sources: %s", err.Error())
+	}
+
+	if err := json.Unmarshal(data, &sources); err != nil {
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error decoding sources: %s", err.Error())
+	}
+	return sources, nil
+}
@@ -0,0 +1,99 @@
+package fsrepo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/qri-io/qri/repo"
+)
+
+// TagStore is an on-disk json file implementation of the repo.TagStore
+// interface
+type TagStore struct {
+	sync.Mutex
+	basepath
+}
+
+// NewTagStore allocates a TagStore
+func NewTagStore(bp basepath) *TagStore {
+	return &TagStore{basepath: bp}
+}
+
+// PutTag adds or moves a tag
+func (s *TagStore) PutTag(ref repo.DatasetRef) error {
+	s.Lock()
+	defer s.Unlock()
+
+	tags, err := s.tags()
+	if err != nil {
+		return err
+	}
+	if err := repo.PutTag(tags, ref); err != nil {
+		return err
+	}
+	return s.saveFile(tags, FileTags)
+}
+
+// GetTag completes a tag reference
+func (s *TagStore) GetTag(ref repo.DatasetRef) (repo.DatasetRef, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	tags, err := s.tags()
+	if err != nil {
+		return repo.DatasetRef{}, err
+	}
+	if t, ok := tags[repo.TagKey(ref)]; ok {
+		return t, nil
+	}
+	return repo.DatasetRef{}, repo.ErrNotFound
+}
+
+// DeleteTag removes a tag
+func (s *TagStore) DeleteTag(ref repo.DatasetRef) error {
+	s.Lock()
+	defer s.Unlock()
+
+	tags, err := s.tags()
+	if err != nil {
+		return err
+	}
+	key := repo.TagKey(ref)
+	if _, ok := tags[key]; !ok {
+		return repo.ErrNotFound
+	}
+	delete(tags, key)
+	return s.saveFile(tags, FileTags)
+}
+
+// ListTags lists tags for a dataset, or all tags
+func (s *TagStore) ListTags(ref repo.DatasetRef) ([]repo.DatasetRef, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	tags, err := s.tags()
+	if err != nil {
+		return nil, err
+	}
+	return repo.FilterTags(tags, ref), nil
+}
+
+func (s *TagStore) tags() (map[string]repo.DatasetRef, error) {
+	tags := map[string]repo.DatasetRef{}
+	data, err := s.readBytes(FileTags)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return tags, nil
+		}
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error loading tags: %s", err.Error())
+	}
+
+	if err := json.Unmarshal(data, &tags); err != nil {
+		log.Debug(err.Error())
+		return nil, fmt.Errorf("error decoding tags: %s", err.Error())
+	}
+	return tags, nil
+}
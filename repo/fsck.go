@@ -0,0 +1,242 @@
+package repo
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p-crypto"
+	"github.com/multiformats/go-multihash"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/dsfs"
+	"github.com/qri-io/qri/repo/profile"
+)
+
+// FsckIssueType classifies problems found while checking a repo
+type FsckIssueType string
+
+const (
+	// FsckDanglingRef is a reference to a dataset that can't be loaded
+	FsckDanglingRef = FsckIssueType("dangling_ref")
+	// FsckBrokenHistory is a dataset whose PreviousPath can't be loaded
+	FsckBrokenHistory = FsckIssueType("broken_history")
+	// FsckMissingData is a dataset whose data can't be loaded
+	FsckMissingData = FsckIssueType("missing_data")
+	// FsckChecksumMismatch is a dataset whose data doesn't match Structure.Checksum
+	FsckChecksumMismatch = FsckIssueType("checksum_mismatch")
+	// FsckInvalidSignature is a dataset whose commit signature doesn't verify
+	FsckInvalidSignature = FsckIssueType("invalid_signature")
+	// FsckProfileMismatch is a profile or reference with inconsistent profile details
+	FsckProfileMismatch = FsckIssueType("profile_mismatch")
+	// FsckUnpinned is a referenced dataset that isn't pinned, and may be
+	// garbage collected
+	FsckUnpinned = FsckIssueType("unpinned")
+)
+
+// FsckIssue is a single problem found while checking a repo
+type FsckIssue struct {
+	Type    FsckIssueType `json:"type"`
+	Ref     DatasetRef    `json:"ref"`
+	Path    string        `json:"path,omitempty"`
+	Message string        `json:"message"`
+	// Repaired is true if the issue was fixed
+	Repaired bool `json:"repaired"`
+}
+
+// FsckReport is the result of checking a repo
+type FsckReport struct {
+	// Refs is the number of references checked
+	Refs int `json:"refs"`
+	// Versions is the number of dataset versions checked, including history
+	Versions int `json:"versions"`
+	// Profiles is the number of profiles checked
+	Profiles int         `json:"profiles"`
+	Issues   []FsckIssue `json:"issues"`
+}
+
+// Unrepaired counts issues that haven't been fixed
+func (r *FsckReport) Unrepaired() (count int) {
+	for _, is := range r.Issues {
+		if !is.Repaired {
+			count++
+		}
+	}
+	return
+}
+
+// Fsck checks the integrity of a repo. Every reference must resolve to a
+// dataset with intact history, data that matches its structure checksum, and
+// a valid commit signature. Signatures can only be checked for datasets
+// created by the repo's own profile, versions merged from accepted change
+// requests are signed by their authors & aren't checked. Profiles must be
+// consistent with the references that use them. Stores that can report pins
+// must have every referenced dataset pinned. If repair is true, references to
+// datasets that can't be loaded are dropped, and unpinned datasets are pinned.
+func Fsck(r Repo, repair bool) (*FsckReport, error) {
+	store := r.Store()
+	pinner, isPinner := store.(cafs.Pinner)
+	pins, checkPins := store.(PinChecker)
+	report := &FsckReport{Issues: []FsckIssue{}}
+	issue := func(t FsckIssueType, ref DatasetRef, path, msg string, params ...interface{}) *FsckIssue {
+		report.Issues = append(report.Issues, FsckIssue{Type: t, Ref: ref, Path: path, Message: fmt.Sprintf(msg, params...)})
+		return &report.Issues[len(report.Issues)-1]
+	}
+
+	pro, err := r.Profile()
+	if err != nil {
+		return nil, fmt.Errorf("error loading profile: %s", err.Error())
+	}
+	var pub crypto.PubKey
+	if pk := r.PrivateKey(); pk != nil {
+		pub = pk.GetPublic()
+	}
+
+	profiles, err := r.Profiles().List()
+	if err != nil {
+		return nil, fmt.Errorf("error listing profiles: %s", err.Error())
+	}
+	report.Profiles = len(profiles)
+	for id, p := range profiles {
+		if p.ID != id {
+			issue(FsckProfileMismatch, DatasetRef{ProfileID: id}, "", "profile stored under id %s has id %s", id, p.ID)
+		}
+	}
+
+	// versions from accepted change requests carry their author's signature
+	merged := map[string]bool{}
+	crs, err := r.ChangeRequests().ListChangeRequests(-1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error listing change requests: %s", err.Error())
+	}
+	for _, cr := range crs {
+		if cr.Status == CRStatusAccepted && pro != nil && cr.Incoming(pro.ID) {
+			merged[cr.Proposal.Path] = true
+		}
+	}
+
+	refs := []DatasetRef{}
+	for offset := 0; ; offset += 100 {
+		page, err := r.References(100, offset)
+		if err != nil {
+			return nil, fmt.Errorf("error reading references: %s", err.Error())
+		}
+		refs = append(refs, page...)
+		if len(page) < 100 {
+			break
+		}
+	}
+	report.Refs = len(refs)
+
+	for _, ref := range refs {
+		p, ok := profiles[ref.ProfileID]
+		if !ok && pro != nil && pro.ID == ref.ProfileID {
+			p, ok = pro, true
+		}
+		if !ok {
+			issue(FsckProfileMismatch, ref, "", "no profile found for profile id %s", ref.ProfileID)
+		} else if p.Peername != ref.Peername {
+			issue(FsckProfileMismatch, ref, "", "reference peername %s doesn't match profile peername %s", ref.Peername, p.Peername)
+		}
+
+		ds, err := dsfs.LoadDataset(store, datastore.NewKey(ref.Path))
+		if err != nil {
+			is := issue(FsckDanglingRef, ref, ref.Path, "error loading dataset: %s", err.Error())
+			if repair {
+				is.Repaired = r.DeleteRef(ref) == nil
+			}
+			continue
+		}
+
+		root := datastore.NewKey(ref.Path)
+		if checkPins {
+			if pinned, err := pins.IsPinned(root); err != nil {
+				issue(FsckUnpinned, ref, ref.Path, "error checking pin: %s", err.Error())
+			} else if !pinned {
+				is := issue(FsckUnpinned, ref, ref.Path, "dataset isn't pinned")
+				if repair && isPinner {
+					if err := pinner.Pin(root, true); err != nil {
+						is.Message = fmt.Sprintf("error pinning dataset: %s", err.Error())
+					} else {
+						is.Repaired = true
+					}
+				}
+			}
+		} else if isPinner && repair {
+			// without a way to check for pins, re-pin everything when
+			// repairing. pinning an already-pinned path is a no-op
+			if err := pinner.Pin(root, true); err != nil {
+				issue(FsckUnpinned, ref, ref.Path, "error pinning dataset: %s", err.Error())
+			}
+		}
+
+		// walk history, checking each version
+		path := ref.Path
+		for {
+			report.Versions++
+			key := pub
+			if merged[path] {
+				key = nil
+			}
+			checkDataset(ref, path, ds, store, pro, key, issue)
+
+			if ds.PreviousPath == "" || ds.PreviousPath == "/" {
+				break
+			}
+			prev := ds.PreviousPath
+			if ds, err = dsfs.LoadDataset(store, datastore.NewKey(prev)); err != nil {
+				issue(FsckBrokenHistory, ref, prev, "error loading previous version of %s: %s", path, err.Error())
+				break
+			}
+			path = prev
+		}
+	}
+
+	return report, nil
+}
+
+// checkDataset verifies data checksums & commit signatures of a single
+// dataset version
+func checkDataset(ref DatasetRef, path string, ds *dataset.Dataset, store cafs.Filestore, pro *profile.Profile, pub crypto.PubKey, issue func(FsckIssueType, DatasetRef, string, string, ...interface{}) *FsckIssue) {
+	if ds.DataPath != "" && ds.Structure != nil && ds.Structure.Checksum != "" {
+		if sum, err := dataChecksum(store, ds.DataPath); err != nil {
+			issue(FsckMissingData, ref, path, "error loading data %s: %s", ds.DataPath, err.Error())
+		} else if sum != ds.Structure.Checksum {
+			issue(FsckChecksumMismatch, ref, path, "data checksum %s doesn't match structure checksum %s", sum, ds.Structure.Checksum)
+		}
+	}
+
+	// we only hold the public key for this repo's own profile
+	if pub == nil || pro == nil || ref.ProfileID != pro.ID || ds.Commit == nil {
+		return
+	}
+	sig, err := base64.StdEncoding.DecodeString(ds.Commit.Signature)
+	if err != nil || len(sig) == 0 {
+		issue(FsckInvalidSignature, ref, path, "commit signature is missing or malformed")
+		return
+	}
+	if ok, err := pub.Verify(ds.Commit.SignableBytes(), sig); err != nil || !ok {
+		issue(FsckInvalidSignature, ref, path, "commit signature doesn't match profile key")
+	}
+}
+
+// dataChecksum calculates the base58-encoded sha256 multihash of data
+// stored at path
+func dataChecksum(store cafs.Filestore, path string) (string, error) {
+	f, err := store.Get(datastore.NewKey(path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	sum, err := multihash.Sum(data, multihash.SHA2_256, -1)
+	if err != nil {
+		return "", err
+	}
+	return sum.B58String(), nil
+}
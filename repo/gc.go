@@ -0,0 +1,289 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/dsfs"
+)
+
+// KeyLister is an opt-in interface for stores that can enumerate every key
+// they hold. Garbage collection can only find unreferenced content that's
+// either listed by the store or recorded in the repo's event log
+type KeyLister interface {
+	Keys() ([]datastore.Key, error)
+}
+
+// GCItem is a single unreferenced path found during garbage collection
+type GCItem struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// GCResult reports the outcome of garbage collection
+type GCResult struct {
+	// DryRun is true if nothing was removed
+	DryRun bool `json:"dryRun"`
+	// Live is the number of paths reachable from the repo's references
+	Live int `json:"live"`
+	// Garbage lists paths no reference depends on
+	Garbage []GCItem `json:"garbage"`
+	// Bytes is the total size of Garbage
+	Bytes int64 `json:"bytes"`
+}
+
+// LivePaths computes the set of store paths reachable from a repo: every
+// version of every referenced dataset, branch & open change request along
+// with their data, structure, meta, transform & commit components, and
+// profile photos. Paths are normalized to the root of the content they
+// point into
+func LivePaths(r Repo) (map[string]bool, error) {
+	live := map[string]bool{}
+	add := func(paths ...string) {
+		for _, p := range paths {
+			if p != "" && p != "/" {
+				live[PathRoot(p)] = true
+			}
+		}
+	}
+
+	it := IterateDatasets(context.Background(), r, IterateOptions{})
+	for v, ok := it.Next(); ok; v, ok = it.Next() {
+		if v.Err != nil {
+			continue
+		}
+		add(v.Ref.Path)
+		add(datasetPaths(v.Ref.Dataset)...)
+	}
+	// never guess about what's live
+	if errs := it.Errors(); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	// walk adds a version & its history until it joins history that's
+	// already live
+	walk := func(path string) error {
+		for path != "" && path != "/" && !live[PathRoot(path)] {
+			ds, err := dsfs.LoadDatasetRefs(r.Store(), datastore.NewKey(path))
+			if err != nil {
+				return err
+			}
+			add(path)
+			add(datasetPaths(ds)...)
+			path = ds.PreviousPath
+		}
+		return nil
+	}
+
+	// branches can hold versions the default branch never reached
+	branches, err := r.Branches().ListBranches(DatasetRef{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing branches: %s", err.Error())
+	}
+	for _, b := range branches {
+		if err := walk(b.Path); err != nil {
+			return nil, fmt.Errorf("error loading branch %s: %s", b.Branch, err.Error())
+		}
+	}
+
+	// open change requests need both the proposed version & the version
+	// it builds on to be decided
+	for offset := 0; ; offset += 100 {
+		crs, err := r.ChangeRequests().ListChangeRequests(100, offset)
+		if err != nil {
+			return nil, fmt.Errorf("error listing change requests: %s", err.Error())
+		}
+		for _, cr := range crs {
+			if cr.Status != CRStatusOpen {
+				continue
+			}
+			for _, path := range []string{cr.Proposal.Path, cr.Target.Path} {
+				if err := walk(path); err != nil {
+					return nil, fmt.Errorf("error loading change request %s: %s", cr.ID, err.Error())
+				}
+			}
+		}
+		if len(crs) < 100 {
+			break
+		}
+	}
+
+	if pro, err := r.Profile(); err == nil && pro != nil {
+		add(pro.Photo.String(), pro.Thumb.String(), pro.Poster.String())
+	}
+
+	return live, nil
+}
+
+// GarbageCollect removes content from a repo's store that isn't reachable
+// from any reference. Content that's pinned is unpinned before being
+// deleted. Setting dryRun reports what would be removed without removing it
+func GarbageCollect(r Repo, dryRun bool) (*GCResult, error) {
+	store := r.Store()
+	live, err := LivePaths(r)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating live paths: %s", err.Error())
+	}
+
+	candidates, err := gcCandidates(r, live)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GCResult{DryRun: dryRun, Live: len(live), Garbage: []GCItem{}}
+	for _, path := range candidates {
+		key := datastore.NewKey(path)
+		if has, err := store.Has(key); err != nil || !has {
+			continue
+		}
+
+		item := GCItem{Path: path}
+		if f, err := store.Get(key); err == nil {
+			item.Size, _ = fileSize(f)
+			f.Close()
+		}
+		res.Garbage = append(res.Garbage, item)
+		res.Bytes += item.Size
+
+		if dryRun {
+			continue
+		}
+		if pinner, ok := store.(cafs.Pinner); ok {
+			// content may not be pinned, so failing to unpin is expected
+			pinner.Unpin(key, true)
+		}
+		if err := store.Delete(key); err != nil {
+			return res, fmt.Errorf("error deleting %s: %s", path, err.Error())
+		}
+	}
+
+	return res, nil
+}
+
+// PathRoot trims a store path to the content it points into, so
+// "/ipfs/QmFoo/dataset.json" becomes "/ipfs/QmFoo"
+func PathRoot(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) < 2 {
+		return path
+	}
+	return "/" + parts[0] + "/" + parts[1]
+}
+
+// gcCandidates collects every path that may be garbage, in sorted order.
+// Candidates come from store keys & dataset versions recorded in the event
+// log, along with the components & history of any unreachable dataset
+func gcCandidates(r Repo, live map[string]bool) ([]string, error) {
+	store := r.Store()
+	found := map[string]bool{}
+	datasets := []string{}
+
+	if lister, ok := store.(KeyLister); ok {
+		keys, err := lister.Keys()
+		if err != nil {
+			return nil, fmt.Errorf("error listing store keys: %s", err.Error())
+		}
+		for _, k := range keys {
+			found[PathRoot(k.String())] = true
+		}
+	}
+
+	for offset := 0; ; offset += 100 {
+		events, err := r.Events(100, offset)
+		if err != nil {
+			return nil, fmt.Errorf("error reading events: %s", err.Error())
+		}
+		for _, e := range events {
+			if e.Ref.Path != "" {
+				datasets = append(datasets, e.Ref.Path)
+			}
+		}
+		if len(events) < 100 {
+			break
+		}
+	}
+
+	// follow unreachable datasets through their history, adding components
+	// as we go. there's no need to look past a live dataset
+	for len(datasets) > 0 {
+		path := datasets[0]
+		datasets = datasets[1:]
+		root := PathRoot(path)
+		if live[root] || found[root] {
+			continue
+		}
+		found[root] = true
+
+		ds, err := dsfs.LoadDatasetRefs(store, datastore.NewKey(path))
+		if err != nil {
+			continue
+		}
+		for _, p := range datasetPaths(ds) {
+			found[PathRoot(p)] = true
+		}
+		if ds.PreviousPath != "" && ds.PreviousPath != "/" {
+			datasets = append(datasets, ds.PreviousPath)
+		}
+	}
+
+	candidates := make([]string, 0, len(found))
+	for path := range found {
+		if path != "" && !live[path] {
+			candidates = append(candidates, path)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates, nil
+}
+
+// datasetPaths lists the paths of a dataset's stored components
+func datasetPaths(ds *dataset.Dataset) (paths []string) {
+	if ds == nil {
+		return
+	}
+	paths = append(paths, ds.DataPath)
+	if ds.Structure != nil {
+		paths = append(paths, ds.Structure.Path().String())
+	}
+	if ds.Meta != nil {
+		paths = append(paths, ds.Meta.Path().String())
+	}
+	if ds.Transform != nil {
+		paths = append(paths, ds.Transform.Path().String())
+	}
+	if ds.Commit != nil {
+		paths = append(paths, ds.Commit.Path().String())
+	}
+	return
+}
+
+// fileSize counts the bytes in a file, including all files within
+// a directory
+func fileSize(f cafs.File) (int64, error) {
+	if !f.IsDirectory() {
+		return io.Copy(ioutil.Discard, f)
+	}
+
+	var size int64
+	for {
+		child, err := f.NextFile()
+		if err == io.EOF {
+			return size, nil
+		} else if err != nil {
+			return size, err
+		}
+		s, err := fileSize(child)
+		child.Close()
+		size += s
+		if err != nil {
+			return size, err
+		}
+	}
+}
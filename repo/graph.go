@@ -1,16 +1,14 @@
 package repo
 
 import (
+	"context"
 	"fmt"
-	"sync"
 
 	"github.com/ipfs/go-datastore"
 	"github.com/qri-io/dataset/dsfs"
 	"github.com/qri-io/dataset/dsgraph"
 )
 
-var walkParallelism = 4
-
 // HasPath returns true if this repo already has a reference to
 // a given path.
 func HasPath(r Repo, path datastore.Key) (bool, error) {
@@ -49,21 +47,23 @@ func DatasetForQuery(r Repo, qpath datastore.Key) (datastore.Key, error) {
 func Graph(r Repo) (map[string]*dsgraph.Node, error) {
 	nodes := NodeList{Nodes: map[string]*dsgraph.Node{}}
 	root := nodes.node(dsgraph.NtNamespace, "root")
-	mu := sync.Mutex{}
-	err := WalkRepoDatasets(r, func(prev *dsgraph.Node) func(int, *DatasetRef, error) (bool, error) {
-		return func(depth int, ref *DatasetRef, e error) (kontinue bool, err error) {
-			if e != nil {
-				return false, e
-			}
-			mu.Lock()
-			ds := nodes.nodesFromDatasetRef(r, ref)
-			prev.AddLinks(dsgraph.Link{From: prev, To: ds})
-			prev = ds
-			mu.Unlock()
-			return true, nil
+
+	it := IterateDatasets(context.Background(), r, IterateOptions{})
+	for v, ok := it.Next(); ok; v, ok = it.Next() {
+		if v.Err != nil {
+			continue
+		}
+		ds := nodes.nodesFromDatasetRef(r, &v.Ref)
+		// versions link to their previous version, only the latest version
+		// of each dataset hangs off the root
+		if v.Depth == 0 {
+			root.AddLinks(dsgraph.Link{From: root, To: ds})
 		}
-	}(root))
-	return nodes.Nodes, err
+	}
+	if errs := it.Errors(); len(errs) > 0 {
+		return nodes.Nodes, errs[0]
+	}
+	return nodes.Nodes, nil
 }
 
 // QueriesMap returns a mapped subset of a list of nodes in the form:
@@ -161,93 +161,3 @@ func (nl NodeList) nodesFromDatasetRef(r Repo, ref *DatasetRef) *dsgraph.Node {
 
 	return root
 }
-
-// WalkRepoDatasets visits every dataset in the history of a user's namespace
-// Yes, this potentially a very expensive function to call, use sparingly.
-func WalkRepoDatasets(r Repo, visit func(depth int, ref *DatasetRef, err error) (bool, error)) error {
-	pll := walkParallelism
-	store := r.Store()
-	count, err := r.RefCount()
-	if err != nil {
-		return err
-	} else if count == 0 {
-		return ErrRepoEmpty
-	}
-
-	if count < pll {
-		pll = count
-	}
-
-	doSection := func(idx, pageSize int, done chan error) error {
-		refs, err := r.References(pageSize, idx*pageSize)
-		if err != nil {
-			done <- err
-			return err
-		}
-
-		for _, ref := range refs {
-			ref.Dataset, err = dsfs.LoadDatasetRefs(store, datastore.NewKey(ref.Path))
-			// TODO - remove this once loading is more consistent.
-			if err != nil {
-				ref.Dataset, err = dsfs.LoadDatasetRefs(store, datastore.NewKey(ref.Path))
-			}
-			if err != nil {
-				ref.Dataset, err = dsfs.LoadDatasetRefs(store, datastore.NewKey(ref.Path))
-			}
-			if err != nil {
-				err = fmt.Errorf("error loading dataset: %s", err.Error())
-			}
-
-			kontinue, err := visit(0, &ref, err)
-			if err != nil {
-				err = fmt.Errorf("error visiting node: %s", err.Error())
-				done <- err
-				return err
-			}
-			if !kontinue {
-				break
-			}
-
-			depth := 1
-			for ref.Dataset != nil && ref.Dataset.PreviousPath != "" && ref.Dataset.PreviousPath != "/" {
-				ref.Path = ref.Dataset.PreviousPath
-
-				ref.Dataset, err = dsfs.LoadDatasetRefs(store, datastore.NewKey(ref.Path))
-				if err != nil {
-					done <- err
-					return err
-				}
-				kontinue, err = visit(depth, &ref, err)
-				if err != nil {
-					done <- err
-					return err
-				}
-				if !kontinue {
-					break
-				}
-				depth++
-			}
-		}
-		done <- nil
-		return nil
-	}
-
-	pageSize := count / pll
-	done := make(chan error, pll)
-	for i := 0; i < pll; i++ {
-		go doSection(i, pageSize, done)
-	}
-
-	for i := 0; i < pll; i++ {
-		err := <-done
-		if err != nil {
-			return err
-		}
-	}
-
-	go func() {
-		done <- nil
-	}()
-
-	return <-done
-}
@@ -0,0 +1,162 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/qri-io/dataset/dsgraph"
+)
+
+// GraphView is a portion of a repo graph, limited by depth & node type,
+// for display. Encode views as JSON, or as Graphviz DOT with DOT
+type GraphView struct {
+	Nodes []GraphViewNode `json:"nodes"`
+	Links []GraphViewLink `json:"links"`
+}
+
+// GraphViewNode is a single node in a GraphView
+type GraphViewNode struct {
+	Path string           `json:"path"`
+	Type dsgraph.NodeType `json:"type"`
+	// Name is the alias of datasets the node is the latest version of
+	Name string `json:"name,omitempty"`
+	// Depth is the number of links between the node & the nearest
+	// dataset the view starts from
+	Depth int `json:"depth"`
+}
+
+// GraphViewLink connects two nodes in a GraphView by path
+type GraphViewLink struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// NewGraphView selects nodes from a graph built by Graph, following links
+// from the latest version of each of refs. depth limits how many links are
+// followed, with depth <= 0 following all links. Only nodes of the given
+// types are included if any types are given. Links pass through excluded
+// nodes, so a view of only datasets still links transformed datasets to
+// their inputs
+func NewGraphView(nodes map[string]*dsgraph.Node, refs []DatasetRef, depth int, types ...dsgraph.NodeType) *GraphView {
+	include := func(t dsgraph.NodeType) bool {
+		if len(types) == 0 {
+			return true
+		}
+		for _, it := range types {
+			if t == it {
+				return true
+			}
+		}
+		return false
+	}
+
+	names := map[string]string{}
+	queue := []*dsgraph.Node{}
+	depths := map[*dsgraph.Node]int{}
+	for _, ref := range refs {
+		n, ok := nodes[ref.Path]
+		if !ok {
+			continue
+		}
+		names[ref.Path] = ref.AliasString()
+		if _, seen := depths[n]; !seen {
+			depths[n] = 0
+			queue = append(queue, n)
+		}
+	}
+
+	// breadth-first, so each node is reached by its shortest path
+	visited := []*dsgraph.Node{}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		visited = append(visited, n)
+		if depth > 0 && depths[n] >= depth {
+			continue
+		}
+		for _, l := range n.Links {
+			if l.To == nil || l.To.Path == "" {
+				continue
+			}
+			if _, seen := depths[l.To]; !seen {
+				depths[l.To] = depths[n] + 1
+				queue = append(queue, l.To)
+			}
+		}
+	}
+
+	v := &GraphView{Nodes: []GraphViewNode{}, Links: []GraphViewLink{}}
+	linked := map[GraphViewLink]bool{}
+	for _, n := range visited {
+		if !include(n.Type) {
+			continue
+		}
+		v.Nodes = append(v.Nodes, GraphViewNode{Path: n.Path, Type: n.Type, Name: names[n.Path], Depth: depths[n]})
+
+		// find the nearest included nodes, looking through excluded ones
+		passed := map[*dsgraph.Node]bool{n: true}
+		next := append([]dsgraph.Link{}, n.Links...)
+		for len(next) > 0 {
+			l := next[0]
+			next = next[1:]
+			if _, inView := depths[l.To]; !inView || l.To.Path == "" || passed[l.To] {
+				continue
+			}
+			passed[l.To] = true
+			if include(l.To.Type) {
+				linked[GraphViewLink{From: n.Path, To: l.To.Path}] = true
+			} else {
+				next = append(next, l.To.Links...)
+			}
+		}
+	}
+
+	for l := range linked {
+		v.Links = append(v.Links, l)
+	}
+	sort.Slice(v.Nodes, func(i, j int) bool {
+		if v.Nodes[i].Depth != v.Nodes[j].Depth {
+			return v.Nodes[i].Depth < v.Nodes[j].Depth
+		}
+		return v.Nodes[i].Path < v.Nodes[j].Path
+	})
+	sort.Slice(v.Links, func(i, j int) bool {
+		if v.Links[i].From != v.Links[j].From {
+			return v.Links[i].From < v.Links[j].From
+		}
+		return v.Links[i].To < v.Links[j].To
+	})
+	return v
+}
+
+// graphShapes gives the Graphviz shape used for each node type
+var graphShapes = map[dsgraph.NodeType]string{
+	dsgraph.NtDataset:   "box",
+	dsgraph.NtData:      "cylinder",
+	dsgraph.NtCommit:    "note",
+	dsgraph.NtTransform: "hexagon",
+}
+
+// DOT encodes a view in the Graphviz DOT language, for rendering with
+// Graphviz tools like dot
+func (v *GraphView) DOT() []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("digraph qri {\n")
+	for _, n := range v.Nodes {
+		label := fmt.Sprintf("%s\\n%s", n.Type, n.Path)
+		if n.Name != "" {
+			label = fmt.Sprintf("%s\\n%s", n.Name, n.Path)
+		}
+		shape := graphShapes[n.Type]
+		if shape == "" {
+			shape = "ellipse"
+		}
+		fmt.Fprintf(buf, "  %q [label=\"%s\", shape=%s];\n", n.Path, label, shape)
+	}
+	for _, l := range v.Links {
+		fmt.Fprintf(buf, "  %q -> %q;\n", l.From, l.To)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/dsfs"
+)
+
+// defaultIterateConcurrency is the number of references a DatasetIterator
+// walks at once if IterateOptions doesn't say otherwise
+var defaultIterateConcurrency = 4
+
+// iteratePageSize is the number of references read from a repo at a time
+var iteratePageSize = 100
+
+// DatasetVersion is a single version of a dataset produced by a
+// DatasetIterator
+type DatasetVersion struct {
+	// Ref is the version, with Dataset loaded unless Err is set
+	Ref DatasetRef
+	// Depth counts versions back from the latest version, which has depth 0
+	Depth int
+	// Err is any error loading this version. Versions before a version
+	// that fails to load aren't visited
+	Err error
+}
+
+// IterateOptions configures a DatasetIterator
+type IterateOptions struct {
+	// Concurrency is the number of references walked at once, default 4
+	Concurrency int
+	// LatestOnly visits only the latest version of each reference, skipping
+	// history
+	LatestOnly bool
+	// LoadComponents loads the meta, structure, commit & transform of each
+	// version. Otherwise components only have their paths set
+	LoadComponents bool
+}
+
+// DatasetIterator streams the versions of every dataset referenced in a
+// repo. Each reference's history is produced in order, newest first, but
+// histories of different references interleave. Iterators must be read
+// until Next returns false, or closed
+type DatasetIterator struct {
+	parent   context.Context
+	ctx      context.Context
+	cancel   context.CancelFunc
+	versions chan DatasetVersion
+
+	lock sync.Mutex
+	errs []error
+}
+
+// IterateDatasets starts streaming datasets from a repo. Iteration stops
+// early if ctx is cancelled
+func IterateDatasets(ctx context.Context, r Repo, opts IterateOptions) *DatasetIterator {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultIterateConcurrency
+	}
+
+	it := &DatasetIterator{
+		parent:   ctx,
+		versions: make(chan DatasetVersion),
+	}
+	it.ctx, it.cancel = context.WithCancel(ctx)
+
+	refs := make(chan DatasetRef)
+	go it.listRefs(r, refs)
+
+	wg := sync.WaitGroup{}
+	wg.Add(opts.Concurrency)
+	for i := 0; i < opts.Concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for ref := range refs {
+				if !it.walk(r.Store(), ref, opts) {
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(it.versions)
+		it.cancel()
+	}()
+	return it
+}
+
+// Next blocks until the next version is available. ok is false once every
+// version has been produced, or the iterator is cancelled or closed
+func (it *DatasetIterator) Next() (v DatasetVersion, ok bool) {
+	v, ok = <-it.versions
+	return
+}
+
+// Close stops iteration, discarding any versions that haven't been read
+func (it *DatasetIterator) Close() {
+	it.cancel()
+	for range it.versions {
+	}
+}
+
+// Errors lists errors encountered while iterating: versions that failed
+// to load, references that couldn't be listed, and cancellation of the
+// context the iterator was created with. Call Errors once Next returns false
+func (it *DatasetIterator) Errors() []error {
+	it.lock.Lock()
+	defer it.lock.Unlock()
+
+	errs := append([]error{}, it.errs...)
+	if err := it.parent.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func (it *DatasetIterator) addErr(err error) {
+	it.lock.Lock()
+	it.errs = append(it.errs, err)
+	it.lock.Unlock()
+}
+
+// listRefs pages through every reference in a repo
+func (it *DatasetIterator) listRefs(r Repo, refs chan<- DatasetRef) {
+	defer close(refs)
+	for offset := 0; ; offset += iteratePageSize {
+		page, err := r.References(iteratePageSize, offset)
+		if err != nil {
+			it.addErr(fmt.Errorf("error listing references: %s", err.Error()))
+			return
+		}
+		for _, ref := range page {
+			select {
+			case refs <- ref:
+			case <-it.ctx.Done():
+				return
+			}
+		}
+		if len(page) < iteratePageSize {
+			return
+		}
+	}
+}
+
+// walk produces a reference & its history, returning false if iteration
+// has been cancelled
+func (it *DatasetIterator) walk(store cafs.Filestore, ref DatasetRef, opts IterateOptions) bool {
+	for depth := 0; ; depth++ {
+		var (
+			ds  *dataset.Dataset
+			err error
+		)
+		if opts.LoadComponents {
+			ds, err = dsfs.LoadDataset(store, datastore.NewKey(ref.Path))
+		} else {
+			ds, err = dsfs.LoadDatasetRefs(store, datastore.NewKey(ref.Path))
+		}
+
+		v := DatasetVersion{Ref: ref, Depth: depth}
+		if err != nil {
+			v.Err = fmt.Errorf("error loading dataset %s: %s", ref.Path, err.Error())
+			it.addErr(v.Err)
+		} else {
+			v.Ref.Dataset = ds
+		}
+
+		select {
+		case it.versions <- v:
+		case <-it.ctx.Done():
+			return false
+		}
+
+		if err != nil || opts.LatestOnly || ds.PreviousPath == "" || ds.PreviousPath == "/" {
+			return true
+		}
+		ref.Path = ds.PreviousPath
+	}
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIterateDatasets(t *testing.T) {
+	r, err := makeTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+
+	// read a page at a time smaller than the number of refs
+	defer func(size int) { iteratePageSize = size }(iteratePageSize)
+	iteratePageSize = 1
+
+	cases := []struct {
+		opts     IterateOptions
+		versions int
+	}{
+		{IterateOptions{}, 2},
+		{IterateOptions{Concurrency: 1}, 2},
+		{IterateOptions{LatestOnly: true, LoadComponents: true}, 2},
+	}
+
+	for i, c := range cases {
+		it := IterateDatasets(context.Background(), r, c.opts)
+		names := map[string]bool{}
+		got := 0
+		for v, ok := it.Next(); ok; v, ok = it.Next() {
+			if v.Err != nil {
+				t.Errorf("case %d unexpected error: %s", i, v.Err.Error())
+				continue
+			}
+			if v.Ref.Dataset == nil {
+				t.Errorf("case %d expected dataset to be loaded", i)
+				continue
+			}
+			if c.opts.LoadComponents && v.Ref.Dataset.Meta.Title == "" {
+				t.Errorf("case %d expected components to be loaded", i)
+			}
+			names[v.Ref.Name] = true
+			got++
+		}
+		if got != c.versions {
+			t.Errorf("case %d version count mismatch. expected: %d, got: %d", i, c.versions, got)
+		}
+		if !names["ds1"] || !names["ds2"] {
+			t.Errorf("case %d expected every ref to be visited. got: %v", i, names)
+		}
+		if errs := it.Errors(); len(errs) != 0 {
+			t.Errorf("case %d unexpected errors: %v", i, errs)
+		}
+	}
+}
+
+func TestIterateDatasetsErrors(t *testing.T) {
+	r, err := makeTestRepo()
+	if err != nil {
+		t.Errorf("error allocating test repo: %s", err.Error())
+		return
+	}
+	if err := r.PutRef(DatasetRef{Peername: "peer", Name: "missing", Path: "/map/QmMissing"}); err != nil {
+		t.Errorf("error putting ref: %s", err.Error())
+		return
+	}
+
+	it := IterateDatasets(context.Background(), r, IterateOptions{})
+	failed := 0
+	for v, ok := it.Next(); ok; v, ok = it.Next() {
+		if v.Err != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("expected 1 failed version. got: %d", failed)
+	}
+	if errs := it.Errors(); len(errs) != 1 {
+		t.Errorf("expected 1 error. got: %v", errs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it = IterateDatasets(ctx, r, IterateOptions{})
+	for _, ok := it.Next(); ok; _, ok = it.Next() {
+	}
+	if errs := it.Errors(); len(errs) == 0 || errs[len(errs)-1] != context.Canceled {
+		t.Errorf("expected cancelled iteration to report context.Canceled. got: %v", errs)
+	}
+}
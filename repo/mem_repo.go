@@ -17,6 +17,11 @@ type MemRepo struct {
 	*MemEventLog
 	profile  *profile.Profile
 	profiles profile.Store
+	crs      *MemChangeRequestStore
+	stats    *MemAnalytics
+	tags     *MemTagStore
+	branches *MemBranchStore
+	sources  *MemSourceCache
 }
 
 // NewMemRepo creates a new in-memory repository
@@ -28,6 +33,11 @@ func NewMemRepo(p *profile.Profile, store cafs.Filestore, ps profile.Store) (Rep
 		refCache:    &MemRefstore{},
 		profile:     p,
 		profiles:    ps,
+		crs:         NewMemChangeRequestStore(),
+		stats:       NewMemAnalytics(),
+		tags:        NewMemTagStore(),
+		branches:    NewMemBranchStore(),
+		sources:     NewMemSourceCache(),
 	}, nil
 }
 
@@ -72,3 +82,35 @@ func (r *MemRepo) SetProfile(p *profile.Profile) error {
 func (r *MemRepo) Profiles() profile.Store {
 	return r.profiles
 }
+
+// ChangeRequests gives this repo's ChangeRequestStore implementation
+func (r *MemRepo) ChangeRequests() ChangeRequestStore {
+	return r.crs
+}
+
+// Analytics gives this repo's Analytics implementation
+func (r *MemRepo) Analytics() Analytics {
+	return r.stats
+}
+
+// Tags gives this repo's TagStore implementation
+func (r *MemRepo) Tags() TagStore {
+	return r.tags
+}
+
+// Branches gives this repo's BranchStore implementation
+func (r *MemRepo) Branches() BranchStore {
+	return r.branches
+}
+
+// SourceValidators gets the validators of a dataset's source, implementing
+// the SourceCache interface
+func (r *MemRepo) SourceValidators(ref DatasetRef) (SourceValidators, error) {
+	return r.sources.SourceValidators(ref)
+}
+
+// PutSourceValidators sets the validators of a dataset's source,
+// implementing the SourceCache interface
+func (r *MemRepo) PutSourceValidators(ref DatasetRef, v SourceValidators) error {
+	return r.sources.PutSourceValidators(ref, v)
+}
@@ -0,0 +1,357 @@
+package repo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	golog "github.com/ipfs/go-log"
+	"github.com/libp2p/go-libp2p-crypto"
+	"github.com/qri-io/qri/repo/profile"
+)
+
+var (
+	// ErrReadOnly is returned by repos wrapped with read_only middleware
+	// when asked to make changes
+	ErrReadOnly = fmt.Errorf("repo: repo is read-only")
+	// ErrQuotaExceeded is returned by repos wrapped with quota middleware when
+	// adding a dataset would exceed the number of datasets allowed
+	ErrQuotaExceeded = fmt.Errorf("repo: dataset quota exceeded")
+)
+
+// Middleware wraps a repo, returning a repo with altered behaviour.
+// arg is an optional argument taken from configuration, see ApplyMiddleware
+type Middleware func(r Repo, arg string) (Repo, error)
+
+var (
+	middlewareLock sync.Mutex
+	middleware     = map[string]Middleware{}
+)
+
+// RegisterMiddleware makes a middleware available by name. It panics if
+// called twice with the same name
+func RegisterMiddleware(name string, mw Middleware) {
+	middlewareLock.Lock()
+	defer middlewareLock.Unlock()
+
+	if mw == nil {
+		panic(fmt.Errorf("repo: middleware %s is nil", name))
+	}
+	if _, exists := middleware[name]; exists {
+		panic(fmt.Errorf("repo: middleware %s is already registered", name))
+	}
+	middleware[name] = mw
+}
+
+// MiddlewareNames lists the names of all registered middleware, sorted
+func MiddlewareNames() []string {
+	middlewareLock.Lock()
+	defer middlewareLock.Unlock()
+
+	names := make([]string, 0, len(middleware))
+	for name := range middleware {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyMiddleware wraps a repo in named middleware. Names may carry an
+// argument after a colon, eg: "quota:100". Calls to the returned repo pass
+// through middleware in the order they're listed, so the first name is the
+// outermost wrapper
+func ApplyMiddleware(r Repo, names []string) (Repo, error) {
+	middlewareLock.Lock()
+	defer middlewareLock.Unlock()
+
+	for i := len(names) - 1; i >= 0; i-- {
+		name, arg := names[i], ""
+		if idx := strings.Index(name, ":"); idx != -1 {
+			name, arg = name[:idx], name[idx+1:]
+		}
+
+		mw, ok := middleware[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown repo middleware: %s", name)
+		}
+
+		wrapped, err := mw(r, arg)
+		if err != nil {
+			return nil, fmt.Errorf("error applying repo middleware %s: %s", name, err.Error())
+		}
+		r = &wrappedRepo{Repo: wrapped, base: r}
+	}
+	return r, nil
+}
+
+// wrappedRepo records the repo a middleware wrapped, so Unwrap can find
+// the original repo
+type wrappedRepo struct {
+	Repo
+	base Repo
+}
+
+// Unwrap strips all middleware from a repo, returning the original repo.
+// Optional interfaces like Searchable should be found with AsSearchable &
+// friends instead, so middleware can guard them
+func Unwrap(r Repo) Repo {
+	for {
+		w, ok := r.(*wrappedRepo)
+		if !ok {
+			return r
+		}
+		r = w.base
+	}
+}
+
+// findOptional walks a repo's middleware from the outside in, returning the
+// first layer is accepts, or nil if no layer does. Middleware guards an
+// optional interface by implementing it, shadowing the repo it wraps
+func findOptional(r Repo, is func(Repo) bool) Repo {
+	for {
+		w, ok := r.(*wrappedRepo)
+		if !ok {
+			if is(r) {
+				return r
+			}
+			return nil
+		}
+		if is(w.Repo) {
+			return w.Repo
+		}
+		r = w.base
+	}
+}
+
+// AsSearchable gives the Searchable implementation of a repo, if any
+func AsSearchable(r Repo) (Searchable, bool) {
+	s, ok := findOptional(r, func(r Repo) bool {
+		_, ok := r.(Searchable)
+		return ok
+	}).(Searchable)
+	return s, ok
+}
+
+// AsEventImporter gives the EventImporter implementation of a repo, if any
+func AsEventImporter(r Repo) (EventImporter, bool) {
+	i, ok := findOptional(r, func(r Repo) bool {
+		_, ok := r.(EventImporter)
+		return ok
+	}).(EventImporter)
+	return i, ok
+}
+
+// AsSourceCache gives the SourceCache implementation of a repo, if any
+func AsSourceCache(r Repo) (SourceCache, bool) {
+	c, ok := findOptional(r, func(r Repo) bool {
+		_, ok := r.(SourceCache)
+		return ok
+	}).(SourceCache)
+	return c, ok
+}
+
+func init() {
+	RegisterMiddleware("read_only", NewReadOnlyRepo)
+	RegisterMiddleware("audit", NewAuditRepo)
+	RegisterMiddleware("quota", NewQuotaRepo)
+}
+
+// ReadOnlyRepo refuses to change references, tags, branches, profile
+// information, keys, events, analytics, source validators or change
+// requests, returning ErrReadOnly. Content can still be written to the
+// repo's store, but will never be referenced
+type ReadOnlyRepo struct {
+	Repo
+}
+
+// NewReadOnlyRepo wraps a repo with a read-only guard. It takes no argument
+func NewReadOnlyRepo(r Repo, arg string) (Repo, error) {
+	if arg != "" {
+		return nil, fmt.Errorf("read_only doesn't take an argument")
+	}
+	return &ReadOnlyRepo{r}, nil
+}
+
+// PutRef returns ErrReadOnly
+func (ReadOnlyRepo) PutRef(ref DatasetRef) error { return ErrReadOnly }
+
+// DeleteRef returns ErrReadOnly
+func (ReadOnlyRepo) DeleteRef(ref DatasetRef) error { return ErrReadOnly }
+
+// SetProfile returns ErrReadOnly
+func (ReadOnlyRepo) SetProfile(p *profile.Profile) error { return ErrReadOnly }
+
+// SetPrivateKey returns ErrReadOnly
+func (ReadOnlyRepo) SetPrivateKey(pk crypto.PrivKey) error { return ErrReadOnly }
+
+// LogEvent returns ErrReadOnly
+func (ReadOnlyRepo) LogEvent(t EventType, ref DatasetRef) error { return ErrReadOnly }
+
+// ImportEvents returns ErrReadOnly, implementing the EventImporter interface
+func (ReadOnlyRepo) ImportEvents(events []*Event) error { return ErrReadOnly }
+
+// SourceValidators reads source validators from the underlying repo,
+// implementing the SourceCache interface
+func (r ReadOnlyRepo) SourceValidators(ref DatasetRef) (SourceValidators, error) {
+	if cache, ok := AsSourceCache(r.Repo); ok {
+		return cache.SourceValidators(ref)
+	}
+	return SourceValidators{}, ErrNotFound
+}
+
+// PutSourceValidators returns ErrReadOnly
+func (ReadOnlyRepo) PutSourceValidators(ref DatasetRef, v SourceValidators) error {
+	return ErrReadOnly
+}
+
+// Profiles gives a read-only view of the underlying repo's profiles
+func (r ReadOnlyRepo) Profiles() profile.Store {
+	return readOnlyProfiles{r.Repo.Profiles()}
+}
+
+type readOnlyProfiles struct {
+	profile.Store
+}
+
+func (readOnlyProfiles) PutProfile(p *profile.Profile) error { return ErrReadOnly }
+func (readOnlyProfiles) DeleteProfile(id profile.ID) error   { return ErrReadOnly }
+
+// Analytics gives a read-only view of the underlying repo's analytics
+func (r ReadOnlyRepo) Analytics() Analytics {
+	return readOnlyAnalytics{r.Repo.Analytics()}
+}
+
+type readOnlyAnalytics struct {
+	Analytics
+}
+
+func (readOnlyAnalytics) IncrementStat(ref DatasetRef, t StatType) error { return ErrReadOnly }
+
+// ChangeRequests gives a read-only view of the underlying repo's change
+// requests
+func (r ReadOnlyRepo) ChangeRequests() ChangeRequestStore {
+	return readOnlyChangeRequests{r.Repo.ChangeRequests()}
+}
+
+type readOnlyChangeRequests struct {
+	ChangeRequestStore
+}
+
+func (readOnlyChangeRequests) PutChangeRequest(cr *ChangeRequest) error { return ErrReadOnly }
+func (readOnlyChangeRequests) DeleteChangeRequest(id string) error      { return ErrReadOnly }
+
+// Tags gives a read-only view of the underlying repo's tags
+func (r ReadOnlyRepo) Tags() TagStore {
+	return readOnlyTags{r.Repo.Tags()}
+}
+
+type readOnlyTags struct {
+	TagStore
+}
+
+func (readOnlyTags) PutTag(ref DatasetRef) error    { return ErrReadOnly }
+func (readOnlyTags) DeleteTag(ref DatasetRef) error { return ErrReadOnly }
+
+// Branches gives a read-only view of the underlying repo's branches
+func (r ReadOnlyRepo) Branches() BranchStore {
+	return readOnlyBranches{r.Repo.Branches()}
+}
+
+type readOnlyBranches struct {
+	BranchStore
+}
+
+func (readOnlyBranches) PutBranch(ref DatasetRef) error    { return ErrReadOnly }
+func (readOnlyBranches) DeleteBranch(ref DatasetRef) error { return ErrReadOnly }
+
+var auditLog = golog.Logger("repo_audit")
+
+// AuditRepo logs every change made to a repo. Entries are written to the
+// "repo_audit" logger at info level
+type AuditRepo struct {
+	Repo
+}
+
+// NewAuditRepo wraps a repo with audit logging. It takes no argument
+func NewAuditRepo(r Repo, arg string) (Repo, error) {
+	if arg != "" {
+		return nil, fmt.Errorf("audit doesn't take an argument")
+	}
+	return &AuditRepo{r}, nil
+}
+
+// PutRef logs & adds a reference
+func (r AuditRepo) PutRef(ref DatasetRef) error {
+	err := r.Repo.PutRef(ref)
+	r.audit("PutRef", ref.String(), err)
+	return err
+}
+
+// DeleteRef logs & removes a reference
+func (r AuditRepo) DeleteRef(ref DatasetRef) error {
+	err := r.Repo.DeleteRef(ref)
+	r.audit("DeleteRef", ref.String(), err)
+	return err
+}
+
+// SetProfile logs & sets profile information
+func (r AuditRepo) SetProfile(p *profile.Profile) error {
+	err := r.Repo.SetProfile(p)
+	r.audit("SetProfile", p.Peername, err)
+	return err
+}
+
+// SetPrivateKey logs & sets the private key. The key itself is never logged
+func (r AuditRepo) SetPrivateKey(pk crypto.PrivKey) error {
+	err := r.Repo.SetPrivateKey(pk)
+	r.audit("SetPrivateKey", "", err)
+	return err
+}
+
+// LogEvent logs & records an event
+func (r AuditRepo) LogEvent(t EventType, ref DatasetRef) error {
+	err := r.Repo.LogEvent(t, ref)
+	r.audit("LogEvent", fmt.Sprintf("%s %s", t, ref), err)
+	return err
+}
+
+func (r AuditRepo) audit(method, detail string, err error) {
+	if err != nil {
+		auditLog.Infof("%s %s failed: %s", method, detail, err.Error())
+		return
+	}
+	auditLog.Infof("%s %s", method, detail)
+}
+
+// QuotaRepo limits the number of dataset references a repo can hold
+type QuotaRepo struct {
+	Repo
+	max int
+}
+
+// NewQuotaRepo wraps a repo with a dataset quota. arg is the maximum number
+// of datasets the repo can hold, and is required
+func NewQuotaRepo(r Repo, arg string) (Repo, error) {
+	max, err := strconv.Atoi(arg)
+	if err != nil || max < 0 {
+		return nil, fmt.Errorf("quota requires a maximum number of datasets, eg: quota:100")
+	}
+	return &QuotaRepo{Repo: r, max: max}, nil
+}
+
+// PutRef adds a reference, returning ErrQuotaExceeded if the reference is
+// new & the repo is full. Updating existing references is always allowed
+func (r QuotaRepo) PutRef(ref DatasetRef) error {
+	if _, err := r.Repo.GetRef(DatasetRef{Peername: ref.Peername, ProfileID: ref.ProfileID, Name: ref.Name}); err == ErrNotFound {
+		count, err := r.Repo.RefCount()
+		if err != nil {
+			return err
+		}
+		if count >= r.max {
+			return ErrQuotaExceeded
+		}
+	}
+	return r.Repo.PutRef(ref)
+}
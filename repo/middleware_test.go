@@ -0,0 +1,111 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/qri/repo/profile"
+)
+
+func TestApplyMiddleware(t *testing.T) {
+	mr, err := NewMemRepo(&profile.Profile{Peername: "peer"}, cafs.NewMapstore(), profile.NewMemStore())
+	if err != nil {
+		t.Errorf("error allocating mem repo: %s", err.Error())
+		return
+	}
+
+	badCases := [][]string{
+		{"not_a_middleware"},
+		{"quota"},
+		{"quota:lots"},
+		{"read_only:please"},
+	}
+	for i, names := range badCases {
+		if _, err := ApplyMiddleware(mr, names); err == nil {
+			t.Errorf("case %d: expected applying %v to error", i, names)
+		}
+	}
+
+	r, err := ApplyMiddleware(mr, []string{"audit", "quota:1"})
+	if err != nil {
+		t.Errorf("error applying middleware: %s", err.Error())
+		return
+	}
+	if Unwrap(r) != mr {
+		t.Errorf("expected Unwrap to return the original repo")
+	}
+
+	a := DatasetRef{Peername: "peer", Name: "a", Path: "/map/a"}
+	if err := r.PutRef(a); err != nil {
+		t.Errorf("error putting ref: %s", err.Error())
+		return
+	}
+	a.Path = "/map/a2"
+	if err := r.PutRef(a); err != nil {
+		t.Errorf("expected updating an existing ref within quota to succeed. got: %s", err.Error())
+	}
+	if err := r.PutRef(DatasetRef{Peername: "peer", Name: "b", Path: "/map/b"}); err != ErrQuotaExceeded {
+		t.Errorf("expected adding a ref beyond quota to return ErrQuotaExceeded. got: %v", err)
+	}
+
+	ro, err := ApplyMiddleware(mr, []string{"read_only"})
+	if err != nil {
+		t.Errorf("error applying middleware: %s", err.Error())
+		return
+	}
+	if err := ro.DeleteRef(a); err != ErrReadOnly {
+		t.Errorf("expected read-only delete to return ErrReadOnly. got: %v", err)
+	}
+	if err := ro.ChangeRequests().PutChangeRequest(&ChangeRequest{ID: "a"}); err != ErrReadOnly {
+		t.Errorf("expected read-only change request put to return ErrReadOnly. got: %v", err)
+	}
+	if _, err := ro.GetRef(DatasetRef{Peername: "peer", Name: "a"}); err != nil {
+		t.Errorf("expected read-only repo to allow reads. got: %s", err.Error())
+	}
+	if err := ro.Profiles().PutProfile(&profile.Profile{ID: "other", Peername: "other"}); err != ErrReadOnly {
+		t.Errorf("expected read-only profile put to return ErrReadOnly. got: %v", err)
+	}
+	if err := ro.Analytics().IncrementStat(a, StatAPIReads); err != ErrReadOnly {
+		t.Errorf("expected read-only stat increment to return ErrReadOnly. got: %v", err)
+	}
+
+	// optional interfaces are found through middleware, which can guard them
+	cached := &sourceCacheRepo{Repo: mr, MemSourceCache: NewMemSourceCache()}
+	if err := cached.PutSourceValidators(a, SourceValidators{URL: "https://example.com/a.csv"}); err != nil {
+		t.Errorf("error putting source validators: %s", err.Error())
+		return
+	}
+	audited, err := ApplyMiddleware(cached, []string{"audit"})
+	if err != nil {
+		t.Errorf("error applying middleware: %s", err.Error())
+		return
+	}
+	if cache, ok := AsSourceCache(audited); !ok || cache != SourceCache(cached) {
+		t.Errorf("expected source cache to be found through middleware that doesn't guard it")
+	}
+	ro, err = ApplyMiddleware(cached, []string{"audit", "read_only"})
+	if err != nil {
+		t.Errorf("error applying middleware: %s", err.Error())
+		return
+	}
+	cache, ok := AsSourceCache(ro)
+	if !ok {
+		t.Errorf("expected read-only repo to have a source cache")
+		return
+	}
+	if v, err := cache.SourceValidators(a); err != nil || v.URL != "https://example.com/a.csv" {
+		t.Errorf("expected read-only source cache to allow reads. got: %v, %v", v, err)
+	}
+	if err := cache.PutSourceValidators(a, SourceValidators{}); err != ErrReadOnly {
+		t.Errorf("expected read-only source cache put to return ErrReadOnly. got: %v", err)
+	}
+	if importer, ok := AsEventImporter(ro); !ok || importer.ImportEvents([]*Event{}) != ErrReadOnly {
+		t.Errorf("expected read-only repo to refuse imported events")
+	}
+}
+
+// sourceCacheRepo adds a SourceCache to a repo
+type sourceCacheRepo struct {
+	Repo
+	*MemSourceCache
+}
@@ -55,6 +55,10 @@ type DatasetRef struct {
 	Name string `json:"name,omitempty"`
 	// Content-addressed path for this dataset
 	Path string `json:"path,omitempty"`
+	// Tag names a version of this dataset, see TagStore
+	Tag string `json:"tag,omitempty"`
+	// Branch names a line of this dataset's history, see BranchStore
+	Branch string `json:"branch,omitempty"`
 	// Dataset is a pointer to the dataset being referenced
 	Dataset *dataset.Dataset `json:"dataset,omitempty"`
 }
@@ -71,6 +75,9 @@ func (r DatasetRef) String() (s string) {
 	if r.Path != "" {
 		s += r.Path
 	}
+	if r.Tag != "" && r.ProfileID.String() == "" && r.Path == "" {
+		s += "@" + r.Tag
+	}
 	return
 }
 
@@ -129,6 +136,7 @@ func (r DatasetRef) IsEmpty() bool {
 //     peer_id
 //     @peer_id
 //     @peer_id/network/hash
+//     peer_name/dataset_name@tag
 //
 // see tests for more exmples
 //
@@ -153,7 +161,7 @@ func ParseDatasetRef(ref string) (DatasetRef, error) {
 	if atIndex != -1 {
 
 		dsr.Peername, dsr.Name = parseAlias(ref[:atIndex])
-		dsr.ProfileID, dsr.Path, err = parseIdentifiers(ref[atIndex+1:])
+		dsr.ProfileID, dsr.Path, dsr.Tag, err = parseIdentifiers(ref[atIndex+1:])
 
 	} else {
 
@@ -194,7 +202,7 @@ func ParseDatasetRef(ref string) (DatasetRef, error) {
 		}
 	}
 
-	if dsr.ProfileID == "" && dsr.Peername == "" && dsr.Name == "" && dsr.Path == "" {
+	if dsr.ProfileID == "" && dsr.Peername == "" && dsr.Name == "" && dsr.Path == "" && dsr.Tag == "" {
 		err = fmt.Errorf("malformed DatasetRef string: %s", ref)
 		return dsr, err
 	}
@@ -221,7 +229,9 @@ func parseAlias(alias string) (peer, dataset string) {
 	return
 }
 
-func parseIdentifiers(ids string) (profileID profile.ID, path string, err error) {
+// parseIdentifiers splits the part of a reference after the @ into a
+// profile ID and path. A single identifier that isn't a hash is a tag
+func parseIdentifiers(ids string) (profileID profile.ID, path, tag string, err error) {
 
 	toks := strings.Split(ids, "/")
 	switch len(toks) {
@@ -229,6 +239,11 @@ func parseIdentifiers(ids string) (profileID profile.ID, path string, err error)
 		err = fmt.Errorf("malformed DatasetRef identifier: %s", ids)
 	case 1:
 		if toks[0] != "" {
+			if !isBase58Multihash(toks[0]) {
+				tag = toks[0]
+				err = ValidateTag(tag)
+				return
+			}
 			profileID, err = profile.IDB58Decode(toks[0])
 			// if !isBase58Multihash(toks[0]) {
 			// 	err = fmt.Errorf("'%s' is not a base58 multihash", ids)
@@ -237,6 +252,13 @@ func parseIdentifiers(ids string) (profileID profile.ID, path string, err error)
 			return
 		}
 	case 2:
+		// "@/tag" is how tags arrive from http paths
+		if toks[0] == "" && toks[1] != "" && !isBase58Multihash(toks[1]) {
+			tag = toks[1]
+			err = ValidateTag(tag)
+			return
+		}
+
 		if pid, e := profile.IDB58Decode(toks[0]); e == nil {
 			profileID = pid
 		}
@@ -296,6 +318,30 @@ func CanonicalizeDatasetRef(r Repo, ref *DatasetRef) error {
 		return err
 	}
 
+	// tags & branches share a namespace, "@main" is the default branch,
+	// which is the dataset's reference
+	if ref.Tag == DefaultBranch {
+		ref.Tag = ""
+	}
+	if ref.Tag != "" && ref.Path == "" {
+		got, err := r.Tags().GetTag(*ref)
+		if err == ErrNotFound {
+			got, err = r.Branches().GetBranch(DatasetRef{Peername: ref.Peername, Name: ref.Name, Branch: ref.Tag})
+			if err == ErrNotFound {
+				return fmt.Errorf("unknown tag or branch: %s@%s", ref.AliasString(), ref.Tag)
+			}
+			ref.Branch, ref.Tag = ref.Tag, ""
+		}
+		if err != nil {
+			return err
+		}
+		ref.Path = got.Path
+		if ref.ProfileID == "" {
+			ref.ProfileID = got.ProfileID
+		}
+		return nil
+	}
+
 	if ref.Path != "" && ref.ProfileID != "" && ref.Name != "" && ref.Peername != "" {
 		return nil
 	}
@@ -419,5 +465,8 @@ func CompareDatasetRef(a, b DatasetRef) error {
 	if a.Path != b.Path {
 		return fmt.Errorf("path mismatch. %s != %s", a.Path, b.Path)
 	}
+	if a.Tag != b.Tag {
+		return fmt.Errorf("tag mismatch. %s != %s", a.Tag, b.Tag)
+	}
 	return nil
 }
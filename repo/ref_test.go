@@ -54,6 +54,11 @@ var cases = []struct {
 		ProfileID: profile.IDB58MustDecode("QmYCvbfNbCwFR45HiNP45rwJgvatpiW38D961L5qAhUM5Y"),
 		Peername:  "bad_name",
 	}, "bad_name@QmYCvbfNbCwFR45HiNP45rwJgvatpiW38D961L5qAhUM5Y", "bad_name"},
+	{DatasetRef{
+		Peername: "lucille",
+		Name:     "ball",
+		Tag:      "v2018",
+	}, "lucille/ball@v2018", "lucille/ball"},
 	// TODO - this used to be me@badId, which isn't very useful, but at least provided coding parity
 	// might be worth revisiting
 	{DatasetRef{
@@ -135,6 +140,12 @@ func TestParseDatasetRef(t *testing.T) {
 		Path: "/map/QmcQsi93yUryyWvw6mPyDNoKRb7FcBx8QGBAeJ25kXQjnC",
 	}
 
+	tagDatasetRef := DatasetRef{
+		Peername: "peername",
+		Name:     "datasetname",
+		Tag:      "v2018",
+	}
+
 	cases := []struct {
 		input  string
 		expect DatasetRef
@@ -171,6 +182,11 @@ func TestParseDatasetRef(t *testing.T) {
 		{"peername/datasetname/@/network/QmYCvbfNbCwFR45HiNP45rwJgvatpiW38D961L5qAhUM5Y/junk/junk/...", fullDatasetRef, ""},
 		{"peername/datasetname/@/ipfs/QmYCvbfNbCwFR45HiNP45rwJgvatpiW38D961L5qAhUM5Y/junk/junk/...", fullIPFSDatasetRef, ""},
 
+		{"peername/datasetname@v2018", tagDatasetRef, ""},
+		{"peername/datasetname/@v2018", tagDatasetRef, ""},
+		{"peername/datasetname@/v2018", tagDatasetRef, ""},
+		{"peername/datasetname@*bad*", DatasetRef{Peername: "peername", Name: "datasetname", Tag: "*bad*"}, "invalid tag '*bad*'. tag names must start with a letter or number, and contain only letters, numbers, '_', '.' or '-'"},
+
 		// TODO - restore. These have been removed b/c I didn't have time to make dem work properly - @b5
 		// {"peername/datasetname@/QmYCvbfNbCwFR45HiNP45rwJgvatpiW38D961L5qAhUM5Y/junk/junk/...", fullIPFSDatasetRef, ""},
 		// {"peername/datasetname@QmYCvbfNbCwFR45HiNP45rwJgvatpiW38D961L5qAhUM5Y/junk/junk/...", fullIPFSDatasetRef, ""},
@@ -335,6 +351,10 @@ func TestCanonicalizeDatasetRef(t *testing.T) {
 		t.Errorf("error allocating mem repo: %s", err.Error())
 		return
 	}
+	if err := repo.Tags().PutTag(DatasetRef{Peername: "lucille", Name: "ball", Tag: "v1", Path: "/ipfs/QmRdexT18WuAKVX3vPusqmJTWLeNSeJgjmMbaF5QLGHna1"}); err != nil {
+		t.Errorf("error putting tag: %s", err.Error())
+		return
+	}
 
 	cases := []struct {
 		input  string
@@ -344,6 +364,8 @@ func TestCanonicalizeDatasetRef(t *testing.T) {
 		{"me/foo", "lucille/foo", ""},
 		{"you/foo", "you/foo", ""},
 		{"me/ball@/ipfs/QmRdexT18WuAKVX3vPusqmJTWLeNSeJgjmMbaF5QLGHna1", "lucille/ball@/ipfs/QmRdexT18WuAKVX3vPusqmJTWLeNSeJgjmMbaF5QLGHna1", ""},
+		{"me/ball@v1", "lucille/ball@/ipfs/QmRdexT18WuAKVX3vPusqmJTWLeNSeJgjmMbaF5QLGHna1", ""},
+		{"me/ball@v2", "lucille/ball@v2", "unknown tag or branch: lucille/ball@v2"},
 		// TODO - add tests that show path fulfillment
 		// {"@/ipfs/QmRdexT18WuAKVX3vPusqmJTWLeNSeJgjmMbaF5QLGHna1", "lucille/ball@/ipfs/QmRdexT18WuAKVX3vPusqmJTWLeNSeJgjmMbaF5QLGHna1", ""},
 	}
@@ -73,6 +73,15 @@ type Repo interface {
 	// TODO - should rename this to "profiles" to separate from the networking
 	// concept of a peer
 	Profiles() profile.Store
+	// ChangeRequests gives access to change requests made by & to this
+	// repo's profile
+	ChangeRequests() ChangeRequestStore
+	// Analytics keeps usage counters for datasets in this repo
+	Analytics() Analytics
+	// Tags gives access to named versions of datasets
+	Tags() TagStore
+	// Branches gives access to named lines of dataset history
+	Branches() BranchStore
 }
 
 // SearchParams encapsulates parameters provided to Searchable.Search
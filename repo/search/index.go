@@ -1,8 +1,8 @@
 package search
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/ipfs/go-datastore"
 	"log"
 	"time"
 
@@ -10,8 +10,6 @@ import (
 	"github.com/qri-io/bleve/analysis/lang/en"
 	//_ "github.com/qri-io/bleve/config"
 	"github.com/qri-io/bleve/mapping"
-	"github.com/qri-io/cafs"
-	"github.com/qri-io/dataset/dsfs"
 	"github.com/qri-io/qri/repo"
 )
 
@@ -103,29 +101,25 @@ func buildIndexMapping() (mapping.IndexMapping, error) {
 	return indexMapping, nil
 }
 
-// IndexRepo calculates an index for a given repository
-func IndexRepo(r repo.Repo, i bleve.Index) error {
-	refs, err := r.References(-1, 0)
-	if err != nil {
-		return err
-	}
-	return indexDatasetRefs(r.Store(), i, refs)
-}
-
-func indexDatasetRefs(store cafs.Filestore, i bleve.Index, refs []repo.DatasetRef) error {
+// IndexRepo calculates an index for the latest version of every dataset in
+// a repository. Datasets that fail to load are skipped. Indexing stops early
+// if ctx is cancelled
+func IndexRepo(ctx context.Context, r repo.Repo, i bleve.Index) error {
 	log.Printf("Indexing...")
 	count := 0
 	startTime := time.Now()
 	batch := i.NewBatch()
 	batchCount := 0
-	for _, ref := range refs {
-		ds, err := dsfs.LoadDataset(store, datastore.NewKey(ref.Path))
-		if err != nil {
-			log.Printf("error loading dataset: %s", err.Error())
+
+	it := repo.IterateDatasets(ctx, r, repo.IterateOptions{LatestOnly: true, LoadComponents: true})
+	defer it.Close()
+	for v, ok := it.Next(); ok; v, ok = it.Next() {
+		if v.Err != nil {
+			log.Printf("error loading dataset: %s", v.Err.Error())
 			continue
 		}
 		//remove extra fields
-		data, err := json.Marshal(ds)
+		data, err := json.Marshal(v.Ref.Dataset)
 		if err != nil {
 			log.Printf("error marshalling dataset: %s", err.Error())
 			//continue
@@ -134,7 +128,7 @@ func indexDatasetRefs(store cafs.Filestore, i bleve.Index, refs []repo.DatasetRe
 		leanMetadata := NewIndexableMetadataStruct()
 		json.Unmarshal(data, leanMetadata)
 
-		batch.Index(ref.Path, leanMetadata.MapValues())
+		batch.Index(v.Ref.Path, leanMetadata.MapValues())
 		batchCount++
 
 		if batchCount >= batchSize {
@@ -146,13 +140,9 @@ func indexDatasetRefs(store cafs.Filestore, i bleve.Index, refs []repo.DatasetRe
 			batchCount = 0
 		}
 		count++
-		// if count%1000 == 0 {
-		// 	indexDuration := time.Since(startTime)
-		// 	indexDurationSeconds := float64(indexDuration) / float64(time.Second)
-		// 	timePerDoc := float64(indexDuration) / float64(count)
-		// 	log.Printf("Indexed %d documents, in %.2fs average %2fms/doc)", count, indexDurationSeconds, timePerDoc/float64(time.Millisecond))
-		// }
-		// }
+	}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 	//flush the last batch
 	if batchCount > 0 {
@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"sync"
+)
+
+// SourceValidators are the cache validators a url responded with when data
+// was fetched from it for a dataset, used to ask the url if data has
+// changed when fetching it again
+type SourceValidators struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	// Checksum is the checksum of the data fetched. Validators only apply
+	// to versions of a dataset with this checksum
+	Checksum string `json:"checksum"`
+	// Auth names the configured credentials URL was fetched with. It's kept
+	// whatever the checksum, so later fetches of URL use the same credentials
+	Auth string `json:"auth,omitempty"`
+}
+
+// SourceCache is an opt-in interface for repos that keep the validators of
+// dataset sources, by dataset alias
+type SourceCache interface {
+	SourceValidators(ref DatasetRef) (SourceValidators, error)
+	PutSourceValidators(ref DatasetRef, v SourceValidators) error
+}
+
+// MemSourceCache is an in-memory implementation of the SourceCache
+// interface
+type MemSourceCache struct {
+	sync.Mutex
+	sources map[string]SourceValidators
+}
+
+// NewMemSourceCache allocates a MemSourceCache
+func NewMemSourceCache() *MemSourceCache {
+	return &MemSourceCache{sources: map[string]SourceValidators{}}
+}
+
+// SourceValidators gets the validators of a dataset's source
+func (c *MemSourceCache) SourceValidators(ref DatasetRef) (SourceValidators, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if v, ok := c.sources[ref.AliasString()]; ok {
+		return v, nil
+	}
+	return SourceValidators{}, ErrNotFound
+}
+
+// PutSourceValidators sets the validators of a dataset's source
+func (c *MemSourceCache) PutSourceValidators(ref DatasetRef, v SourceValidators) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.sources[ref.AliasString()] = v
+	return nil
+}
@@ -0,0 +1,257 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/qri-io/cafs"
+	"github.com/qri-io/dataset/dsfs"
+)
+
+// PinChecker is an opt-in interface for stores that can report whether
+// content is pinned
+type PinChecker interface {
+	IsPinned(key datastore.Key) (bool, error)
+}
+
+// Summary summarizes the contents & size of a repo
+type Summary struct {
+	// Refs is the number of dataset references
+	Refs int `json:"refs"`
+	// Versions is the number of versions across the history of every
+	// referenced dataset
+	Versions int `json:"versions"`
+	// Unreadable counts versions that couldn't be loaded
+	Unreadable int `json:"unreadable"`
+	// Blocks is the number of distinct blocks referenced datasets use,
+	// counting each dataset package & data file once
+	Blocks int `json:"blocks"`
+	// DataBlocks is the number of distinct data files
+	DataBlocks int `json:"dataBlocks"`
+	// Bytes is the total size of Blocks
+	Bytes int64 `json:"bytes"`
+	// PinsChecked is false if the store can't report pinned content, in
+	// which case PinnedBytes & UnpinnedBytes are zero
+	PinsChecked   bool  `json:"pinsChecked"`
+	PinnedBytes   int64 `json:"pinnedBytes"`
+	UnpinnedBytes int64 `json:"unpinnedBytes"`
+	// Datasets breaks down size by reference, largest first
+	Datasets []DatasetSize `json:"datasets"`
+	// Growth lists versions & bytes added each day, oldest first
+	Growth []GrowthStats `json:"growth"`
+}
+
+// DatasetSize is the size of a single referenced dataset, including
+// its history
+type DatasetSize struct {
+	Ref      DatasetRef `json:"ref"`
+	Versions int        `json:"versions"`
+	Blocks   int        `json:"blocks"`
+	// OwnBytes is the size of blocks no other dataset uses
+	OwnBytes int64 `json:"ownBytes"`
+	// SharedBytes is the size of blocks other datasets use as well
+	SharedBytes int64 `json:"sharedBytes"`
+}
+
+// GrowthStats is the content added to a repo on a single day, as recorded
+// in the event log. Totals include versions that may since have been
+// removed, so they can exceed the current size of the repo
+type GrowthStats struct {
+	// Date is the start of the day, in UTC
+	Date          time.Time `json:"date"`
+	Versions      int       `json:"versions"`
+	TotalVersions int       `json:"totalVersions"`
+	// Bytes is the size of blocks first used that day
+	Bytes      int64 `json:"bytes"`
+	TotalBytes int64 `json:"totalBytes"`
+}
+
+// Stats calculates statistics for a repo by visiting every version of
+// every referenced dataset. Blocks are sized once, no matter how many
+// versions or datasets use them
+func Stats(ctx context.Context, r Repo) (*Summary, error) {
+	store := r.Store()
+	stats := &Summary{Datasets: []DatasetSize{}, Growth: []GrowthStats{}}
+	datasets := map[string]*DatasetSize{}
+	// users maps blocks to the names of datasets that use them
+	users := map[string]map[string]bool{}
+	dataBlocks := map[string]bool{}
+	use := func(name, path string) {
+		if path == "" || path == "/" {
+			return
+		}
+		root := PathRoot(path)
+		if users[root] == nil {
+			users[root] = map[string]bool{}
+		}
+		users[root][name] = true
+	}
+
+	it := IterateDatasets(ctx, r, IterateOptions{})
+	for v, ok := it.Next(); ok; v, ok = it.Next() {
+		name := v.Ref.AliasString()
+		ds := datasets[name]
+		if ds == nil {
+			ds = &DatasetSize{}
+			datasets[name] = ds
+		}
+		if v.Depth == 0 {
+			ds.Ref = v.Ref
+			ds.Ref.Dataset = nil
+		}
+		if v.Err != nil {
+			stats.Unreadable++
+			continue
+		}
+		ds.Versions++
+		stats.Versions++
+
+		use(name, v.Ref.Path)
+		for _, p := range datasetPaths(v.Ref.Dataset) {
+			use(name, p)
+		}
+		if p := v.Ref.Dataset.DataPath; p != "" {
+			dataBlocks[PathRoot(p)] = true
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// any errors beyond unreadable versions mean references couldn't be listed
+	if errs := it.Errors(); len(errs) > stats.Unreadable {
+		return nil, errs[0]
+	}
+	stats.Refs = len(datasets)
+
+	pins, checkPins := store.(PinChecker)
+	stats.PinsChecked = checkPins
+	sizes := map[string]int64{}
+	for root, names := range users {
+		size, err := blockSize(store, root)
+		if err != nil {
+			continue
+		}
+		sizes[root] = size
+		stats.Blocks++
+		stats.Bytes += size
+		if dataBlocks[root] {
+			stats.DataBlocks++
+		}
+		if checkPins {
+			if pinned, err := pins.IsPinned(datastore.NewKey(root)); err == nil && pinned {
+				stats.PinnedBytes += size
+			} else {
+				stats.UnpinnedBytes += size
+			}
+		}
+		for name := range names {
+			datasets[name].Blocks++
+			if len(names) == 1 {
+				datasets[name].OwnBytes += size
+			} else {
+				datasets[name].SharedBytes += size
+			}
+		}
+	}
+
+	for _, ds := range datasets {
+		stats.Datasets = append(stats.Datasets, *ds)
+	}
+	sort.Slice(stats.Datasets, func(i, j int) bool {
+		a, b := stats.Datasets[i], stats.Datasets[j]
+		if a.OwnBytes+a.SharedBytes != b.OwnBytes+b.SharedBytes {
+			return a.OwnBytes+a.SharedBytes > b.OwnBytes+b.SharedBytes
+		}
+		return a.Ref.AliasString() < b.Ref.AliasString()
+	})
+
+	growth, err := growthStats(r, sizes)
+	if err != nil {
+		return nil, err
+	}
+	stats.Growth = growth
+	return stats, nil
+}
+
+// growthStats buckets dataset creation events by day. sizes caches the
+// size of known blocks
+func growthStats(r Repo, sizes map[string]int64) ([]GrowthStats, error) {
+	store := r.Store()
+	events, err := allEvents(r)
+	if err != nil {
+		return nil, err
+	}
+
+	growth := []GrowthStats{}
+	seen := map[string]bool{}
+	for _, e := range events {
+		if e.Type != ETDsCreated || e.Ref.Path == "" {
+			continue
+		}
+		date := e.Time.UTC().Truncate(24 * time.Hour)
+		if len(growth) == 0 || !growth[len(growth)-1].Date.Equal(date) {
+			g := GrowthStats{Date: date}
+			if len(growth) > 0 {
+				g.TotalVersions = growth[len(growth)-1].TotalVersions
+				g.TotalBytes = growth[len(growth)-1].TotalBytes
+			}
+			growth = append(growth, g)
+		}
+		g := &growth[len(growth)-1]
+		g.Versions++
+		g.TotalVersions++
+
+		paths := []string{e.Ref.Path}
+		if ds, err := dsfs.LoadDatasetRefs(store, datastore.NewKey(e.Ref.Path)); err == nil {
+			paths = append(paths, datasetPaths(ds)...)
+		}
+		for _, p := range paths {
+			if p == "" || p == "/" || seen[PathRoot(p)] {
+				continue
+			}
+			root := PathRoot(p)
+			seen[root] = true
+			size, ok := sizes[root]
+			if !ok {
+				// content that's been removed has no size
+				if size, err = blockSize(store, root); err != nil {
+					continue
+				}
+			}
+			g.Bytes += size
+			g.TotalBytes += size
+		}
+	}
+	return growth, nil
+}
+
+// allEvents reads a repo's entire event log, oldest first
+func allEvents(r Repo) ([]*Event, error) {
+	all := []*Event{}
+	for offset := 0; ; offset += 100 {
+		events, err := r.Events(100, offset)
+		if err != nil {
+			return nil, fmt.Errorf("error reading events: %s", err.Error())
+		}
+		// events are listed newest first
+		for _, e := range events {
+			all = append([]*Event{e}, all...)
+		}
+		if len(events) < 100 {
+			return all, nil
+		}
+	}
+}
+
+// blockSize gets the size of a single block in a store
+func blockSize(store cafs.Filestore, path string) (int64, error) {
+	f, err := store.Get(datastore.NewKey(path))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return fileSize(f)
+}
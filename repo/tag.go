@@ -0,0 +1,171 @@
+package repo
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// TagStore keeps named references to dataset versions. Tags are
+// DatasetRefs with Tag set, and are unique per dataset, so me/census@v2018
+// always refers to a single version of me/census
+type TagStore interface {
+	// PutTag adds or moves a tag. ref must have Peername, Name, Tag & Path
+	PutTag(ref DatasetRef) error
+	// GetTag completes a tag reference with the path it names, returning
+	// ErrNotFound for unknown tags
+	GetTag(ref DatasetRef) (DatasetRef, error)
+	// DeleteTag removes a tag
+	DeleteTag(ref DatasetRef) error
+	// ListTags lists tags for the dataset ref names, or all tags if ref has
+	// no Name. Tags are ordered by alias, then tag
+	ListTags(ref DatasetRef) ([]DatasetRef, error)
+}
+
+// validRefName matches tag & branch names that can't be confused with
+// profile IDs or paths
+var validRefName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.\-]*$`)
+
+// ValidateTag checks a tag name is usable in dataset references
+func ValidateTag(tag string) error {
+	return validateRefName("tag", tag)
+}
+
+func validateRefName(kind, name string) error {
+	if !validRefName.MatchString(name) {
+		return fmt.Errorf("invalid %s '%s'. %s names must start with a letter or number, and contain only letters, numbers, '_', '.' or '-'", kind, name, kind)
+	}
+	if isBase58Multihash(name) {
+		return fmt.Errorf("invalid %s '%s'. %s names can't be valid hashes", kind, name, kind)
+	}
+	return nil
+}
+
+// TagKey gives the key a tag is stored under, for use by TagStore
+// implementations
+func TagKey(ref DatasetRef) string {
+	return ref.AliasString() + "@" + ref.Tag
+}
+
+// MemTagStore is an in-memory implementation of the TagStore interface
+type MemTagStore struct {
+	sync.Mutex
+	tags map[string]DatasetRef
+}
+
+// NewMemTagStore allocates a MemTagStore
+func NewMemTagStore() *MemTagStore {
+	return &MemTagStore{tags: map[string]DatasetRef{}}
+}
+
+// PutTag adds or moves a tag
+func (s *MemTagStore) PutTag(ref DatasetRef) error {
+	s.Lock()
+	defer s.Unlock()
+	return PutTag(s.tags, ref)
+}
+
+// GetTag completes a tag reference
+func (s *MemTagStore) GetTag(ref DatasetRef) (DatasetRef, error) {
+	s.Lock()
+	defer s.Unlock()
+	if t, ok := s.tags[TagKey(ref)]; ok {
+		return t, nil
+	}
+	return DatasetRef{}, ErrNotFound
+}
+
+// DeleteTag removes a tag
+func (s *MemTagStore) DeleteTag(ref DatasetRef) error {
+	s.Lock()
+	defer s.Unlock()
+	key := TagKey(ref)
+	if _, ok := s.tags[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.tags, key)
+	return nil
+}
+
+// ListTags lists tags for a dataset, or all tags
+func (s *MemTagStore) ListTags(ref DatasetRef) ([]DatasetRef, error) {
+	s.Lock()
+	defer s.Unlock()
+	return FilterTags(s.tags, ref), nil
+}
+
+// FilterTags lists the tags in a map of tags that belong to the dataset
+// ref names, or all tags if ref has no Name
+func FilterTags(tags map[string]DatasetRef, ref DatasetRef) []DatasetRef {
+	list := []DatasetRef{}
+	for _, t := range tags {
+		if ref.Name == "" || (t.Peername == ref.Peername && t.Name == ref.Name) {
+			list = append(list, t)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if ai, aj := list[i].AliasString(), list[j].AliasString(); ai != aj {
+			return ai < aj
+		}
+		return list[i].Tag < list[j].Tag
+	})
+	return list
+}
+
+// PutTag adds a tag to a map of tags keyed by TagKey, for use by TagStore
+// implementations. ref must have Peername, Name, Tag & Path
+func PutTag(tags map[string]DatasetRef, ref DatasetRef) error {
+	if ref.Peername == "" {
+		return ErrPeernameRequired
+	} else if ref.Name == "" {
+		return ErrNameRequired
+	} else if ref.Path == "" {
+		return ErrPathRequired
+	}
+	if err := ValidateTag(ref.Tag); err != nil {
+		return err
+	}
+
+	tags[TagKey(ref)] = DatasetRef{
+		Peername:  ref.Peername,
+		ProfileID: ref.ProfileID,
+		Name:      ref.Name,
+		Tag:       ref.Tag,
+		Path:      ref.Path,
+	}
+	return nil
+}
+
+// RenameTags moves all tags of one dataset to another, for use when a
+// dataset is renamed
+func RenameTags(ts TagStore, from, to DatasetRef) error {
+	tags, err := ts.ListTags(from)
+	if err != nil {
+		return err
+	}
+	for _, t := range tags {
+		if err := ts.DeleteTag(t); err != nil {
+			return err
+		}
+		t.Peername, t.ProfileID, t.Name = to.Peername, to.ProfileID, to.Name
+		if err := ts.PutTag(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteTags removes all tags of a dataset
+func DeleteTags(ts TagStore, ref DatasetRef) error {
+	tags, err := ts.ListTags(ref)
+	if err != nil {
+		return err
+	}
+	for _, t := range tags {
+		if err := ts.DeleteTag(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}